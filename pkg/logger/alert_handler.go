@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 告警的默认参数
+const (
+	defaultAlertThrottle = time.Minute
+	alertQueueSize       = 100
+	alertTimeout         = 10 * time.Second
+)
+
+// alertHandler 将达到告警级别的记录 POST 到 webhook（例如 Slack Incoming Webhook）的 handler 包装
+//
+// 记录照常交给 inner 处理，告警在后台 goroutine 中发送，不会阻塞日志调用；
+// 同一消息在 throttle 时间内最多告警一次，队列已满时直接丢弃告警。
+// 发送失败时通过创建时的 inner handler 记录一条 WARN 日志，不经过告警逻辑，避免递归。
+// 告警内容中的消息和属性与输出一样经过 ReplaceAttr 脱敏，敏感信息不会发送到第三方服务。
+type alertHandler struct {
+	inner  slog.Handler
+	groups []string
+	attrs  []slog.Attr // With 添加的属性（已带 group 前缀），用于告警内容
+	state  *alertState
+}
+
+// alertState 同一 handler 及其派生 handler 共享的告警状态
+type alertState struct {
+	root     slog.Handler                                 // 记录发送失败的 handler
+	replace  func(groups []string, a slog.Attr) slog.Attr // 与输出相同的 ReplaceAttr，见 buildReplaceAttr
+	url      string
+	minLevel slog.Level
+	throttle time.Duration
+	client   *http.Client
+	now      func() time.Time
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time // 每条消息最近一次告警的时间
+	queue    chan alertPayload
+	done     chan struct{}
+	closed   bool
+}
+
+// alertPayload webhook 请求体，text 字段与 Slack Incoming Webhook 兼容
+type alertPayload struct {
+	Text string `json:"text"`
+}
+
+// newAlertHandler 创建告警 handler 并启动后台发送 goroutine，replace 用于告警内容的脱敏
+func newAlertHandler(inner slog.Handler, url string, minLevel slog.Level, throttle time.Duration, replace func([]string, slog.Attr) slog.Attr) *alertHandler {
+	if throttle <= 0 {
+		throttle = defaultAlertThrottle
+	}
+	state := &alertState{
+		root:     inner,
+		replace:  replace,
+		url:      url,
+		minLevel: minLevel,
+		throttle: throttle,
+		client:   &http.Client{Timeout: alertTimeout},
+		now:      time.Now,
+		lastSent: make(map[string]time.Time),
+		queue:    make(chan alertPayload, alertQueueSize),
+		done:     make(chan struct{}),
+	}
+	go state.run()
+	return &alertHandler{inner: inner, state: state}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *alertHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *alertHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.state.minLevel && h.state.allow(r.Message) {
+		h.state.enqueue(alertPayload{Text: h.format(r)})
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *alertHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newAttrs := append(h.attrs[:len(h.attrs):len(h.attrs)], h.prepare(attrs)...)
+	return &alertHandler{inner: h.inner.WithAttrs(attrs), groups: h.groups, attrs: newAttrs, state: h.state}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *alertHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &alertHandler{inner: h.inner.WithGroup(name), groups: groups, attrs: h.attrs, state: h.state}
+}
+
+// format 生成告警文本：第一行为级别和消息，之后每行一个属性
+func (h *alertHandler) format(r slog.Record) string {
+	var b strings.Builder
+	msg := ""
+	if a, ok := replaceAttr(h.state.replace, nil, slog.String(slog.MessageKey, r.Message)); ok {
+		msg = a.Value.String()
+	}
+	fmt.Fprintf(&b, "[%s] %s", levelName(r.Level), msg)
+	writeAttr := func(a slog.Attr) {
+		fmt.Fprintf(&b, "\n%s=%s", a.Key, a.Value.String())
+	}
+	for _, a := range h.attrs {
+		writeAttr(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		for _, attr := range h.prepare([]slog.Attr{a}) {
+			writeAttr(attr)
+		}
+		return true
+	})
+	return b.String()
+}
+
+// prepare 对属性应用 ReplaceAttr，然后展开为加上 group 前缀的键
+func (h *alertHandler) prepare(attrs []slog.Attr) []slog.Attr {
+	replaced := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a, ok := replaceAttr(h.state.replace, h.groups, a); ok {
+			replaced = append(replaced, a)
+		}
+	}
+	return prefixAttrs(h.groups, replaced)
+}
+
+// prefixAttrs 展开分组属性，并为键加上 group 前缀（以 "." 连接）
+func prefixAttrs(groups []string, attrs []slog.Attr) []slog.Attr {
+	prefix := ""
+	if len(groups) > 0 {
+		prefix = strings.Join(groups, ".") + "."
+	}
+	var result []slog.Attr
+	var walk func(prefix string, a slog.Attr)
+	walk = func(prefix string, a slog.Attr) {
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			if a.Key != "" {
+				prefix += a.Key + "."
+			}
+			for _, child := range v.Group() {
+				walk(prefix, child)
+			}
+			return
+		}
+		if a.Key != "" {
+			result = append(result, slog.Attr{Key: prefix + a.Key, Value: v})
+		}
+	}
+	for _, a := range attrs {
+		walk(prefix, a)
+	}
+	return result
+}
+
+// allow 判断消息是否超过了告警间隔
+func (s *alertState) allow(msg string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if last, ok := s.lastSent[msg]; ok && now.Sub(last) < s.throttle {
+		return false
+	}
+	// 消息种类过多时清理已过期的记录，避免无限增长
+	if len(s.lastSent) >= 1000 {
+		for m, last := range s.lastSent {
+			if now.Sub(last) >= s.throttle {
+				delete(s.lastSent, m)
+			}
+		}
+	}
+	s.lastSent[msg] = now
+	return true
+}
+
+// enqueue 将告警放入发送队列，队列已满或已关闭时丢弃
+func (s *alertState) enqueue(p alertPayload) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- p:
+	default:
+	}
+}
+
+// run 后台发送循环
+func (s *alertState) run() {
+	defer close(s.done)
+	for p := range s.queue {
+		if err := s.post(p); err != nil {
+			s.reportFailure(err)
+		}
+	}
+}
+
+// post 发送一条告警
+func (s *alertState) post(p alertPayload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("POST alert webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+// reportFailure 通过 root handler 记录告警发送失败，不经过告警逻辑
+func (s *alertState) reportFailure(err error) {
+	ctx := context.Background()
+	if !s.root.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+	r := slog.NewRecord(s.now(), slog.LevelWarn, "alert webhook failed", 0)
+	r.AddAttrs(slog.String("error", err.Error()))
+	_ = s.root.Handle(ctx, r)
+}
+
+// Close 实现 io.Closer 接口，发送完队列中的告警后停止后台 goroutine
+func (s *alertState) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.mu.Unlock()
+
+	<-s.done
+	return nil
+}
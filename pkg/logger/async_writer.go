@@ -0,0 +1,130 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// 缓冲区满时的处理策略
+const (
+	overflowBlock = "block"
+	overflowDrop  = "drop"
+)
+
+// defaultBufferSize 异步写入默认可缓冲的记录数
+const defaultBufferSize = 1024
+
+// asyncMessage 异步队列中的一项：待写入的数据，或 Sync 使用的刷新标记
+type asyncMessage struct {
+	data    []byte
+	flushed chan struct{} // 非 nil 表示刷新标记，处理到此处时关闭
+}
+
+// asyncWriter 通过带缓冲的 channel 将写入交给后台 goroutine 完成
+//
+// 每条记录在入队前复制，因为 handler 会复用其缓冲区。
+// 队列满时按策略阻塞等待或直接丢弃；Sync 和 Close 会等待队列中已有的记录写完。
+type asyncWriter struct {
+	out    io.Writer
+	inner  io.Closer // 底层资源，stdout/stderr 时为 nil
+	queue  chan asyncMessage
+	drop   bool
+	done   chan struct{}
+	mu     sync.RWMutex // 保护 closed，防止向已关闭的队列发送
+	closed bool
+}
+
+// newAsyncWriter 创建异步写入器并启动后台 goroutine
+func newAsyncWriter(out io.Writer, inner io.Closer, bufferSize int, policy string) *asyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
+	}
+	w := &asyncWriter{
+		out:   out,
+		inner: inner,
+		queue: make(chan asyncMessage, bufferSize),
+		drop:  policy == overflowDrop,
+		done:  make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// run 后台写入循环，队列关闭后退出
+func (w *asyncWriter) run() {
+	defer close(w.done)
+	for msg := range w.queue {
+		if msg.flushed != nil {
+			close(msg.flushed)
+			continue
+		}
+		// 异步写入无法向调用方返回错误，与 slog 内置 handler 一样忽略写入失败
+		_, _ = w.out.Write(msg.data)
+	}
+}
+
+// Write 实现 io.Writer 接口
+func (w *asyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	msg := asyncMessage{data: append([]byte(nil), p...)}
+	if w.drop {
+		select {
+		case w.queue <- msg:
+		default:
+			// 队列已满，丢弃该记录
+		}
+		return len(p), nil
+	}
+	w.queue <- msg
+	return len(p), nil
+}
+
+// Sync 等待队列中已有的记录写完，然后刷新底层资源
+func (w *asyncWriter) Sync() error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil
+	}
+	// 刷新标记与数据共用队列，保证其之前的记录都已写入
+	flushed := make(chan struct{})
+	w.queue <- asyncMessage{flushed: flushed}
+	w.mu.RUnlock()
+
+	<-flushed
+	return syncResource(w.inner)
+}
+
+// Rotate 等待队列中已有的记录写完，然后轮转底层资源，之后的记录写入新文件
+func (w *asyncWriter) Rotate() error {
+	if err := w.Sync(); err != nil {
+		return err
+	}
+	return rotateResource(w.inner)
+}
+
+// Close 实现 io.Closer 接口，写完队列中的所有记录后关闭底层资源
+func (w *asyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	if w.inner == nil {
+		return nil
+	}
+	return errors.Join(syncResource(w.inner), w.inner.Close())
+}
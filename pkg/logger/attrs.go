@@ -0,0 +1,122 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// AttrSet 类型安全的属性构造器，由 [Fields] 创建
+//
+// 与 attrs ...any 的键值对写法相比，键的类型和成对关系在编译期检查，
+// 不会因为参数个数为奇数而输出 !BADKEY：
+//
+//	fields := logger.Fields().Str("user_id", id).Int("retry", n).Err(err)
+//	logger.InfoAttrs("请求失败", fields.Build()...)
+type AttrSet struct {
+	attrs []slog.Attr
+}
+
+// Fields 创建一个空的属性构造器
+func Fields() *AttrSet {
+	return &AttrSet{}
+}
+
+// Str 添加字符串属性
+func (s *AttrSet) Str(key, value string) *AttrSet {
+	return s.Attr(slog.String(key, value))
+}
+
+// Int 添加整数属性
+func (s *AttrSet) Int(key string, value int) *AttrSet {
+	return s.Attr(slog.Int(key, value))
+}
+
+// Int64 添加 int64 属性
+func (s *AttrSet) Int64(key string, value int64) *AttrSet {
+	return s.Attr(slog.Int64(key, value))
+}
+
+// Uint64 添加 uint64 属性
+func (s *AttrSet) Uint64(key string, value uint64) *AttrSet {
+	return s.Attr(slog.Uint64(key, value))
+}
+
+// Float 添加浮点数属性
+func (s *AttrSet) Float(key string, value float64) *AttrSet {
+	return s.Attr(slog.Float64(key, value))
+}
+
+// Bool 添加布尔属性
+func (s *AttrSet) Bool(key string, value bool) *AttrSet {
+	return s.Attr(slog.Bool(key, value))
+}
+
+// Dur 添加时长属性
+func (s *AttrSet) Dur(key string, value time.Duration) *AttrSet {
+	return s.Attr(slog.Duration(key, value))
+}
+
+// Time 添加时间属性
+func (s *AttrSet) Time(key string, value time.Time) *AttrSet {
+	return s.Attr(slog.Time(key, value))
+}
+
+// Err 以 error 为键添加错误，err 为 nil 时忽略
+func (s *AttrSet) Err(err error) *AttrSet {
+	if err == nil {
+		return s
+	}
+	return s.Attr(slog.Any("error", err))
+}
+
+// Any 添加任意类型的属性
+func (s *AttrSet) Any(key string, value any) *AttrSet {
+	return s.Attr(slog.Any(key, value))
+}
+
+// Attr 添加已构造的属性，例如 slog.Group
+func (s *AttrSet) Attr(attrs ...slog.Attr) *AttrSet {
+	s.attrs = append(s.attrs, attrs...)
+	return s
+}
+
+// Build 返回按添加顺序排列的属性
+func (s *AttrSet) Build() []slog.Attr {
+	return s.attrs
+}
+
+// DebugAttrs 与 [Debug] 相同，但只接受 slog.Attr，可配合 [Fields] 使用
+func DebugAttrs(msg string, attrs ...slog.Attr) {
+	logAttrsAt(context.Background(), slog.Default(), slog.LevelDebug, msg, attrs...)
+}
+
+// InfoAttrs 与 [Info] 相同，但只接受 slog.Attr，可配合 [Fields] 使用
+//
+//	logger.InfoAttrs("用户登录成功", logger.Fields().Int("user_id", 123).Str("ip", ip).Build()...)
+func InfoAttrs(msg string, attrs ...slog.Attr) {
+	logAttrsAt(context.Background(), slog.Default(), slog.LevelInfo, msg, attrs...)
+}
+
+// WarnAttrs 与 [Warn] 相同，但只接受 slog.Attr，可配合 [Fields] 使用
+func WarnAttrs(msg string, attrs ...slog.Attr) {
+	logAttrsAt(context.Background(), slog.Default(), slog.LevelWarn, msg, attrs...)
+}
+
+// ErrorAttrs 与 [Error] 相同，但只接受 slog.Attr，可配合 [Fields] 使用
+func ErrorAttrs(msg string, attrs ...slog.Attr) {
+	logAttrsAt(context.Background(), slog.Default(), slog.LevelError, msg, attrs...)
+}
+
+// logAttrsAt 与 logAt 相同，但直接添加 slog.Attr
+func logAttrsAt(ctx context.Context, logger *slog.Logger, level slog.Level, msg string, attrs ...slog.Attr) {
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // 跳过 runtime.Callers、logAttrsAt 和辅助函数自身
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.AddAttrs(attrs...)
+	_ = logger.Handler().Handle(ctx, r)
+}
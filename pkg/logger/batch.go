@@ -0,0 +1,161 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 批量发送的默认参数
+const (
+	defaultBatchSize     = 100
+	defaultFlushInterval = time.Second
+	defaultQueueSize     = 10000
+)
+
+// batchWriter 将记录缓存在有界队列中，由后台 goroutine 按批发送
+//
+// 用于网络输出：攒满 size 条或每隔 interval 调用一次 send。
+// 队列满时（例如远端不可用、send 正在重试）新记录被丢弃并计数，
+// 丢弃数会定期打印到 stderr（不能通过 logger 自身输出，避免递归）。
+type batchWriter struct {
+	name     string // 输出名称，用于诊断信息
+	send     func(batch [][]byte) error
+	size     int
+	interval time.Duration
+
+	queue   chan []byte
+	flushes chan chan struct{}
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex // 保护 closed，防止向已关闭的队列发送
+	closed bool
+}
+
+// newBatchWriter 创建批量写入器并启动后台 goroutine，参数为 0 时使用默认值
+func newBatchWriter(name string, send func([][]byte) error, size int, interval time.Duration, queueSize int) *batchWriter {
+	if size <= 0 {
+		size = defaultBatchSize
+	}
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	w := &batchWriter{
+		name:     name,
+		send:     send,
+		size:     size,
+		interval: interval,
+		queue:    make(chan []byte, queueSize),
+		flushes:  make(chan chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write 实现 io.Writer 接口，p 为一条格式化后的记录，队列满时丢弃
+func (w *batchWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	select {
+	case w.queue <- append([]byte(nil), p...):
+	default:
+		w.dropped.Add(1)
+	}
+	return len(p), nil
+}
+
+// Sync 立即发送队列中已有的记录
+func (w *batchWriter) Sync() error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		return nil
+	}
+	flushed := make(chan struct{})
+	w.flushes <- flushed
+	w.mu.RUnlock()
+
+	<-flushed
+	return nil
+}
+
+// Close 实现 io.Closer 接口，发送剩余记录后停止后台 goroutine
+func (w *batchWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+// Dropped 返回累计丢弃的记录数
+func (w *batchWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// run 后台发送循环
+func (w *batchWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	var reported int64
+	batch := make([][]byte, 0, w.size)
+	flush := func() {
+		if len(batch) > 0 {
+			if err := w.send(batch); err != nil {
+				w.dropped.Add(int64(len(batch)))
+				fmt.Fprintf(os.Stderr, "logger: %s: send failed: %v\n", w.name, err)
+			}
+			batch = make([][]byte, 0, w.size)
+		}
+		if dropped := w.dropped.Load(); dropped > reported {
+			fmt.Fprintf(os.Stderr, "logger: %s: dropped %d records\n", w.name, dropped-reported)
+			reported = dropped
+		}
+	}
+
+	for {
+		select {
+		case p, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, p)
+			if len(batch) >= w.size {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		case flushed := <-w.flushes:
+			// 先取出队列中已有的记录
+			for n := len(w.queue); n > 0; n-- {
+				batch = append(batch, <-w.queue)
+				if len(batch) >= w.size {
+					flush()
+				}
+			}
+			flush()
+			close(flushed)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// everyCounters 记录 [Every] 各调用位置的调用次数，键为调用位置的 PC
+var everyCounters sync.Map
+
+// Every 每个调用位置每 n 次调用才以 INFO 级别记录一次，并附带截至目前的调用次数 (count)
+//
+// 用于循环中的进度日志，不同调用位置分别计数；n <= 1 时每次都记录。
+//
+//	for i, item := range items {
+//	    logger.Every(1000, "处理中", "item", item.ID)
+//	}
+func Every(n int, msg string, attrs ...any) {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // 跳过 runtime.Callers 和 Every 自身
+	counter, ok := everyCounters.Load(pcs[0])
+	if !ok {
+		counter, _ = everyCounters.LoadOrStore(pcs[0], new(atomic.Uint64))
+	}
+	count := counter.(*atomic.Uint64).Add(1)
+	if n > 1 && count%uint64(n) != 0 || !Enabled(slog.LevelInfo) {
+		return
+	}
+	logAt(context.Background(), slog.Default(), slog.LevelInfo, msg, append(attrs[:len(attrs):len(attrs)], "count", count)...)
+}
+
+// onceKey [LogOnce] 的去重键：调用位置和消息
+type onceKey struct {
+	pc  uintptr
+	msg string
+}
+
+// onceMaxKeys onceLogged 中按调用位置和消息记录的最大键数，超出后新的消息只按调用位置去重
+const onceMaxKeys = 1024
+
+var (
+	// onceLogged 记录 [LogOnce] 已经输出过的调用位置和消息
+	onceLogged sync.Map
+	// onceKeys onceLogged 中的键数
+	onceKeys atomic.Int64
+)
+
+// LogOnce 以 level 级别记录日志，同一调用位置的同一消息在进程内只输出一次
+//
+// 适合只需提示一次的警告，例如使用了已废弃的配置；级别未启用时不计入，之后启用仍会输出。
+// 消息应为常量：包含 ID 等动态内容时，记录的键数达到 1024 后，
+// 新的消息按调用位置去重（每个调用位置至多再输出一次），避免内存无限增长。
+//
+//	logger.LogOnce(slog.LevelWarn, "LOG_COLOR 已废弃，请使用 LOG_COLORS")
+func LogOnce(level slog.Level, msg string, attrs ...any) {
+	if !Enabled(level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // 跳过 runtime.Callers 和 LogOnce 自身
+	key := onceKey{pc: pcs[0], msg: msg}
+	if _, ok := onceLogged.Load(key); ok {
+		return
+	}
+	if onceKeys.Load() >= onceMaxKeys {
+		key.msg = ""
+	}
+	if _, loaded := onceLogged.LoadOrStore(key, struct{}{}); loaded {
+		return
+	}
+	onceKeys.Add(1)
+	logAt(context.Background(), slog.Default(), level, msg, attrs...)
+}
@@ -0,0 +1,39 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// clockHandler 使用 Config.TimeFunc 重新设置记录时间的 handler 包装器
+//
+// slog 创建记录时固定使用 time.Now，因此在最外层替换时间，之后的各个环节和所有格式都使用替换后的时间
+type clockHandler struct {
+	inner slog.Handler
+	now   func() time.Time
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *clockHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *clockHandler) Handle(ctx context.Context, r slog.Record) error {
+	// 零值表示不输出时间，保持不变
+	if !r.Time.IsZero() {
+		r.Time = h.now()
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *clockHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &clockHandler{inner: h.inner.WithAttrs(attrs), now: h.now}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *clockHandler) WithGroup(name string) slog.Handler {
+	return &clockHandler{inner: h.inner.WithGroup(name), now: h.now}
+}
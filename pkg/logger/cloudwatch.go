@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// CloudWatch Logs PutLogEvents 的限制：每批最多 10000 条、1 MiB（每条额外计 26 字节），
+// 单条最大 256 KiB
+const (
+	cloudWatchMaxBatchEvents = 10000
+	cloudWatchMaxBatchBytes  = 1 << 20
+	cloudWatchEventOverhead  = 26
+	cloudWatchMaxEventBytes  = 256<<10 - cloudWatchEventOverhead
+)
+
+// CloudWatch 输出的重试参数
+const (
+	cloudWatchMaxRetries   = 5
+	cloudWatchRetryBackoff = 200 * time.Millisecond
+)
+
+func init() {
+	registerOutput("cloudwatch", newCloudWatchOutput)
+}
+
+// ErrCloudWatchThrottled 请求被限流，[CloudWatchClient] 返回包装了它的错误时按指数退避重试
+var ErrCloudWatchThrottled = errors.New("cloudwatch: throttled")
+
+// CloudWatchTokenError 序列号无效，Expected 为服务端期望的序列号
+type CloudWatchTokenError struct {
+	Expected *string
+}
+
+// Error 实现 error 接口
+func (e *CloudWatchTokenError) Error() string {
+	return "cloudwatch: invalid sequence token"
+}
+
+// CloudWatchEvent 一条待发送的日志事件
+type CloudWatchEvent struct {
+	Timestamp int64 // Unix 毫秒
+	Message   string
+}
+
+// CloudWatchClient CloudWatch Logs 的发送接口，由 cloudwatch 子模块使用 AWS SDK 实现
+//
+// PutLogEvents 返回下一次请求使用的序列号；被限流时返回包装了 [ErrCloudWatchThrottled] 的错误，
+// 序列号无效时返回 *[CloudWatchTokenError]
+type CloudWatchClient interface {
+	PutLogEvents(ctx context.Context, events []CloudWatchEvent, token *string) (*string, error)
+}
+
+// newCloudWatchClient 由 [RegisterCloudWatchClient] 设置，未导入 cloudwatch 子模块时为 nil
+var newCloudWatchClient func(group, stream string) (CloudWatchClient, error)
+
+// RegisterCloudWatchClient 注册 CloudWatch Logs 输出使用的客户端，由 cloudwatch 子模块在 init 中调用：
+//
+//	import _ "github.com/lwmacct/251125-go-mod-logger/pkg/logger/cloudwatch"
+//
+// AWS SDK 只由该子模块引入，不使用 CloudWatch 输出的程序不会依赖它
+func RegisterCloudWatchClient(newClient func(group, stream string) (CloudWatchClient, error)) {
+	newCloudWatchClient = newClient
+}
+
+// newCloudWatchOutput 创建 CloudWatch Logs 输出："cloudwatch://log-group/log-stream"
+//
+// 需要导入 cloudwatch 子模块（见 [RegisterCloudWatchClient]），凭证和区域来自 AWS 默认凭证链（环境变量、配置文件、IAM 角色等）。
+// 日志组名可以包含 "/"，最后一段为日志流名称，日志流不存在时自动创建。
+// 日志行的格式为 json（默认）、logfmt 或 text；批量参数和队列长度与 http 输出相同，
+// 每批按 PutLogEvents 的条数和大小限制拆分发送。
+func newCloudWatchOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	path := strings.TrimPrefix(target, "cloudwatch://")
+	i := strings.LastIndex(path, "/")
+	if path == target || i <= 0 || i == len(path)-1 {
+		return nil, nil, fmt.Errorf("cloudwatch output requires cloudwatch://log-group/log-stream: %q", target)
+	}
+
+	if newCloudWatchClient == nil {
+		return nil, nil, errors.New("cloudwatch output requires importing github.com/lwmacct/251125-go-mod-logger/pkg/logger/cloudwatch")
+	}
+	client, err := newCloudWatchClient(path[:i], path[i+1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	sink := &cloudWatchSink{client: client, backoff: cloudWatchRetryBackoff}
+	writer := newBatchWriter(target, sink.send, cfg.BatchSize, cfg.FlushInterval, cfg.BufferSize)
+
+	switch format {
+	case "logfmt", "text":
+	default:
+		format = "json"
+	}
+	return createHandler(cfg, format, &cloudWatchWriter{out: writer, now: time.Now}, level), writer, nil
+}
+
+// cloudWatchWriter 为一条格式化后的日志行加上 8 字节的时间戳（Unix 毫秒）并放入发送队列
+//
+// 与 lokiWriter 相同，时间戳取写入时间，保证同一批内按时间排序
+type cloudWatchWriter struct {
+	out io.Writer
+	now func() time.Time
+}
+
+// Write 实现 io.Writer 接口
+func (w *cloudWatchWriter) Write(p []byte) (int, error) {
+	entry := make([]byte, 0, 8+len(p))
+	entry = binary.BigEndian.AppendUint64(entry, uint64(w.now().UnixMilli()))
+	entry = append(entry, strings.TrimRight(string(p), "\n")...)
+	if _, err := w.out.Write(entry); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// cloudWatchSink 按限制拆分一批记录并发送，维护序列号
+//
+// 只在 batchWriter 的后台 goroutine 中调用，无需加锁
+type cloudWatchSink struct {
+	client  CloudWatchClient
+	token   *string
+	backoff time.Duration // 首次重试前的等待时间，之后每次翻倍
+}
+
+// send 发送一批由 cloudWatchWriter 编码的记录
+func (s *cloudWatchSink) send(batch [][]byte) error {
+	events := make([]CloudWatchEvent, 0, len(batch))
+	for _, entry := range batch {
+		if len(entry) < 8 {
+			continue
+		}
+		events = append(events, CloudWatchEvent{
+			Timestamp: int64(binary.BigEndian.Uint64(entry)),
+			Message:   truncateUTF8(string(entry[8:]), cloudWatchMaxEventBytes),
+		})
+	}
+
+	var errs []error
+	for _, chunk := range splitCloudWatchEvents(events) {
+		if err := s.put(chunk); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// put 发送一次 PutLogEvents，限流时按指数退避重试，序列号无效时使用服务端期望的序列号重试
+func (s *cloudWatchSink) put(events []CloudWatchEvent) error {
+	var err error
+	backoff := s.backoff
+	for attempt := 0; attempt <= cloudWatchMaxRetries; attempt++ {
+		var next *string
+		next, err = s.client.PutLogEvents(context.Background(), events, s.token)
+		if err == nil {
+			s.token = next
+			return nil
+		}
+
+		var tokenErr *CloudWatchTokenError
+		switch {
+		case errors.As(err, &tokenErr):
+			s.token = tokenErr.Expected
+		case errors.Is(err, ErrCloudWatchThrottled):
+			time.Sleep(backoff)
+			backoff *= 2
+		default:
+			return err
+		}
+	}
+	return err
+}
+
+// splitCloudWatchEvents 按 PutLogEvents 的条数和大小限制拆分事件
+func splitCloudWatchEvents(events []CloudWatchEvent) [][]CloudWatchEvent {
+	var chunks [][]CloudWatchEvent
+	start, size := 0, 0
+	for i, event := range events {
+		n := len(event.Message) + cloudWatchEventOverhead
+		if i > start && (i-start >= cloudWatchMaxBatchEvents || size+n > cloudWatchMaxBatchBytes) {
+			chunks = append(chunks, events[start:i])
+			start, size = i, 0
+		}
+		size += n
+	}
+	if start < len(events) {
+		chunks = append(chunks, events[start:])
+	}
+	return chunks
+}
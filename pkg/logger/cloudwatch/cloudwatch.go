@@ -0,0 +1,106 @@
+// Package cloudwatch 使用 AWS SDK 实现 logger 的 CloudWatch Logs 输出
+//
+// 导入后即可使用 "cloudwatch://log-group/log-stream" 输出，凭证和区域来自 AWS 默认凭证链：
+//
+//	import _ "github.com/lwmacct/251125-go-mod-logger/pkg/logger/cloudwatch"
+//
+// 本包是独立的 Go 模块，AWS SDK 只会进入导入了它的程序的依赖。
+package cloudwatch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/smithy-go"
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+)
+
+// requestTimeout 加载配置和每次请求的超时时间
+const requestTimeout = 10 * time.Second
+
+func init() {
+	logger.RegisterCloudWatchClient(newClient)
+}
+
+// client 使用 AWS SDK 实现 logger.CloudWatchClient
+type client struct {
+	client *cloudwatchlogs.Client
+	group  string
+	stream string
+}
+
+// newClient 从 AWS 默认凭证链加载配置并创建客户端
+//
+// 只加载配置，不访问网络；日志流在首次发送时按需创建
+func newClient(group, stream string) (logger.CloudWatchClient, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	awsCfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cloudwatch: load AWS config: %w", err)
+	}
+	return &client{client: cloudwatchlogs.NewFromConfig(awsCfg), group: group, stream: stream}, nil
+}
+
+// PutLogEvents 实现 logger.CloudWatchClient 接口，日志流不存在时创建后重试一次
+func (c *client) PutLogEvents(ctx context.Context, events []logger.CloudWatchEvent, token *string) (*string, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	input := &cloudwatchlogs.PutLogEventsInput{
+		LogGroupName:  aws.String(c.group),
+		LogStreamName: aws.String(c.stream),
+		LogEvents:     make([]types.InputLogEvent, len(events)),
+		SequenceToken: token,
+	}
+	for i, event := range events {
+		input.LogEvents[i] = types.InputLogEvent{Timestamp: aws.Int64(event.Timestamp), Message: aws.String(event.Message)}
+	}
+
+	out, err := c.client.PutLogEvents(ctx, input)
+	var notFound *types.ResourceNotFoundException
+	if errors.As(err, &notFound) {
+		if err = c.createStream(ctx); err != nil {
+			return nil, err
+		}
+		input.SequenceToken = nil
+		out, err = c.client.PutLogEvents(ctx, input)
+	}
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return out.NextSequenceToken, nil
+}
+
+// createStream 创建日志流，已存在时忽略
+func (c *client) createStream(ctx context.Context) error {
+	_, err := c.client.CreateLogStream(ctx, &cloudwatchlogs.CreateLogStreamInput{
+		LogGroupName:  aws.String(c.group),
+		LogStreamName: aws.String(c.stream),
+	})
+	var exists *types.ResourceAlreadyExistsException
+	if err != nil && !errors.As(err, &exists) {
+		return fmt.Errorf("cloudwatch: create log stream %s/%s: %w", c.group, c.stream, err)
+	}
+	return nil
+}
+
+// convertError 将 SDK 错误转换为 logger 识别的限流和序列号错误
+func convertError(err error) error {
+	var invalid *types.InvalidSequenceTokenException
+	if errors.As(err, &invalid) {
+		return &logger.CloudWatchTokenError{Expected: invalid.ExpectedSequenceToken}
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "ThrottlingException" {
+		return fmt.Errorf("%w: %v", logger.ErrCloudWatchThrottled, err)
+	}
+	return err
+}
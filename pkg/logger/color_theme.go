@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// colorThemeLevels 可在配色中覆盖的级别名称
+var colorThemeLevels = map[string]slog.Level{
+	"TRACE": LevelTrace,
+	"DEBUG": slog.LevelDebug,
+	"INFO":  slog.LevelInfo,
+	"WARN":  slog.LevelWarn,
+	"ERROR": slog.LevelError,
+}
+
+// namedColors 颜色名称对应的 SGR 参数
+var namedColors = map[string]string{
+	"black":         "30",
+	"red":           "31",
+	"green":         "32",
+	"yellow":        "33",
+	"blue":          "34",
+	"magenta":       "35",
+	"cyan":          "36",
+	"white":         "37",
+	"gray":          "90",
+	"grey":          "90",
+	"brightblack":   "90",
+	"brightred":     "91",
+	"brightgreen":   "92",
+	"brightyellow":  "93",
+	"brightblue":    "94",
+	"brightmagenta": "95",
+	"brightcyan":    "96",
+	"brightwhite":   "97",
+}
+
+// colorTheme 彩色 handler 使用的配色
+type colorTheme struct {
+	levels map[slog.Level]string
+	keys   map[string]string
+}
+
+// newColorTheme 在默认配色的基础上应用覆盖项，无法解析的颜色被忽略
+//
+// 覆盖项的键为大写级别名称（TRACE、DEBUG、INFO、WARN、ERROR）或字段名，
+// 字段名中 msg 表示消息，other 表示未单独配置的其他属性
+func newColorTheme(overrides map[string]string) *colorTheme {
+	theme := &colorTheme{
+		levels: make(map[slog.Level]string, len(levelColors)),
+		keys:   make(map[string]string, len(keyColors)),
+	}
+	for level, color := range levelColors {
+		theme.levels[level] = color
+	}
+	for key, color := range keyColors {
+		theme.keys[key] = color
+	}
+
+	for name, value := range overrides {
+		color, ok := parseColor(value)
+		if !ok {
+			continue
+		}
+		if level, ok := colorThemeLevels[name]; ok {
+			theme.levels[level] = color
+		} else {
+			theme.keys[name] = color
+		}
+	}
+	return theme
+}
+
+// levelColor 返回日志级别对应的颜色，自定义级别使用不高于它的最近内置级别的颜色
+func (t *colorTheme) levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return t.levels[slog.LevelError]
+	case level >= slog.LevelWarn:
+		return t.levels[slog.LevelWarn]
+	case level >= slog.LevelInfo:
+		return t.levels[slog.LevelInfo]
+	case level >= slog.LevelDebug:
+		return t.levels[slog.LevelDebug]
+	default:
+		return t.levels[LevelTrace]
+	}
+}
+
+// keyColor 返回字段对应的颜色，未配置的字段使用 other 的颜色
+func (t *colorTheme) keyColor(key string) string {
+	if color, ok := t.keys[key]; ok {
+		return color
+	}
+	return t.keys["other"]
+}
+
+// parseColor 将颜色配置解析为 ANSI 转义序列
+//
+// 支持颜色名称（red、brightblue 等）、SGR 参数（"31"、"1;31"）、
+// 完整的转义序列（"\033[31m"），以及 none 表示不着色
+func parseColor(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "\033[") && strings.HasSuffix(s, "m") {
+		return s, true
+	}
+
+	name := strings.ToLower(s)
+	if name == "none" {
+		return "", true
+	}
+	if code, ok := namedColors[name]; ok {
+		return "\033[" + code + "m", true
+	}
+
+	if s == "" || strings.Trim(s, "0123456789;") != "" {
+		return "", false
+	}
+	return "\033[" + s + "m", true
+}
+
+// validateColors 检查配色中的颜色是否都能解析
+func validateColors(colors map[string]string) error {
+	for name, value := range colors {
+		if _, ok := parseColor(value); !ok {
+			return fmt.Errorf("invalid color for %q: %q, use a name (e.g. red, brightblue), SGR codes (e.g. 1;31) or an ANSI escape sequence", name, value)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
@@ -13,10 +14,13 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"golang.org/x/term"
 )
 
 // 日志级别颜色映射
 var levelColors = map[slog.Level]string{
+	LevelTrace:      "\033[36m", // 青色
 	slog.LevelDebug: "\033[94m", // 蓝色
 	slog.LevelInfo:  "\033[92m", // 绿色
 	slog.LevelWarn:  "\033[93m", // 黄色
@@ -42,8 +46,8 @@ const colorReset = "\033[0m"
 // 该配置控制日志的显示样式，包括颜色、字段排序和时间格式。
 // 使用 [DefaultColoredConfig] 获取推荐的默认配置。
 type ColoredHandlerConfig struct {
-	// Level 最小日志级别
-	Level slog.Level
+	// Level 最小日志级别，可传入 *slog.LevelVar 以便运行时调整，nil 表示 INFO
+	Level slog.Leveler
 	// AddSource 是否添加源代码位置
 	AddSource bool
 	// EnableColor 是否启用颜色
@@ -54,10 +58,17 @@ type ColoredHandlerConfig struct {
 	PriorityKeys []string
 	// TrailingKeys 固定在尾部的字段（按顺序）
 	TrailingKeys []string
-	// TimeFormat 时间格式: datetime (默认), rfc3339, rfc3339ms, time, timems
+	// TimeFormat 时间格式: datetime (默认), rfc3339, rfc3339ms, time, timems，或自定义 Go 时间格式
 	TimeFormat string
 	// Timezone 时区名称，例如 "Asia/Shanghai"
 	Timezone string
+	// ReplaceAttr 属性替换函数，语义与 slog.HandlerOptions.ReplaceAttr 相同
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+	// Colors 覆盖默认配色，键为大写级别名称 (TRACE ... ERROR) 或字段名 (time, msg, source, other 表示其他属性)，
+	// 值为颜色名称 (red, brightblue)、SGR 参数 ("1;31") 或 ANSI 转义序列，未配置的保持默认
+	Colors map[string]string
+	// AlignColumns 将 level 补齐到固定宽度，使之后的字段起始位置一致
+	AlignColumns bool
 }
 
 // DefaultColoredConfig 返回彩色日志的默认配置
@@ -79,6 +90,31 @@ func DefaultColoredConfig() *ColoredHandlerConfig {
 	}
 }
 
+// colorEnabled 判断输出到 w 时是否启用颜色
+//
+// 遵循 NO_COLOR (https://no-color.org) 约定：设置后始终禁用颜色；
+// 否则设置 FORCE_COLOR 时始终启用；都未设置时仅在 w 为终端时启用，
+// 避免重定向到文件或管道时写入 ANSI 转义序列
+func colorEnabled(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	if os.Getenv("FORCE_COLOR") != "" {
+		return true
+	}
+	if line, ok := w.(*lineWriter); ok {
+		w = line.out
+	}
+	if async, ok := w.(*asyncWriter); ok {
+		w = async.out
+	}
+	if pipe, ok := w.(*pipeWriter); ok {
+		w = pipe.file
+	}
+	f, ok := w.(*os.File)
+	return ok && term.IsTerminal(int(f.Fd()))
+}
+
 // coloredHandler 支持彩色输出和字段排序的 handler
 type coloredHandler struct {
 	writer   io.Writer
@@ -87,12 +123,13 @@ type coloredHandler struct {
 	attrs    []slog.Attr
 	mu       sync.Mutex
 	location *time.Location // 缓存的时区
+	theme    *colorTheme
 }
 
 // NewColoredHandler 创建支持 ANSI 颜色输出的 slog.Handler
 //
 // 该 handler 适用于终端输出，提供以下特性：
-//   - 根据日志级别着色（DEBUG 蓝色、INFO 绿色、WARN 黄色、ERROR 红色）
+//   - 根据日志级别着色（TRACE 青色、DEBUG 蓝色、INFO 绿色、WARN 黄色、ERROR 红色）
 //   - 自动平铺 JSON 字符串和嵌套 map
 //   - 可配置的字段显示顺序
 //   - 自动裁剪 /workspace/ 路径前缀
@@ -115,12 +152,17 @@ func NewColoredHandler(w io.Writer, config *ColoredHandlerConfig) *coloredHandle
 		writer:   w,
 		config:   config,
 		location: loc,
+		theme:    newColorTheme(config.Colors),
 	}
 }
 
 // Enabled 实现 slog.Handler 接口
 func (h *coloredHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return level >= h.config.Level
+	minLevel := slog.LevelInfo
+	if h.config.Level != nil {
+		minLevel = h.config.Level.Level()
+	}
+	return level >= minLevel
 }
 
 // Handle 实现 slog.Handler 接口
@@ -128,18 +170,19 @@ func (h *coloredHandler) Handle(ctx context.Context, r slog.Record) error {
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
-	// 收集所有字段
+	// 收集所有字段，names 记录内置字段经 ReplaceAttr 重命名后的键
 	fields := make(map[string]string)
+	names := make(map[string]string, 4)
 
 	// 添加时间
-	fields["time"] = h.getFormattedTime(r.Time)
+	h.addBuiltin(fields, names, "time", h.getFormattedTime(r.Time))
 
 	// 添加级别
-	fields["level"] = r.Level.String()
+	h.addBuiltin(fields, names, "level", levelName(r.Level))
 
 	// 添加消息
 	if r.Message != "" {
-		fields["msg"] = r.Message
+		h.addBuiltin(fields, names, "msg", r.Message)
 	}
 
 	// 添加源代码位置
@@ -147,8 +190,11 @@ func (h *coloredHandler) Handle(ctx context.Context, r slog.Record) error {
 		fs := runtime.CallersFrames([]uintptr{r.PC})
 		f, _ := fs.Next()
 		if f.File != "" {
-			source := fmt.Sprintf("%s:%d", f.File, f.Line)
-			fields["source"] = h.clipPath(source)
+			src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			if a, ok := sourceAttr(h.config.ReplaceAttr, src, h.clipPath); ok {
+				fields[a.Key] = h.formatValue(a.Value)
+				names["source"] = a.Key
+			}
 		}
 	}
 
@@ -159,13 +205,12 @@ func (h *coloredHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// 添加记录中的属性（需要加上当前 group 前缀）
 	r.Attrs(func(a slog.Attr) bool {
-		key := h.buildKey(a.Key)
-		h.flattenAttr(fields, key, a.Value)
+		h.addAttr(fields, a)
 		return true
 	})
 
 	// 格式化输出
-	output := h.formatFields(fields)
+	output := h.formatFields(fields, names, r.Level)
 
 	// 写入
 	_, err := h.writer.Write([]byte(output + "\n"))
@@ -178,6 +223,17 @@ func (h *coloredHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	newAttrs := make([]slog.Attr, 0, len(h.attrs)+len(attrs))
 	newAttrs = append(newAttrs, h.attrs...)
 	for _, attr := range attrs {
+		attr, ok := replaceAttr(h.config.ReplaceAttr, h.groups, attr)
+		if !ok {
+			continue
+		}
+		// 空键分组内联到当前层级
+		if attr.Key == "" && attr.Value.Kind() == slog.KindGroup {
+			for _, child := range attr.Value.Group() {
+				newAttrs = append(newAttrs, slog.Attr{Key: h.buildKey(child.Key), Value: child.Value})
+			}
+			continue
+		}
 		key := h.buildKey(attr.Key)
 		newAttrs = append(newAttrs, slog.Attr{Key: key, Value: attr.Value})
 	}
@@ -188,6 +244,7 @@ func (h *coloredHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		groups:   h.groups,
 		attrs:    newAttrs,
 		location: h.location,
+		theme:    h.theme,
 	}
 }
 
@@ -199,7 +256,42 @@ func (h *coloredHandler) WithGroup(name string) slog.Handler {
 		groups:   append(slices.Clip(h.groups), name),
 		attrs:    h.attrs,
 		location: h.location,
+		theme:    h.theme,
+	}
+}
+
+// addBuiltin 添加内置字段（time、level、msg、source），同样经过 ReplaceAttr 处理
+//
+// 传给 ReplaceAttr 的是已格式化的字符串，返回空键时丢弃该字段；
+// 字段以 ReplaceAttr 返回的键保存，names 记录原字段名对应的键
+func (h *coloredHandler) addBuiltin(fields, names map[string]string, key, value string) {
+	if h.config.ReplaceAttr == nil {
+		fields[key] = value
+		names[key] = key
+		return
+	}
+	a := h.config.ReplaceAttr(nil, slog.String(key, value))
+	if a.Key == "" {
+		return
 	}
+	fields[a.Key] = h.formatValue(a.Value.Resolve())
+	names[key] = a.Key
+}
+
+// addAttr 对记录中的属性应用 ReplaceAttr 后平铺到 fields 中
+func (h *coloredHandler) addAttr(fields map[string]string, a slog.Attr) {
+	a, ok := replaceAttr(h.config.ReplaceAttr, h.groups, a)
+	if !ok {
+		return
+	}
+	// 空键分组内联到当前层级
+	if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+		for _, attr := range a.Value.Group() {
+			h.flattenAttr(fields, h.buildKey(attr.Key), attr.Value)
+		}
+		return
+	}
+	h.flattenAttr(fields, h.buildKey(a.Key), a.Value)
 }
 
 // getFormattedTime 根据配置格式化时间
@@ -209,22 +301,7 @@ func (h *coloredHandler) getFormattedTime(t time.Time) string {
 		t = t.In(h.location)
 	}
 
-	switch h.config.TimeFormat {
-	case "rfc3339":
-		return t.Format(time.RFC3339)
-	case "rfc3339ms":
-		return t.Format("2006-01-02T15:04:05.000Z07:00")
-	case "time":
-		return t.Format("15:04:05")
-	case "timems":
-		return t.Format("15:04:05.000")
-	case "datetime", "":
-		// 默认格式：日期时间（秒精度）
-		return t.Format("2006-01-02 15:04:05")
-	default:
-		// 自定义格式
-		return t.Format(h.config.TimeFormat)
-	}
+	return formatTimeString(t, h.config.TimeFormat)
 }
 
 // buildKey 根据当前 groups 构建完整的 key
@@ -450,7 +527,22 @@ func escapeString(s string) string {
 }
 
 // formatFields 格式化字段为彩色输出
-func (h *coloredHandler) formatFields(fields map[string]string) string {
+//
+// names 为内置字段名到实际键的映射，PriorityKeys、TrailingKeys 和配色按内置字段名匹配，
+// 因此内置字段被重命名后仍保持原来的位置和颜色
+func (h *coloredHandler) formatFields(fields, names map[string]string, level slog.Level) string {
+	// roles 为实际键到内置字段名的映射
+	roles := make(map[string]string, len(names))
+	for role, key := range names {
+		roles[key] = role
+	}
+	resolve := func(key string) string {
+		if name, ok := names[key]; ok {
+			return name
+		}
+		return key
+	}
+
 	// 分类字段
 	priorityFields := make([]string, 0, len(h.config.PriorityKeys))
 	trailingFields := make([]string, 0, len(h.config.TrailingKeys))
@@ -461,6 +553,7 @@ func (h *coloredHandler) formatFields(fields map[string]string) string {
 	trailingSet := make(map[string]bool)
 
 	for _, key := range h.config.PriorityKeys {
+		key = resolve(key)
 		prioritySet[key] = true
 		if _, exists := fields[key]; exists {
 			priorityFields = append(priorityFields, key)
@@ -468,6 +561,7 @@ func (h *coloredHandler) formatFields(fields map[string]string) string {
 	}
 
 	for _, key := range h.config.TrailingKeys {
+		key = resolve(key)
 		trailingSet[key] = true
 		if _, exists := fields[key]; exists {
 			trailingFields = append(trailingFields, key)
@@ -495,6 +589,7 @@ func (h *coloredHandler) formatFields(fields map[string]string) string {
 	builder.WriteByte('{')
 
 	first := true
+	pad := 0 // 对齐时 level 之后需要补齐的空格数
 	for _, key := range allKeys {
 		value, exists := fields[key]
 		if !exists {
@@ -503,8 +598,18 @@ func (h *coloredHandler) formatFields(fields map[string]string) string {
 
 		if !first {
 			builder.WriteByte(',')
+			for ; pad > 0; pad-- {
+				builder.WriteByte(' ')
+			}
 		}
 		first = false
+		role := key
+		if r, ok := roles[key]; ok {
+			role = r
+		}
+		if role == "level" && h.config.AlignColumns {
+			pad = levelColumnWidth - len(value)
+		}
 
 		// 写入键
 		builder.WriteByte('"')
@@ -513,7 +618,7 @@ func (h *coloredHandler) formatFields(fields map[string]string) string {
 
 		// 写入值（带颜色）
 		if h.config.EnableColor {
-			h.writeColoredValue(&builder, key, value)
+			h.writeColoredValue(&builder, role, value, level)
 		} else {
 			builder.WriteString(value)
 		}
@@ -525,26 +630,18 @@ func (h *coloredHandler) formatFields(fields map[string]string) string {
 	return builder.String()
 }
 
-// writeColoredValue 写入带颜色的值
-func (h *coloredHandler) writeColoredValue(builder *strings.Builder, key, value string) {
+// writeColoredValue 写入带颜色的值，key 为内置字段名或属性的键
+func (h *coloredHandler) writeColoredValue(builder *strings.Builder, key, value string, level slog.Level) {
 	// 特殊处理 level 字段
 	if key == "level" {
-		level := parseLevel(value) // 使用包级别函数
-		if color, ok := levelColors[level]; ok {
-			builder.WriteString(color)
-			builder.WriteString(value)
-			builder.WriteString(colorReset)
-			return
-		}
+		builder.WriteString(h.theme.levelColor(level))
+		builder.WriteString(value)
+		builder.WriteString(colorReset)
+		return
 	}
 
 	// 使用字段颜色
-	color, ok := keyColors[key]
-	if !ok {
-		color = keyColors["other"]
-	}
-
-	if color != "" {
+	if color := h.theme.keyColor(key); color != "" {
 		builder.WriteString(color)
 		builder.WriteString(value)
 		builder.WriteString(colorReset)
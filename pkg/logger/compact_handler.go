@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// compactHandler 面向命令行工具的精简格式（compact）handler
+//
+// 输出示例：hello user=42
+// 不输出时间和级别，WARN 及以上的记录以 "warning: " / "error: " 开头；属性与 logfmt 相同，
+// 分组内的属性以 "group.key" 形式输出。用于展示给用户的 CLI 输出，服务端日志应使用其他格式。
+type compactHandler struct {
+	opts     *slog.HandlerOptions
+	writer   io.Writer
+	mu       *sync.Mutex
+	groups   []string // 当前 group 路径
+	preAttrs []byte   // 预先格式化的属性（已包含 group 前缀）
+}
+
+// newCompactHandler 创建 compact handler
+func newCompactHandler(w io.Writer, opts *slog.HandlerOptions) *compactHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	return &compactHandler{opts: opts, writer: w, mu: &sync.Mutex{}}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *compactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *compactHandler) Handle(ctx context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+	switch {
+	case r.Level >= slog.LevelError:
+		buf = append(buf, "error: "...)
+	case r.Level >= slog.LevelWarn:
+		buf = append(buf, "warning: "...)
+	}
+
+	// 消息原样输出，不加引号，同样经过 ReplaceAttr 处理
+	msg := slog.String(slog.MessageKey, r.Message)
+	if h.opts.ReplaceAttr != nil {
+		msg = h.opts.ReplaceAttr(nil, msg)
+	}
+	if msg.Key != "" {
+		buf = append(buf, logfmtValue(msg.Value.Resolve())...)
+	}
+	start := len(buf)
+
+	buf = append(buf, h.preAttrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, a)
+		return true
+	})
+
+	// 添加源代码位置（如果启用），放在最后
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		if f.File != "" {
+			src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			if a, ok := sourceAttr(h.opts.ReplaceAttr, src, clipWorkspacePath); ok {
+				buf = appendLogfmtPair(buf, a.Key, logfmtValue(a.Value))
+			}
+		}
+	}
+
+	// 消息为空时去掉属性开头的空格
+	if (start == 0 || buf[start-1] == ' ') && len(buf) > start && buf[start] == ' ' {
+		buf = append(buf[:start], buf[start+1:]...)
+	}
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.writer.Write(buf)
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *compactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	preAttrs := append([]byte(nil), h.preAttrs...)
+	for _, attr := range attrs {
+		preAttrs = h.appendAttr(preAttrs, attr)
+	}
+
+	return &compactHandler{
+		opts:     h.opts,
+		writer:   h.writer,
+		mu:       h.mu,
+		groups:   h.groups,
+		preAttrs: preAttrs,
+	}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *compactHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &compactHandler{
+		opts:     h.opts,
+		writer:   h.writer,
+		mu:       h.mu,
+		groups:   append(h.groups[:len(h.groups):len(h.groups)], name),
+		preAttrs: h.preAttrs,
+	}
+}
+
+// appendAttr 对属性应用 ReplaceAttr 后追加到当前 group 路径下
+func (h *compactHandler) appendAttr(buf []byte, a slog.Attr) []byte {
+	a, ok := replaceAttr(h.opts.ReplaceAttr, h.groups, a)
+	if !ok {
+		return buf
+	}
+	prefix := ""
+	if len(h.groups) > 0 {
+		prefix = strings.Join(h.groups, ".") + "."
+	}
+	return appendLogfmtAttr(buf, prefix, a)
+}
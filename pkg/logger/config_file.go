@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// InitFromFile 从 YAML 或 JSON 配置文件初始化全局日志系统
+//
+// 按扩展名识别格式 (.yaml、.yml、.json)，字段名为 snake_case，例如：
+//
+//	level: DEBUG
+//	format: json
+//	default_attrs:
+//	  service: api
+//	dedup_window: 5s
+//
+// 未知字段会返回错误，便于发现拼写错误；文件未设置的字段使用默认值。
+// 已设置的 LOG_* 环境变量会覆盖文件中的值（见 [InitEnv]）。
+func InitFromFile(path string) error {
+	cfg, err := loadConfigFile(path)
+	if err != nil {
+		return err
+	}
+	applyEnv(cfg)
+	if err := InitCfg(cfg); err != nil {
+		return err
+	}
+	setReloadFile(path)
+	return nil
+}
+
+// loadConfigFile 读取配置文件，未设置的字段使用默认配置
+//
+// JSON 是 YAML 的子集，两种格式统一使用 YAML 解析，时长字段可写为 "5s" 等字符串
+func loadConfigFile(path string) (*Config, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml", ".json":
+	default:
+		return nil, fmt.Errorf("unsupported config file extension: %q, valid options: .yaml, .yml, .json", ext)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	cfg := defaultConfig()
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return cfg, nil
+}
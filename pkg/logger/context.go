@@ -2,7 +2,12 @@ package logger
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base32"
 	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // contextKey 是用于 context 中存储 logger 的键类型
@@ -10,17 +15,32 @@ type contextKey struct{}
 
 var loggerKey = contextKey{}
 
-// WithLogger 将 logger 存入 context
+// correlationIDKey 是用于 context 中存储关联 ID 的键类型
+type correlationIDKey struct{}
+
+// correlationIDEncoding 关联 ID 的编码：小写、无填充的 base32
+var correlationIDEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// WithContext 将 logger 存入 context
 //
-// 用于在请求处理链路中传递带有特定上下文信息的 logger
-func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+// 用于在请求处理链路中传递带有特定上下文信息（如 trace ID）的 logger，
+// 下游通过 [FromContext] 取出，[LogError] 也会优先使用其中的 logger
+func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
 	return context.WithValue(ctx, loggerKey, logger)
 }
 
+// WithLogger 将 logger 存入 context，等同于 [WithContext]
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return WithContext(ctx, logger)
+}
+
 // FromContext 从 context 中获取 logger
 //
-// 如果 context 中没有 logger，则返回全局默认 logger
+// 如果 context 中没有 logger 或 ctx 为 nil，则返回全局默认 logger
 func FromContext(ctx context.Context) *slog.Logger {
+	if ctx == nil {
+		return slog.Default()
+	}
 	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
 		return logger
 	}
@@ -32,5 +52,101 @@ func FromContext(ctx context.Context) *slog.Logger {
 // 常用于 HTTP 请求处理，用于追踪单个请求的日志
 func WithRequestID(ctx context.Context, requestID string) context.Context {
 	logger := FromContext(ctx).With("request_id", requestID)
-	return WithLogger(ctx, logger)
+	return WithContext(ctx, logger)
+}
+
+// NewCorrelationID 生成 16 个字符的随机关联 ID（10 字节随机数的 base32 编码，小写）
+func NewCorrelationID() string {
+	var b [10]byte
+	_, _ = rand.Read(b[:])
+	return strings.ToLower(correlationIDEncoding.EncodeToString(b[:]))
+}
+
+// WithCorrelationID 将关联 ID 存入 context
+//
+// 不需要完整的 OpenTelemetry 时用于串联一次请求（包括跨服务调用）的日志：
+// 通过 InfoContext 等带 context 的方法记录日志时，自动添加 correlation_id 属性。
+// [Middleware] 会从请求头 X-Correlation-ID 读取或生成关联 ID 并存入请求的 context
+func WithCorrelationID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, correlationIDKey{}, id)
+}
+
+// CorrelationID 返回 context 中的关联 ID，没有时返回空字符串
+//
+// 可用于将关联 ID 传递给下游服务，例如设置到出站请求的 X-Correlation-ID 头
+func CorrelationID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(correlationIDKey{}).(string)
+	return id
+}
+
+var (
+	// extractorsMu 串行化 RegisterContextExtractor
+	extractorsMu sync.Mutex
+	// contextExtractors 已注册的 context 属性提取函数，注册时整体替换（写时复制）
+	contextExtractors atomic.Pointer[[]func(ctx context.Context) []any]
+)
+
+// RegisterContextExtractor 注册从 context 中提取属性的函数
+//
+// 通过 InfoContext 等带 context 的方法记录日志时，依次调用已注册的函数，
+// 将返回的键值对添加到记录中；返回 nil 或空切片时不添加属性。fn 应当开销很小且并发安全：
+//
+//	logger.RegisterContextExtractor(func(ctx context.Context) []any {
+//	    if id, ok := ctx.Value(tenantKey{}).(string); ok {
+//	        return []any{"tenant_id", id}
+//	    }
+//	    return nil
+//	})
+func RegisterContextExtractor(fn func(ctx context.Context) []any) {
+	if fn == nil {
+		return
+	}
+	extractorsMu.Lock()
+	defer extractorsMu.Unlock()
+
+	var fns []func(ctx context.Context) []any
+	if current := contextExtractors.Load(); current != nil {
+		fns = append(fns, *current...)
+	}
+	fns = append(fns, fn)
+	contextExtractors.Store(&fns)
+}
+
+// contextHandler 为记录添加 context 中的关联 ID（见 [WithCorrelationID]）
+// 和已注册的提取函数（见 [RegisterContextExtractor]）返回的属性
+type contextHandler struct {
+	inner slog.Handler
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := CorrelationID(ctx); id != "" {
+		r.AddAttrs(slog.String("correlation_id", id))
+	}
+	if fns := contextExtractors.Load(); fns != nil && ctx != nil {
+		for _, fn := range *fns {
+			if attrs := fn(ctx); len(attrs) > 0 {
+				r.Add(attrs...)
+			}
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{inner: h.inner.WithAttrs(attrs)}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{inner: h.inner.WithGroup(name)}
 }
@@ -0,0 +1,151 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// dedupHandler 将窗口期内连续重复的记录合并为一条的 handler 包装
+//
+// 级别、消息和属性（包括 With 添加的属性）都相同的连续记录视为重复。
+// 记录先暂存，遇到不同的记录、窗口到期或 Sync/Close 时输出，
+// 重复超过一次的记录带上 count 属性表示重复次数。
+type dedupHandler struct {
+	inner slog.Handler
+	scope string // With 添加的属性和分组，参与重复判断
+	state *dedupState
+}
+
+// dedupState 同一 handler 及其派生 handler 共享的暂存状态
+type dedupState struct {
+	window  time.Duration
+	merged  atomic.Int64 // 上次输出汇总后被合并的重复记录数，见 [suppressionSummary]
+	mu      sync.Mutex
+	pending *dedupPending
+}
+
+// dedupPending 暂存的记录及其重复次数
+type dedupPending struct {
+	key     string
+	ctx     context.Context
+	handler slog.Handler
+	record  slog.Record
+	count   int
+	timer   *time.Timer
+}
+
+// newDedupHandler 创建去重 handler
+func newDedupHandler(inner slog.Handler, window time.Duration) *dedupHandler {
+	return &dedupHandler{inner: inner, state: &dedupState{window: window}}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *dedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *dedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	key := h.key(r)
+
+	s := h.state
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p := s.pending; p != nil && p.key == key {
+		p.count++
+		s.merged.Add(1)
+		return nil
+	}
+
+	err := s.flushLocked()
+	p := &dedupPending{
+		key:     key,
+		ctx:     context.WithoutCancel(ctx),
+		handler: h.inner,
+		record:  r.Clone(),
+		count:   1,
+	}
+	p.timer = time.AfterFunc(s.window, func() { _ = s.flushPending(p) })
+	s.pending = p
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *dedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	b.WriteString(h.scope)
+	for _, a := range attrs {
+		writeDedupAttr(&b, a)
+	}
+	return &dedupHandler{inner: h.inner.WithAttrs(attrs), scope: b.String(), state: h.state}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *dedupHandler) WithGroup(name string) slog.Handler {
+	return &dedupHandler{inner: h.inner.WithGroup(name), scope: h.scope + "[" + name + "]", state: h.state}
+}
+
+// Sync 输出暂存的记录
+func (s *dedupState) Sync() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flushLocked()
+}
+
+// Close 实现 io.Closer 接口，输出暂存的记录
+func (s *dedupState) Close() error {
+	return s.Sync()
+}
+
+// flushPending 窗口到期时输出 p，如果 p 已被输出则忽略
+func (s *dedupState) flushPending(p *dedupPending) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending != p {
+		return nil
+	}
+	return s.flushLocked()
+}
+
+// flushLocked 输出暂存的记录（调用方需持有锁）
+func (s *dedupState) flushLocked() error {
+	p := s.pending
+	if p == nil {
+		return nil
+	}
+	s.pending = nil
+	p.timer.Stop()
+
+	if p.count > 1 {
+		p.record.AddAttrs(slog.Int("count", p.count))
+	}
+	return p.handler.Handle(p.ctx, p.record)
+}
+
+// key 返回用于判断重复的记录标识
+func (h *dedupHandler) key(r slog.Record) string {
+	var b strings.Builder
+	b.WriteString(h.scope)
+	b.WriteByte('|')
+	b.WriteString(r.Level.String())
+	b.WriteByte('|')
+	b.WriteString(r.Message)
+	r.Attrs(func(a slog.Attr) bool {
+		writeDedupAttr(&b, a)
+		return true
+	})
+	return b.String()
+}
+
+// writeDedupAttr 将属性写入标识
+func writeDedupAttr(b *strings.Builder, a slog.Attr) {
+	b.WriteByte('|')
+	b.WriteString(a.Key)
+	b.WriteByte('=')
+	b.WriteString(a.Value.Resolve().String())
+}
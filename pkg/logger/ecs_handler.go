@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ecsVersion 输出遵循的 ECS（Elastic Common Schema）版本
+const ecsVersion = "8.11.0"
+
+// ecsTimeFormat ECS @timestamp 的格式：UTC，毫秒精度
+const ecsTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// ecsHandler 输出 ECS（Elastic Common Schema）JSON 的 handler，可直接写入 Elasticsearch
+//
+// 每条记录输出为一行 JSON：@timestamp（UTC）、log.level（小写级别名称）、message、ecs.version，
+// 启用 AddSource 时添加 log.origin.file.name、log.origin.file.line 和 log.origin.function。
+// 顶级的 error 属性展开为 error.message 和 error.type，stacktrace 属性（见 StacktraceLevel）
+// 写入 error.stack_trace；其他属性与 json 格式相同，分组输出为嵌套对象。
+// 这些协议字段是固定的，不受 TimeFormat、Timezone 和 KeyMap 影响，ReplaceAttr 只作用于属性。
+type ecsHandler struct {
+	opts     *slog.HandlerOptions
+	writer   io.Writer
+	mu       sync.Mutex
+	groups   []string       // 当前 group 路径
+	preAttrs map[string]any // 预计算的属性（已考虑 group 嵌套）
+}
+
+// newECSHandler 创建 ECS handler
+func newECSHandler(w io.Writer, opts *slog.HandlerOptions) *ecsHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	return &ecsHandler{
+		opts:     opts,
+		writer:   w,
+		preAttrs: make(map[string]any),
+	}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *ecsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *ecsHandler) Handle(ctx context.Context, r slog.Record) error {
+	m := make(map[string]any, len(h.preAttrs)+r.NumAttrs()+4)
+	for k, v := range h.preAttrs {
+		m[k] = deepCopyValue(v)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.addAttr(m, a)
+		return true
+	})
+
+	// 协议字段最后设置，避免被属性覆盖
+	m["@timestamp"] = r.Time.UTC().Format(ecsTimeFormat)
+	m["message"] = replaceMessage(h.opts.ReplaceAttr, r.Message)
+	setNestedAttr(m, []string{"log"}, "level", strings.ToLower(levelName(r.Level)))
+	setNestedAttr(m, []string{"ecs"}, "version", ecsVersion)
+
+	// 添加源代码位置（如果启用）
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		if f.File != "" {
+			src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			if _, ok := sourceAttr(h.opts.ReplaceAttr, src, clipWorkspacePath); ok {
+				setNestedAttr(m, []string{"log", "origin"}, "function", f.Function)
+				setNestedAttr(m, []string{"log", "origin", "file"}, "name", clipWorkspacePath(f.File))
+				setNestedAttr(m, []string{"log", "origin", "file"}, "line", f.Line)
+			}
+		}
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.writer.Write(append(data, '\n'))
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *ecsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	preAttrs := make(map[string]any, len(h.preAttrs)+len(attrs))
+	for k, v := range h.preAttrs {
+		preAttrs[k] = deepCopyValue(v)
+	}
+	for _, attr := range attrs {
+		h.addAttr(preAttrs, attr)
+	}
+
+	return &ecsHandler{
+		opts:     h.opts,
+		writer:   h.writer,
+		groups:   h.groups,
+		preAttrs: preAttrs,
+	}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *ecsHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &ecsHandler{
+		opts:     h.opts,
+		writer:   h.writer,
+		groups:   append(h.groups[:len(h.groups):len(h.groups)], name),
+		preAttrs: h.preAttrs,
+	}
+}
+
+// addAttr 对属性应用 ReplaceAttr 后添加到当前 group 路径下，顶级的 error 和 stacktrace 写入 ECS 的 error 对象
+func (h *ecsHandler) addAttr(m map[string]any, a slog.Attr) {
+	a, ok := replaceAttr(h.opts.ReplaceAttr, h.groups, a)
+	if !ok {
+		return
+	}
+	if len(h.groups) == 0 {
+		switch a.Key {
+		case "error":
+			addECSError(m, a.Value)
+			return
+		case "stacktrace":
+			setNestedAttr(m, []string{"error"}, "stack_trace", a.Value.String())
+			return
+		}
+	}
+	// 空键分组内联到当前层级
+	if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+		for _, attr := range a.Value.Group() {
+			setNestedAttr(m, h.groups, attr.Key, jsonValue(attr.Value))
+		}
+		return
+	}
+	setNestedAttr(m, h.groups, a.Key, jsonValue(a.Value))
+}
+
+// addECSError 将 error 属性展开为 error.message 和 error.type（值为 error 时），
+// 启用 ErrorChain 时展开后的字段直接写入 error 对象
+func addECSError(m map[string]any, v slog.Value) {
+	if v.Kind() == slog.KindGroup {
+		for _, attr := range v.Group() {
+			setNestedAttr(m, []string{"error"}, attr.Key, jsonValue(attr.Value))
+		}
+		return
+	}
+	if err, ok := v.Any().(error); ok && v.Kind() == slog.KindAny {
+		setNestedAttr(m, []string{"error"}, "message", err.Error())
+		setNestedAttr(m, []string{"error"}, "type", fmt.Sprintf("%T", err))
+		return
+	}
+	setNestedAttr(m, []string{"error"}, "message", v.String())
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// AttrError 携带日志属性的错误，[LogError] 等函数记录时自动合并其中的属性
+//
+// 属性随错误沿调用栈向上传递，记录日志的位置无需重复指定：
+//
+//	return logger.WrapError(err, "查询订单失败", "order_id", id)
+//	...
+//	logger.LogError(ctx, "处理请求失败", err) // 自动带上 order_id
+//
+// 由于包中已有 [Error] 日志函数，类型命名为 AttrError
+type AttrError struct {
+	// Msg 错误信息
+	Msg string
+	// Attrs 键值对形式的属性，与 Info 等函数的 attrs 参数相同
+	Attrs []any
+	// Err 被包装的错误，可以为 nil
+	Err error
+}
+
+// NewError 创建携带属性的错误
+func NewError(msg string, attrs ...any) *AttrError {
+	return &AttrError{Msg: msg, Attrs: attrs}
+}
+
+// WrapError 包装 err 并附带属性，err 为 nil 时返回 nil
+func WrapError(err error, msg string, attrs ...any) error {
+	if err == nil {
+		return nil
+	}
+	return &AttrError{Msg: msg, Attrs: attrs, Err: err}
+}
+
+// Error 实现 error 接口，格式为 "Msg: Err"
+func (e *AttrError) Error() string {
+	switch {
+	case e.Err == nil:
+		return e.Msg
+	case e.Msg == "":
+		return e.Err.Error()
+	default:
+		return e.Msg + ": " + e.Err.Error()
+	}
+}
+
+// Unwrap 返回被包装的错误
+func (e *AttrError) Unwrap() error {
+	return e.Err
+}
+
+// errorAttrs 收集错误链中所有 AttrError 的属性，外层在前
+func errorAttrs(err error) []any {
+	var attrs []any
+	var e *AttrError
+	for errors.As(err, &e) {
+		attrs = append(attrs, e.Attrs...)
+		err = e.Err
+	}
+	return attrs
+}
+
+// errorChainValue 将错误展开为 message（完整的错误信息）和 chain 两个字段的分组，见 [Config].ErrorChain
+//
+// chain 按 Unwrap 的顺序列出链上的每一层错误，外层在前：message 为该层自身的信息（去掉被包装错误的部分），
+// AttrError 的属性经过 replace 处理后一并加入；Unwrap() []error（如 errors.Join）的错误作为链的末端
+func errorChainValue(err error, replace func(slog.Attr) slog.Attr) slog.Value {
+	var chain []any
+	for e := err; e != nil; {
+		next := errors.Unwrap(e)
+		entry := map[string]any{"message": errorMessage(e, next)}
+		if ae, ok := e.(*AttrError); ok {
+			for _, a := range slog.Group("", ae.Attrs...).Value.Group() {
+				a = replace(a)
+				entry[a.Key] = jsonValue(a.Value.Resolve())
+			}
+		}
+		chain = append(chain, entry)
+		e = next
+	}
+	return slog.GroupValue(slog.String("message", err.Error()), slog.Any("chain", chain))
+}
+
+// errorMessage 返回 err 自身的错误信息，去掉末尾被包装错误 next 的信息（如 fmt.Errorf 的 ": %w"）
+func errorMessage(err, next error) string {
+	if ae, ok := err.(*AttrError); ok && ae.Msg != "" {
+		return ae.Msg
+	}
+	msg := err.Error()
+	if next != nil {
+		msg = strings.TrimSuffix(msg, ": "+next.Error())
+	}
+	return msg
+}
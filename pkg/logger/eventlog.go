@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// eventLogID 写入 Windows 事件日志的事件 ID
+const eventLogID = 1
+
+func init() {
+	registerOutput("eventlog", newEventLogOutput)
+}
+
+// eventLogWriter 事件日志的写入接口，与 golang.org/x/sys/windows/svc/eventlog.Log 的方法一致
+type eventLogWriter interface {
+	Info(eid uint32, msg string) error
+	Warning(eid uint32, msg string) error
+	Error(eid uint32, msg string) error
+	Close() error
+}
+
+// newEventLogOutput 创建 Windows 事件日志输出（仅 Windows）：
+//   - eventlog: 以程序名（不含 .exe）作为事件源
+//   - eventlog://Source: 指定事件源名称
+//
+// 首次使用时注册事件源（需要管理员权限，已注册或无权限时直接打开）；
+// 记录按 format 格式化后写入，ERROR 及以上为错误、WARN 为警告、其他为信息
+func newEventLogOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	source := strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	if target != "eventlog" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid eventlog target %q: %w", target, err)
+		}
+		if u.Host != "" {
+			source = u.Host
+		}
+	}
+
+	log, err := openEventLog(source)
+	if err != nil {
+		return nil, nil, err
+	}
+	sink := &eventLogSink{log: log}
+	return &eventLogHandler{inner: createHandler(cfg, format, sink, level), sink: sink}, sink, nil
+}
+
+// eventLogHandler 将记录交给 inner 格式化，并为写入 sink 的消息附带记录的级别
+type eventLogHandler struct {
+	inner slog.Handler
+	sink  *eventLogSink
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *eventLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *eventLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+
+	h.sink.level = r.Level
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &eventLogHandler{inner: h.inner.WithAttrs(attrs), sink: h.sink}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	return &eventLogHandler{inner: h.inner.WithGroup(name), sink: h.sink}
+}
+
+// eventLogSink 按记录级别写入事件日志的写入器
+//
+// Write 只由 eventLogHandler 在持有 mu 时调用，level 为当前记录的级别
+type eventLogSink struct {
+	mu    sync.Mutex
+	log   eventLogWriter
+	level slog.Level
+}
+
+// Write 实现 io.Writer 接口，p 为一条格式化后的记录
+func (s *eventLogSink) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\r\n")
+	var err error
+	switch {
+	case s.level >= slog.LevelError:
+		err = s.log.Error(eventLogID, msg)
+	case s.level >= slog.LevelWarn:
+		err = s.log.Warning(eventLogID, msg)
+	default:
+		err = s.log.Info(eventLogID, msg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close 实现 io.Closer 接口
+func (s *eventLogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.Close()
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package logger
+
+import "fmt"
+
+// openEventLog 当前平台不支持 Windows 事件日志
+func openEventLog(source string) (eventLogWriter, error) {
+	return nil, fmt.Errorf("eventlog output is not supported on this platform")
+}
@@ -0,0 +1,18 @@
+package logger
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// openEventLog 注册（如果尚未注册）并打开事件源
+func openEventLog(source string) (eventLogWriter, error) {
+	// 注册需要管理员权限，事件源已存在或没有权限时忽略错误，直接尝试打开
+	_ = eventlog.InstallAsEventCreate(source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	log, err := eventlog.Open(source)
+	if err != nil {
+		return nil, fmt.Errorf("open eventlog source %q: %w", source, err)
+	}
+	return log, nil
+}
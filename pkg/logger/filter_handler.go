@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// FilterFunc 记录过滤函数，返回 false 表示丢弃该记录
+//
+// attrs 包含记录自身和 With 添加的属性，分组属性展开为以 "." 连接的键；
+// 函数会被并发调用，不应修改 attrs
+type FilterFunc func(level slog.Level, msg string, attrs map[string]any) bool
+
+// filterHandler 按 FilterFunc 丢弃记录的 handler 包装器
+type filterHandler struct {
+	inner  slog.Handler
+	filter FilterFunc
+	groups []string
+	attrs  []slog.Attr // 已展开并加上 group 前缀的 With 属性
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *filterHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *filterHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.keep(r) {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// keep 判断记录是否保留
+func (h *filterHandler) keep(r slog.Record) bool {
+	attrs := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for _, a := range h.attrs {
+		attrs[a.Key] = a.Value.Any()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		for _, attr := range prefixAttrs(h.groups, []slog.Attr{a}) {
+			attrs[attr.Key] = attr.Value.Any()
+		}
+		return true
+	})
+	return h.filter(r.Level, r.Message, attrs)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *filterHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newAttrs := append(h.attrs[:len(h.attrs):len(h.attrs)], prefixAttrs(h.groups, attrs)...)
+	return &filterHandler{inner: h.inner.WithAttrs(attrs), filter: h.filter, groups: h.groups, attrs: newAttrs}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *filterHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &filterHandler{inner: h.inner.WithGroup(name), filter: h.filter, groups: groups, attrs: h.attrs}
+}
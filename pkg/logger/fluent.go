@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func init() {
+	registerOutput("fluent", newFluentOutput)
+}
+
+// FluentEntry 一条 forward 协议的事件
+type FluentEntry struct {
+	Time   time.Time
+	Record map[string]any
+}
+
+// encodeFluentForward 由 [RegisterFluentEncoder] 设置，未导入 fluent 子模块时为 nil
+var encodeFluentForward func(tag string, entries []FluentEntry) ([]byte, error)
+
+// RegisterFluentEncoder 注册 Fluentd 输出使用的 forward 协议编码函数（MessagePack），
+// 由 fluent 子模块在 init 中调用：
+//
+//	import _ "github.com/lwmacct/251125-go-mod-logger/pkg/logger/fluent"
+//
+// MessagePack 库只由该子模块引入，不使用 Fluentd 输出的程序不会依赖它
+func RegisterFluentEncoder(encode func(tag string, entries []FluentEntry) ([]byte, error)) {
+	encodeFluentForward = encode
+}
+
+// newFluentOutput 创建 Fluentd / Fluent Bit 输出："fluent://host:24224?tag=myapp"
+//
+// 需要导入 fluent 子模块（见 [RegisterFluentEncoder]）。每条记录以 JSON 格式化后转换为 forward 协议的事件（MessagePack），
+// 按批以 Forward 模式发送，tag 未指定时使用程序名。批量参数和队列长度与 http 输出相同，
+// 聚合器不可用时队列满后丢弃新记录，不会阻塞记录日志的调用方；连接断开时在下一批发送前重连。
+func newFluentOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	u, err := url.Parse(target)
+	if err != nil || u.Host == "" {
+		return nil, nil, fmt.Errorf("fluent output requires fluent://host:24224?tag=app: %q", target)
+	}
+	if encodeFluentForward == nil {
+		return nil, nil, errors.New("fluent output requires importing github.com/lwmacct/251125-go-mod-logger/pkg/logger/fluent")
+	}
+	tag := u.Query().Get("tag")
+	if tag == "" {
+		tag = filepath.Base(os.Args[0])
+	}
+
+	sink := &fluentSink{
+		address: u.Host,
+		tag:     tag,
+		dial: func(address string) (net.Conn, error) {
+			return net.DialTimeout("tcp", address, httpTimeout)
+		},
+		encode: encodeFluentForward,
+	}
+	writer := newBatchWriter(target, sink.send, cfg.BatchSize, cfg.FlushInterval, cfg.BufferSize)
+	return createHandler(cfg, "json", &fluentWriter{out: writer, now: time.Now}, level), newMultiCloser(writer, sink), nil
+}
+
+// fluentWriter 为一条 JSON 记录加上 8 字节的时间戳（Unix 纳秒）并放入发送队列
+type fluentWriter struct {
+	out io.Writer
+	now func() time.Time
+}
+
+// Write 实现 io.Writer 接口
+func (w *fluentWriter) Write(p []byte) (int, error) {
+	entry := make([]byte, 0, 8+len(p))
+	entry = binary.BigEndian.AppendUint64(entry, uint64(w.now().UnixNano()))
+	entry = append(entry, p...)
+	if _, err := w.out.Write(entry); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// fluentSink 将一批记录编码为 forward 协议的消息并通过 TCP 发送
+//
+// 只在 batchWriter 的后台 goroutine 中调用（Close 在 batchWriter 关闭之后），无需加锁
+type fluentSink struct {
+	address string
+	tag     string
+	dial    func(address string) (net.Conn, error)
+	encode  func(tag string, entries []FluentEntry) ([]byte, error) // 见 [RegisterFluentEncoder]
+	conn    net.Conn                                                // 当前连接，发送失败后置为 nil，下次发送前重连
+}
+
+// send 发送一批由 fluentWriter 编码的记录，连接已断开时重连并重发一次
+func (s *fluentSink) send(batch [][]byte) error {
+	entries := make([]FluentEntry, 0, len(batch))
+	for _, entry := range batch {
+		if len(entry) < 8 {
+			continue
+		}
+		record, err := fluentRecord(entry[8:])
+		if err != nil {
+			continue
+		}
+		entries = append(entries, FluentEntry{
+			Time:   time.Unix(0, int64(binary.BigEndian.Uint64(entry))),
+			Record: record,
+		})
+	}
+	payload, err := s.encode(s.tag, entries)
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		if s.conn == nil {
+			if s.conn, err = s.dial(s.address); err != nil {
+				s.conn = nil
+				return fmt.Errorf("fluent: %w", err)
+			}
+		}
+		_ = s.conn.SetWriteDeadline(time.Now().Add(httpTimeout))
+		if _, err = s.conn.Write(payload); err == nil {
+			return nil
+		}
+		_ = s.conn.Close()
+		s.conn = nil
+	}
+	return fmt.Errorf("fluent: %w", err)
+}
+
+// Close 实现 io.Closer 接口，关闭当前连接
+func (s *fluentSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// fluentRecord 将一条 JSON 记录解析为事件的 record，整数保持为整数
+func fluentRecord(line []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var record map[string]any
+	if err := dec.Decode(&record); err != nil {
+		return nil, err
+	}
+	return fluentValue(record).(map[string]any), nil
+}
+
+// fluentValue 将 json.Number 转换为 int64 或 float64，递归处理对象和数组
+func fluentValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, value := range v {
+			v[key] = fluentValue(value)
+		}
+		return v
+	case []any:
+		for i, value := range v {
+			v[i] = fluentValue(value)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,62 @@
+// Package fluent 实现 logger 的 Fluentd / Fluent Bit 输出使用的 forward 协议编码（MessagePack）
+//
+// 导入后即可使用 "fluent://host:24224?tag=myapp" 输出：
+//
+//	import _ "github.com/lwmacct/251125-go-mod-logger/pkg/logger/fluent"
+//
+// 本包是独立的 Go 模块，MessagePack 库只会进入导入了它的程序的依赖。
+package fluent
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// eventTimeExt forward 协议中 EventTime 的 MessagePack 扩展类型
+const eventTimeExt = 0
+
+func init() {
+	logger.RegisterFluentEncoder(encodeForward)
+}
+
+// encodeForward 按 forward 协议的 Forward 模式编码一批事件：
+// [tag, [[EventTime, record], ...], {"size": n}]
+//
+// EventTime 为扩展类型 0，8 字节的秒和纳秒（均为大端 uint32），保留纳秒精度
+func encodeForward(tag string, entries []logger.FluentEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.UseCompactInts(true)
+
+	if err := enc.EncodeArrayLen(3); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeString(tag); err != nil {
+		return nil, err
+	}
+	if err := enc.EncodeArrayLen(len(entries)); err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err := enc.EncodeArrayLen(2); err != nil {
+			return nil, err
+		}
+		if err := enc.EncodeExtHeader(eventTimeExt, 8); err != nil {
+			return nil, err
+		}
+		var t [8]byte
+		binary.BigEndian.PutUint32(t[:4], uint32(entry.Time.Unix()))
+		binary.BigEndian.PutUint32(t[4:], uint32(entry.Time.Nanosecond()))
+		buf.Write(t[:])
+		if err := enc.Encode(entry.Record); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Encode(map[string]any{"size": len(entries)}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+package fluent
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// fluentEvent 模拟的聚合器解码出的一条事件
+type fluentEvent struct {
+	tag    string
+	time   time.Time
+	record map[string]any
+}
+
+// decodeFluentForward 按 Forward 模式解码一条消息
+func decodeFluentForward(dec *msgpack.Decoder) ([]fluentEvent, map[string]any, error) {
+	if _, err := dec.DecodeArrayLen(); err != nil {
+		return nil, nil, err
+	}
+	tag, err := dec.DecodeString()
+	if err != nil {
+		return nil, nil, err
+	}
+	n, err := dec.DecodeArrayLen()
+	if err != nil {
+		return nil, nil, err
+	}
+	events := make([]fluentEvent, 0, n)
+	for i := 0; i < n; i++ {
+		if _, err := dec.DecodeArrayLen(); err != nil {
+			return nil, nil, err
+		}
+		id, size, err := dec.DecodeExtHeader()
+		if err != nil {
+			return nil, nil, err
+		}
+		if id != eventTimeExt || size != 8 {
+			return nil, nil, fmt.Errorf("unexpected event time ext %d (%d bytes)", id, size)
+		}
+		var t [8]byte
+		if err := dec.ReadFull(t[:]); err != nil {
+			return nil, nil, err
+		}
+		record, err := dec.DecodeMap()
+		if err != nil {
+			return nil, nil, err
+		}
+		events = append(events, fluentEvent{
+			tag:    tag,
+			time:   time.Unix(int64(binary.BigEndian.Uint32(t[:4])), int64(binary.BigEndian.Uint32(t[4:]))),
+			record: record,
+		})
+	}
+	option, err := dec.DecodeMap()
+	return events, option, err
+}
+
+func TestFluentForwardEncoding(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+
+	type message struct {
+		events []fluentEvent
+		option map[string]any
+	}
+	messages := make(chan message, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		dec := msgpack.NewDecoder(conn)
+		for {
+			events, option, err := decodeFluentForward(dec)
+			if err != nil {
+				close(messages)
+				return
+			}
+			messages <- message{events: events, option: option}
+		}
+	}()
+
+	start := time.Now()
+	log, closer, err := logger.NewWithCloser(&logger.Config{Level: "INFO", Format: "json", Output: "fluent://" + ln.Addr().String() + "?tag=myapp", FlushInterval: time.Hour})
+	require.NoError(t, err)
+	log.Info("hello", "n", 42, "user", slog.GroupValue(slog.String("name", "alice")))
+	log.Warn("second")
+	require.NoError(t, closer.Close())
+
+	msg := <-messages
+	require.Len(t, msg.events, 2)
+	assert.EqualValues(t, 2, msg.option["size"])
+
+	first := msg.events[0]
+	assert.Equal(t, "myapp", first.tag)
+	assert.WithinDuration(t, start, first.time, time.Minute)
+	assert.Equal(t, "hello", first.record["msg"])
+	assert.Equal(t, "INFO", first.record["level"])
+	assert.EqualValues(t, 42, first.record["n"])
+	assert.Equal(t, map[string]any{"name": "alice"}, first.record["user"])
+	assert.Equal(t, "second", msg.events[1].record["msg"])
+}
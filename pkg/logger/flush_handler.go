@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// flushHandler 在达到指定级别的记录写出后立即刷新输出的 handler 包装器
+//
+// 启用 Async 或网络输出时，记录先进入缓冲队列；刷新会等待队列中已有的记录
+// （包括这条记录）写完并同步到磁盘，然后 Handle 才返回，保证进程随后崩溃时
+// 重要的记录不会丢失。刷新的开销较大，只应对 ERROR 等低频级别启用。
+type flushHandler struct {
+	inner    slog.Handler
+	minLevel slog.Level
+	outputs  io.Closer // 各输出的资源，通过 syncResource 刷新
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *flushHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *flushHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.inner.Handle(ctx, r)
+	if r.Level >= h.minLevel {
+		err = errors.Join(err, syncResource(h.outputs))
+	}
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *flushHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &flushHandler{inner: h.inner.WithAttrs(attrs), minLevel: h.minLevel, outputs: h.outputs}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *flushHandler) WithGroup(name string) slog.Handler {
+	return &flushHandler{inner: h.inner.WithGroup(name), minLevel: h.minLevel, outputs: h.outputs}
+}
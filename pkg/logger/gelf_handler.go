@@ -0,0 +1,184 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// gelfVersion 输出的 GELF 协议版本
+const gelfVersion = "1.1"
+
+// gelfHandler 输出 GELF（Graylog Extended Log Format）JSON 的 handler
+//
+// 每条记录输出为一行 JSON：version、host（主机名）、short_message（消息）、
+// timestamp（Unix 时间戳，秒，带小数）、level（syslog 严重级别），
+// 属性以 "_" 前缀的自定义字段输出，分组内的属性为 "_group.key"。
+// 这些协议字段是固定的，ReplaceAttr 只作用于属性。
+type gelfHandler struct {
+	opts     *slog.HandlerOptions
+	writer   io.Writer
+	mu       sync.Mutex
+	groups   []string       // 当前 group 路径
+	preAttrs map[string]any // 预计算的自定义字段（已带 "_" 前缀）
+}
+
+// newGELFHandler 创建 GELF handler
+func newGELFHandler(w io.Writer, opts *slog.HandlerOptions) *gelfHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	return &gelfHandler{
+		opts:     opts,
+		writer:   w,
+		preAttrs: make(map[string]any),
+	}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *gelfHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *gelfHandler) Handle(ctx context.Context, r slog.Record) error {
+	m := make(map[string]any, len(h.preAttrs)+r.NumAttrs()+6)
+	for k, v := range h.preAttrs {
+		m[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		h.addAttr(m, a)
+		return true
+	})
+
+	// 添加源代码位置（如果启用）
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		if f.File != "" {
+			src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			if a, ok := sourceAttr(h.opts.ReplaceAttr, src, clipWorkspacePath); ok {
+				m["_"+slog.SourceKey] = a.Value.String()
+			}
+		}
+	}
+
+	// 协议字段最后设置，避免被属性覆盖
+	m["version"] = gelfVersion
+	m["host"] = hostname()
+	m["short_message"] = replaceMessage(h.opts.ReplaceAttr, r.Message)
+	m["timestamp"] = float64(r.Time.UnixMilli()) / 1e3
+	m["level"] = syslogSeverity(r.Level)
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err = h.writer.Write(append(data, '\n'))
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *gelfHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	preAttrs := make(map[string]any, len(h.preAttrs)+len(attrs))
+	for k, v := range h.preAttrs {
+		preAttrs[k] = v
+	}
+	for _, attr := range attrs {
+		h.addAttr(preAttrs, attr)
+	}
+
+	return &gelfHandler{
+		opts:     h.opts,
+		writer:   h.writer,
+		groups:   h.groups,
+		preAttrs: preAttrs,
+	}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *gelfHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &gelfHandler{
+		opts:     h.opts,
+		writer:   h.writer,
+		groups:   append(h.groups[:len(h.groups):len(h.groups)], name),
+		preAttrs: h.preAttrs,
+	}
+}
+
+// addAttr 对属性应用 ReplaceAttr 后添加为自定义字段
+func (h *gelfHandler) addAttr(m map[string]any, a slog.Attr) {
+	a, ok := replaceAttr(h.opts.ReplaceAttr, h.groups, a)
+	if !ok {
+		return
+	}
+	prefix := ""
+	if len(h.groups) > 0 {
+		prefix = strings.Join(h.groups, ".") + "."
+	}
+	addGELFField(m, prefix, a)
+}
+
+// addGELFField 添加自定义字段，分组递归展开为 "_group.key"
+func addGELFField(m map[string]any, prefix string, a slog.Attr) {
+	if a.Value.Kind() == slog.KindGroup {
+		// 空键分组内联到当前层级
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, attr := range a.Value.Group() {
+			addGELFField(m, prefix, attr)
+		}
+		return
+	}
+	m[gelfFieldName(prefix+a.Key)] = jsonValue(a.Value)
+}
+
+// gelfFieldName 返回自定义字段名：加上 "_" 前缀，GELF 不允许的字符替换为 "_"
+//
+// GELF 保留了 _id 字段，名为 id 的属性输出为 _id_
+func gelfFieldName(key string) string {
+	key = strings.Map(func(r rune) rune {
+		if r == '.' || r == '-' || r == '_' ||
+			(r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, key)
+	if key == "id" {
+		return "_id_"
+	}
+	return "_" + key
+}
+
+// syslogSeverity 将日志级别映射为 syslog 严重级别（RFC 5424）
+//
+// TRACE/DEBUG → 7 (debug)、INFO → 6 (informational)、WARN → 4 (warning)、
+// ERROR 及以上 → 3 (error)；自定义级别按不高于它的最近内置级别映射
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	default:
+		return 7
+	}
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"sync"
+)
+
+// gidHandler 为每条记录添加当前 goroutine ID（gid）的 handler 包装器
+//
+// Go 没有公开 goroutine ID，这里从 runtime.Stack 输出的第一行 "goroutine 123 [running]:" 中解析，
+// 只读取固定长度的开头部分，缓冲区通过 sync.Pool 复用，不分配内存。slog 在调用方的 goroutine 中执行 Handle，
+// 因此需位于去重、异步写入等可能延迟处理记录的环节之前。
+type gidHandler struct {
+	inner slog.Handler
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *gidHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *gidHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(slog.Uint64("gid", goroutineID()))
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *gidHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &gidHandler{inner: h.inner.WithAttrs(attrs)}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *gidHandler) WithGroup(name string) slog.Handler {
+	return &gidHandler{inner: h.inner.WithGroup(name)}
+}
+
+// gidBufPool 缓存解析 goroutine ID 使用的缓冲区，第一行不超过 64 字节
+var gidBufPool = sync.Pool{New: func() any { return new([64]byte) }}
+
+// goroutineID 返回当前 goroutine 的 ID，解析失败时返回 0
+func goroutineID() uint64 {
+	buf := gidBufPool.Get().(*[64]byte)
+	defer gidBufPool.Put(buf)
+	b := buf[:runtime.Stack(buf[:], false)]
+	// 跳过 "goroutine "
+	const prefix = len("goroutine ")
+	if len(b) <= prefix {
+		return 0
+	}
+	var id uint64
+	for _, c := range b[prefix:] {
+		if c < '0' || c > '9' {
+			break
+		}
+		id = id*10 + uint64(c-'0')
+	}
+	return id
+}
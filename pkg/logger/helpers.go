@@ -1,63 +1,293 @@
 package logger
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"math"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
+// osExit 用于 Fatal 退出进程，测试中可替换
+var osExit = os.Exit
+
 // FormatBytes 格式化字节数为人类可读格式
 //
 // 用于日志中输出文件大小、传输速率等信息
 func FormatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
+	return formatBytes(bytes, 1024, "KMGTPE")
+}
+
+// FormatBytesSI 使用十进制单位（1000 进制）格式化字节数，例如 1.0 kB、1.5 MB
+//
+// 适用于网络速率、磁盘容量等习惯使用十进制单位的场景；二进制单位见 FormatBytes
+func FormatBytesSI(bytes int64) string {
+	return formatBytes(bytes, 1000, "kMGTPE")
+}
+
+// formatBytes 按给定进制和单位前缀格式化字节数，负数格式化其绝对值并加上 "-"
+func formatBytes(bytes int64, unit uint64, prefixes string) string {
+	sign := ""
+	// 使用无符号数表示绝对值，math.MinInt64 取反也不会溢出
+	n := uint64(bytes)
+	if bytes < 0 {
+		sign = "-"
+		n = -n
+	}
+	if n < unit {
+		return fmt.Sprintf("%s%d B", sign, n)
 	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
+	div, exp := unit, 0
+	for m := n / unit; m >= unit; m /= unit {
 		div *= unit
 		exp++
 	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return fmt.Sprintf("%s%.1f %cB", sign, float64(n)/float64(div), prefixes[exp])
 }
 
-// LogError 记录错误日志并返回错误
+// FormatDuration 格式化时长为人类可读格式
 //
-// 这是一个便捷函数，用于在需要同时记录日志和返回错误的场景：
-//   return logger.LogError(ctx, "操作失败", err, "user_id", userID)
-func LogError(ctx interface{}, msg string, err error, attrs ...any) error {
-	var logger *slog.Logger
-
-	// 尝试从 context 获取 logger
-	if c, ok := ctx.(interface{ Value(key any) any }); ok {
-		if l, ok := c.Value(loggerKey).(*slog.Logger); ok {
-			logger = l
+// 用于日志中输出耗时，例如 850ns、12.5µs、250ms、1.5s、3m04s、2h、2h05m；
+// 小数部分保留一位并向下截断。只分配一次内存，适合在热点路径中使用：
+//
+//	logger.Info("query done", "took", logger.FormatDuration(time.Since(start)))
+func FormatDuration(d time.Duration) string {
+	var buf [32]byte
+	return string(appendDuration(buf[:0], d))
+}
+
+// appendDuration 将格式化后的时长追加到 b
+func appendDuration(b []byte, d time.Duration) []byte {
+	if d < 0 {
+		b = append(b, '-')
+		d = -d
+		if d < 0 { // math.MinInt64 取反后仍为负数
+			d = math.MaxInt64
+		}
+	}
+
+	switch {
+	case d < time.Microsecond:
+		b = strconv.AppendInt(b, int64(d), 10)
+		return append(b, "ns"...)
+	case d < time.Millisecond:
+		return append(appendDecimal(b, d, time.Microsecond), "µs"...)
+	case d < time.Second:
+		return append(appendDecimal(b, d, time.Millisecond), "ms"...)
+	case d < time.Minute:
+		return append(appendDecimal(b, d, time.Second), 's')
+	case d < time.Hour:
+		b = strconv.AppendInt(b, int64(d/time.Minute), 10)
+		b = append(b, 'm')
+		b = appendTwoDigits(b, int64(d%time.Minute/time.Second))
+		return append(b, 's')
+	default:
+		b = strconv.AppendInt(b, int64(d/time.Hour), 10)
+		b = append(b, 'h')
+		if m := int64(d % time.Hour / time.Minute); m > 0 {
+			b = appendTwoDigits(b, m)
+			b = append(b, 'm')
+		}
+		return b
+	}
+}
+
+// appendDecimal 以 unit 为单位追加 d，保留一位小数，小数为 0 时省略
+func appendDecimal(b []byte, d, unit time.Duration) []byte {
+	b = strconv.AppendInt(b, int64(d/unit), 10)
+	if frac := int64(d % unit * 10 / unit); frac > 0 {
+		b = append(b, '.', byte('0'+frac))
+	}
+	return b
+}
+
+// appendTwoDigits 追加两位数字，不足两位时补 0
+func appendTwoDigits(b []byte, n int64) []byte {
+	return append(b, byte('0'+n/10), byte('0'+n%10))
+}
+
+// FormatRate 格式化传输速率，使用十进制单位，例如 850 B/s、12.3 MB/s
+//
+// 用于日志中输出吞吐量；d 不大于 0 时无法计算速率，bytes 为 0 返回 "0 B/s"，否则返回 "∞ B/s"：
+//
+//	logger.Info("download", "rate", logger.FormatRate(n, time.Since(start)))
+func FormatRate(bytes int64, d time.Duration) string {
+	if d <= 0 {
+		if bytes == 0 {
+			return "0 B/s"
 		}
+		return "∞ B/s"
+	}
+	rate := float64(bytes) / d.Seconds()
+	// 超出 int64 范围时取边界值（float64(math.MaxInt64) 向上舍入，直接转换会溢出）
+	n := int64(math.MaxInt64)
+	switch {
+	case rate <= math.MinInt64:
+		n = math.MinInt64
+	case rate < math.MaxInt64:
+		n = int64(math.Round(rate))
 	}
+	return formatBytes(n, 1000, "kMGTPE") + "/s"
+}
 
-	// 如果没有从 context 获取到，使用默认 logger
-	if logger == nil {
-		logger = slog.Default()
+// LogError 记录错误日志并返回错误
+//
+// 这是一个便捷函数，用于在需要同时记录日志和返回错误的场景：
+//
+//	return logger.LogError(ctx, "操作失败", err, "user_id", userID)
+//
+// 优先使用 context 中的 logger（见 [WithContext]），没有则使用默认 logger；
+// err 的错误链中含有 [AttrError] 时自动合并其属性；err 为 nil 时不记录日志，直接返回 nil
+func LogError(ctx context.Context, msg string, err error, attrs ...any) error {
+	if err == nil {
+		return nil
+	}
+	logger := FromContext(ctx)
+	if !enabled(ctx, logger, slog.LevelError) {
+		return err
 	}
 
-	// 合并错误到属性中
-	allAttrs := append([]any{"error", err}, attrs...)
-	logger.Error(msg, allAttrs...)
+	// 合并错误及其携带的属性（见 [AttrError]）
+	allAttrs := append(append([]any{"error", err}, errorAttrs(err)...), attrs...)
+	logAt(ctx, logger, slog.LevelError, msg, allAttrs...)
 
 	return err
 }
 
 // LogAndWrap 记录错误日志并包装错误信息
 //
-// 用于在错误传播链中添加上下文信息
+// 用于在错误传播链中添加上下文信息，需要使用 context 中的 logger 时见 [LogAndWrapCtx]；
+// err 为 nil 时不记录日志，直接返回 nil
 func LogAndWrap(msg string, err error, attrs ...any) error {
-	allAttrs := append([]any{"error", err}, attrs...)
-	slog.Error(msg, allAttrs...)
+	if err == nil {
+		return nil
+	}
+	if Enabled(slog.LevelError) {
+		allAttrs := append(append([]any{"error", err}, errorAttrs(err)...), attrs...)
+		logAt(context.Background(), slog.Default(), slog.LevelError, msg, allAttrs...)
+	}
 	return fmt.Errorf("%s: %w", msg, err)
 }
 
+// LogAndWrapCtx 与 [LogAndWrap] 相同，但与 [LogError] 一样优先使用 context 中的 logger
+//
+//	return logger.LogAndWrapCtx(ctx, "查询订单失败", err, "order_id", orderID)
+func LogAndWrapCtx(ctx context.Context, msg string, err error, attrs ...any) error {
+	if err == nil {
+		return nil
+	}
+	if logger := FromContext(ctx); enabled(ctx, logger, slog.LevelError) {
+		allAttrs := append(append([]any{"error", err}, errorAttrs(err)...), attrs...)
+		logAt(ctx, logger, slog.LevelError, msg, allAttrs...)
+	}
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+// LogIfContextDone ctx 已取消或超时时以 WARN 级别记录 msg 和原因 (error)，否则什么都不做
+//
+// 与 [LogError] 一样优先使用 context 中的 logger，适合在长操作的检查点调用：
+//
+//	logger.LogIfContextDone(ctx, "批量导入中断", "imported", n)
+func LogIfContextDone(ctx context.Context, msg string, attrs ...any) {
+	if ctx == nil || ctx.Err() == nil {
+		return
+	}
+	if logger := FromContext(ctx); enabled(ctx, logger, slog.LevelWarn) {
+		logAt(ctx, logger, slog.LevelWarn, msg, append([]any{"error", context.Cause(ctx)}, attrs...)...)
+	}
+}
+
+// Monitored 执行 fn 并返回其错误；fn 返回时 ctx 已取消或超时，则以 WARN 级别记录操作名 (op)、原因 (error) 和耗时 (duration)
+//
+// 用于定位超时：fn 在取消之前完成时不记录。
+//
+//	err := logger.Monitored(ctx, "sync-orders", func() error {
+//	    return syncOrders(ctx)
+//	})
+func Monitored(ctx context.Context, op string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	if ctx == nil || ctx.Err() == nil {
+		return err
+	}
+	if logger := FromContext(ctx); enabled(ctx, logger, slog.LevelWarn) {
+		logAt(ctx, logger, slog.LevelWarn, "context done before operation finished",
+			"op", op,
+			"error", context.Cause(ctx),
+			"duration", FormatDuration(time.Since(start)),
+		)
+	}
+	return err
+}
+
+// Measure 执行 fn 并记录其开始和结束，返回 fn 的错误
+//
+// 开始时以 DEBUG 级别记录 "operation started"，成功时以 INFO 级别记录 "operation finished"，
+// 失败时以 ERROR 级别记录 "operation failed" 和错误 (error，合并 [AttrError] 的属性)，
+// 都带有操作名 (op)，结束时还带有耗时 (duration)。与 [LogError] 一样优先使用 context 中的 logger：
+//
+//	err := logger.Measure(ctx, "sync-orders", func() error {
+//	    return syncOrders(ctx)
+//	})
+func Measure(ctx context.Context, op string, fn func() error) error {
+	logger := FromContext(ctx)
+	if enabled(ctx, logger, slog.LevelDebug) {
+		logAt(ctx, logger, slog.LevelDebug, "operation started", "op", op)
+	}
+
+	start := time.Now()
+	err := fn()
+	duration := time.Since(start)
+
+	if err == nil {
+		if enabled(ctx, logger, slog.LevelInfo) {
+			logAt(ctx, logger, slog.LevelInfo, "operation finished", "op", op, "duration", FormatDuration(duration))
+		}
+		return nil
+	}
+	if enabled(ctx, logger, slog.LevelError) {
+		attrs := append([]any{"op", op, "duration", FormatDuration(duration), "error", err}, errorAttrs(err)...)
+		logAt(ctx, logger, slog.LevelError, "operation failed", attrs...)
+	}
+	return err
+}
+
+// Enabled 报告默认 logger 是否会输出 level 级别的日志
+//
+// 本包的辅助函数在构造属性前已自行检查级别，未启用的 Debug 调用不会分配内存；
+// 但调用方传入的参数仍会先求值。参数计算开销较大时，应先检查级别：
+//
+//	if logger.Enabled(slog.LevelDebug) {
+//	    logger.Debug("请求详情", "body", dumpRequest(req))
+//	}
+func Enabled(level slog.Level) bool {
+	return slog.Default().Enabled(context.Background(), level)
+}
+
+// enabled 报告 logger 是否会输出 level 级别的日志，ctx 为 nil 时使用 context.Background()
+func enabled(ctx context.Context, logger *slog.Logger, level slog.Level) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return logger.Enabled(ctx, level)
+}
+
+// Trace 记录跟踪级别的结构化日志
+//
+// 比 Debug 更详细，用于输出报文、协议交互等底层细节，
+// 只有日志级别设置为 TRACE 时才会输出。
+//
+//	logger.Trace("收到数据帧", "bytes", len(frame))
+func Trace(msg string, attrs ...any) {
+	logAt(context.Background(), slog.Default(), LevelTrace, msg, attrs...)
+}
+
 // Debug 记录调试级别的结构化日志
 //
 // 用于输出详细的调试信息，生产环境通常不会开启。
@@ -65,7 +295,7 @@ func LogAndWrap(msg string, err error, attrs ...any) error {
 //
 //	logger.Debug("处理请求", "user_id", 123, "action", "login")
 func Debug(msg string, attrs ...any) {
-	slog.Debug(msg, attrs...)
+	logAt(context.Background(), slog.Default(), slog.LevelDebug, msg, attrs...)
 }
 
 // Info 记录信息级别的结构化日志
@@ -75,7 +305,7 @@ func Debug(msg string, attrs ...any) {
 //
 //	logger.Info("用户登录成功", "user_id", 123, "ip", "192.168.1.1")
 func Info(msg string, attrs ...any) {
-	slog.Info(msg, attrs...)
+	logAt(context.Background(), slog.Default(), slog.LevelInfo, msg, attrs...)
 }
 
 // Warn 记录警告级别的结构化日志
@@ -85,7 +315,7 @@ func Info(msg string, attrs ...any) {
 //
 //	logger.Warn("连接池使用率过高", "usage", 0.95, "max", 100)
 func Warn(msg string, attrs ...any) {
-	slog.Warn(msg, attrs...)
+	logAt(context.Background(), slog.Default(), slog.LevelWarn, msg, attrs...)
 }
 
 // Error 记录错误级别的结构化日志
@@ -95,7 +325,172 @@ func Warn(msg string, attrs ...any) {
 //
 //	logger.Error("数据库连接失败", "error", err, "host", dbHost)
 func Error(msg string, attrs ...any) {
-	slog.Error(msg, attrs...)
+	logAt(context.Background(), slog.Default(), slog.LevelError, msg, attrs...)
+}
+
+// Debugf 以 printf 风格的消息记录调试级别的日志，仅在级别启用时才格式化消息
+//
+// 用于从 log.Printf 等迁移的过渡场景，新代码应使用 [Debug] 并以属性记录变量：
+//
+//	logger.Debugf("processed %d items", n)
+func Debugf(format string, args ...any) {
+	logfAt(slog.Default(), slog.LevelDebug, format, args...)
+}
+
+// Infof 以 printf 风格的消息记录信息级别的日志，仅在级别启用时才格式化消息，见 [Debugf]
+func Infof(format string, args ...any) {
+	logfAt(slog.Default(), slog.LevelInfo, format, args...)
+}
+
+// Warnf 以 printf 风格的消息记录警告级别的日志，仅在级别启用时才格式化消息，见 [Debugf]
+func Warnf(format string, args ...any) {
+	logfAt(slog.Default(), slog.LevelWarn, format, args...)
+}
+
+// Errorf 以 printf 风格的消息记录错误级别的日志，仅在级别启用时才格式化消息，见 [Debugf]
+//
+// 与 fmt.Errorf 不同，Errorf 不返回错误；需要同时记录和返回错误时使用 [LogError]
+func Errorf(format string, args ...any) {
+	logfAt(slog.Default(), slog.LevelError, format, args...)
+}
+
+// logfAt 与 logAt 相同，但消息由 format 和 args 格式化得到，级别未启用时不调用 fmt.Sprintf
+func logfAt(logger *slog.Logger, level slog.Level, format string, args ...any) {
+	ctx := context.Background()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // 跳过 runtime.Callers、logfAt 和辅助函数自身
+	r := slog.NewRecord(time.Now(), level, fmt.Sprintf(format, args...), pcs[0])
+	_ = logger.Handler().Handle(ctx, r)
+}
+
+// Fatal 以 ERROR 级别记录日志并以状态码 1 退出进程
+//
+// 退出前会关闭全局 logger 的资源，确保日志写入完成。
+// 由于会直接退出，defer 语句不会执行，仅用于无法恢复的错误。
+//
+//	logger.Fatal("配置文件加载失败", "error", err)
+func Fatal(msg string, attrs ...any) {
+	logAt(context.Background(), slog.Default(), slog.LevelError, msg, attrs...)
+	_ = Close()
+	osExit(1)
+}
+
+// repanicAfterLog 由 InitCfg 根据 [Config].RepanicAfterLog 设置
+var repanicAfterLog atomic.Bool
+
+// Recover 恢复 panic，以 ERROR 级别记录 panic 值 (error) 和堆栈 (stack)
+//
+// 适用于 goroutine 和 main 函数，需直接 defer 调用：
+//
+//	defer logger.Recover()
+//
+// 启用 [Config].RepanicAfterLog 时，关闭全局 logger 的资源后继续 panic；
+// 否则刷新输出后吞掉 panic。启用 Async 时，panic 会导致缓冲区中的日志丢失，应在 main 开头 defer 调用。
+func Recover() {
+	if r := recover(); r != nil {
+		if Enabled(slog.LevelError) {
+			logAt(context.Background(), slog.Default(), slog.LevelError, "panic",
+				"error", fmt.Sprint(r),
+				"stack", string(debug.Stack()),
+			)
+		}
+		if !repanicAfterLog.Load() {
+			_ = Sync()
+			return
+		}
+		_ = Close()
+		panic(r)
+	}
+}
+
+// DebugContext 记录调试级别的结构化日志，并传递 context
+//
+// context 会传给 handler，用于提取 trace_id、span_id 等链路信息
+// （见 [Config].TraceContext）。
+func DebugContext(ctx context.Context, msg string, attrs ...any) {
+	logAt(ctx, slog.Default(), slog.LevelDebug, msg, attrs...)
+}
+
+// InfoContext 记录信息级别的结构化日志，并传递 context
+//
+//	logger.InfoContext(ctx, "订单创建成功", "order_id", orderID)
+func InfoContext(ctx context.Context, msg string, attrs ...any) {
+	logAt(ctx, slog.Default(), slog.LevelInfo, msg, attrs...)
+}
+
+// WarnContext 记录警告级别的结构化日志，并传递 context
+func WarnContext(ctx context.Context, msg string, attrs ...any) {
+	logAt(ctx, slog.Default(), slog.LevelWarn, msg, attrs...)
+}
+
+// ErrorContext 记录错误级别的结构化日志，并传递 context
+func ErrorContext(ctx context.Context, msg string, attrs ...any) {
+	logAt(ctx, slog.Default(), slog.LevelError, msg, attrs...)
+}
+
+// With 返回带有 attrs 属性的 logger，基于 [InitCfg] 配置的全局 logger
+//
+// 与 slog.With 不同，即使 slog.SetDefault 被替换也始终使用本包的配置；
+// 重新初始化后已派生的 logger 同样使用新配置，初始化之前记录的日志会被丢弃。
+//
+//	log := logger.With("user_id", 123)
+func With(attrs ...any) *slog.Logger {
+	return slog.New(globalHandler).With(attrs...)
+}
+
+// Group 返回将之后的属性嵌套在 name 分组下的 logger，基于 [InitCfg] 配置的全局 logger
+//
+//	logger.Group("db").Info("query", "rows", 5) // JSON: {"db":{"rows":5}}，其他格式: db.rows=5
+func Group(name string) *slog.Logger {
+	return slog.New(globalHandler).WithGroup(name)
+}
+
+// Timer 开始计时并返回结束函数，调用结束函数时以 INFO 级别记录 msg 和耗时 (duration)
+//
+// 配合 defer 一行完成计时：
+//
+//	defer logger.Timer("handleRequest", "user_id", 123)()
+func Timer(msg string, attrs ...any) func() {
+	return TimerWithThreshold(0, msg, attrs...)
+}
+
+// TimerWithThreshold 与 [Timer] 相同，耗时超过 threshold 时记录为 WARN，threshold 为 0 表示始终记录为 INFO
+//
+//	defer logger.TimerWithThreshold(time.Second, "query", "table", "users")()
+func TimerWithThreshold(threshold time.Duration, msg string, attrs ...any) func() {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		level := slog.LevelInfo
+		if threshold > 0 && elapsed > threshold {
+			level = slog.LevelWarn
+		}
+		if !Enabled(level) {
+			return
+		}
+		logAt(context.Background(), slog.Default(), level, msg, append(attrs[:len(attrs):len(attrs)], "duration", FormatDuration(elapsed))...)
+	}
+}
+
+// logAt 使用 logger 记录一条日志，调用位置 (source) 指向调用辅助函数的代码
+//
+// 直接转调 slog.Info 等函数时，slog 记录的调用位置会是本文件，
+// 因此这里自行构造记录并跳过辅助函数的栈帧
+func logAt(ctx context.Context, logger *slog.Logger, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:]) // 跳过 runtime.Callers、logAt 和辅助函数自身
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = logger.Handler().Handle(ctx, r)
 }
 
 // 上海时区固定偏移（UTC+8），用于 time.LoadLocation 失败时的后备方案
@@ -110,24 +505,32 @@ func loadTimezone(timezone string) *time.Location {
 	if timezone == "" {
 		timezone = "Asia/Shanghai"
 	}
+	if loc, ok := lookupTimezone(timezone); ok {
+		return loc
+	}
+	// 最终后备：上海时区
+	return shanghaiTimezone
+}
 
+// lookupTimezone 按 loadTimezone 支持的格式查找时区，无法识别时返回 false
+//
+// "UTC" 和 "Local"（系统本地时区）由 time.LoadLocation 直接支持
+func lookupTimezone(timezone string) (*time.Location, bool) {
 	// 首先尝试 time.LoadLocation（依赖系统时区数据）
 	if loc, err := time.LoadLocation(timezone); err == nil {
-		return loc
+		return loc, true
 	}
 
 	// 尝试解析固定偏移格式（如 "+08:00", "-05:00", "+0800"）
 	if loc := parseFixedOffset(timezone); loc != nil {
-		return loc
+		return loc, true
 	}
 
 	// 对于已知的时区名称，使用固定偏移作为后备
 	if loc := knownTimezoneOffset(timezone); loc != nil {
-		return loc
+		return loc, true
 	}
-
-	// 最终后备：上海时区
-	return shanghaiTimezone
+	return nil, false
 }
 
 // parseFixedOffset 解析固定偏移格式的时区字符串
@@ -176,16 +579,16 @@ func parseFixedOffset(s string) *time.Location {
 func knownTimezoneOffset(timezone string) *time.Location {
 	offsets := map[string]int{
 		// 亚洲
-		"Asia/Shanghai":    8 * 3600,
-		"Asia/Hong_Kong":   8 * 3600,
-		"Asia/Taipei":      8 * 3600,
-		"Asia/Singapore":   8 * 3600,
-		"Asia/Tokyo":       9 * 3600,
-		"Asia/Seoul":       9 * 3600,
+		"Asia/Shanghai":  8 * 3600,
+		"Asia/Hong_Kong": 8 * 3600,
+		"Asia/Taipei":    8 * 3600,
+		"Asia/Singapore": 8 * 3600,
+		"Asia/Tokyo":     9 * 3600,
+		"Asia/Seoul":     9 * 3600,
 		// 欧洲（标准时间，不考虑夏令时）
-		"Europe/London":    0,
-		"Europe/Paris":     1 * 3600,
-		"Europe/Berlin":    1 * 3600,
+		"Europe/London": 0,
+		"Europe/Paris":  1 * 3600,
+		"Europe/Berlin": 1 * 3600,
 		// 美洲（标准时间）
 		"America/New_York":    -5 * 3600,
 		"America/Los_Angeles": -8 * 3600,
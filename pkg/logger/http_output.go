@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// http 输出的重试参数
+const (
+	httpMaxRetries   = 3
+	httpRetryBackoff = 200 * time.Millisecond
+	httpTimeout      = 10 * time.Second
+)
+
+func init() {
+	registerOutput("http", newHTTPOutput)
+	registerOutput("https", newHTTPOutput)
+}
+
+// newHTTPOutput 创建 HTTP 输出：记录按批以 JSON 数组 POST 到 target
+//
+// 格式为 gelf、ecs 时发送对应格式的对象，其他格式一律使用 json。
+// 批量参数由 BatchSize、FlushInterval 控制，队列长度为 BufferSize，
+// 请求头（例如认证信息）由 Headers 指定。
+func newHTTPOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	sink := &httpSink{
+		url:     target,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: httpTimeout},
+		backoff: httpRetryBackoff,
+	}
+	writer := newBatchWriter(target, sink.send, cfg.BatchSize, cfg.FlushInterval, cfg.BufferSize)
+
+	switch format {
+	case "gelf", "ecs":
+	default:
+		format = "json"
+	}
+	return createHandler(cfg, format, writer, level), writer, nil
+}
+
+// httpSink 以 JSON 数组 POST 一批记录，5xx 和网络错误时按指数退避重试
+type httpSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+	backoff time.Duration // 首次重试前的等待时间，之后每次翻倍
+}
+
+// send 发送一批记录，每条记录为一个 JSON 对象
+func (s *httpSink) send(batch [][]byte) error {
+	body := make([]byte, 0, 2+len(batch)*256)
+	body = append(body, '[')
+	for i, record := range batch {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, bytes.TrimRight(record, "\n")...)
+	}
+	body = append(body, ']')
+	return s.deliver(body)
+}
+
+// deliver 发送请求体，5xx 和网络错误时按指数退避重试
+func (s *httpSink) deliver(body []byte) error {
+	var err error
+	backoff := s.backoff
+	for attempt := 0; attempt <= httpMaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		if retry, err = s.post(body); err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post 发送一次请求，返回是否值得重试
+func (s *httpSink) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range s.headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		return true, fmt.Errorf("POST %s: %s", s.url, resp.Status)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("POST %s: %s", s.url, resp.Status)
+	}
+	return false, nil
+}
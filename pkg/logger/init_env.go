@@ -1,14 +1,27 @@
 package logger
 
 import (
+	"errors"
 	"io"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
-// globalCloser 保存全局 logger 的可关闭资源
-var globalCloser io.Closer
+var (
+	// globalMu 保护 globalCloser 和 reloadFile，串行化全局 logger 的初始化和关闭
+	globalMu sync.Mutex
+	// globalCloser 保存全局 logger 的可关闭资源
+	globalCloser io.Closer
+
+	// initMu 串行化全局 logger 的重新配置（InitCfg、SetOutput、SetFormat）
+	initMu sync.Mutex
+	// globalConfig 全局 logger 当前使用的配置副本，SetOutput、SetFormat 在其基础上修改
+	globalConfig *Config
+)
 
 // InitCfg 使用配置初始化全局日志系统
 //
@@ -28,28 +41,151 @@ var globalCloser io.Closer
 //	    // ...
 //	}
 func InitCfg(cfg *Config) error {
-	logger, closer, err := NewWithCloser(cfg)
+	initMu.Lock()
+	defer initMu.Unlock()
+	return initCfg(cfg)
+}
+
+// initCfg 初始化全局 logger，调用者需持有 initMu
+func initCfg(cfg *Config) error {
+	if cfg == nil {
+		cfg = defaultConfig()
+	}
+	// 使用全局 levelVar，以便通过 SetLevel 在运行时调整级别
+	logger, closer, err := newLogger(cfg, levelVar)
 	if err != nil {
 		return err
 	}
-	// 关闭之前的 closer（忽略错误，因为我们正在替换它）
+	levelVar.Set(parseLevel(cfg.Level))
+	// 最近记录缓冲区包装在 DefaultAttrs 等基础属性之外，只保存记录自身和之后 With 添加的属性；
+	// 配置了 TimeFunc 时外层再替换一次时间，缓冲区中的记录同样使用注入的时钟
+	if cfg.RingSize > 0 {
+		ring := newRingBuffer(cfg.RingSize, buildReplaceAttr(cfg))
+		var handler slog.Handler = newRingHandler(logger.Handler(), ring, cfg.Filter)
+		if cfg.TimeFunc != nil {
+			handler = &clockHandler{inner: handler, now: cfg.TimeFunc}
+		}
+		logger = slog.New(handler)
+		globalRing.Store(ring)
+	} else {
+		globalRing.Store(nil)
+	}
+	slowRequestThreshold.Store(int64(cfg.SlowRequestThreshold))
+	repanicAfterLog.Store(cfg.RepanicAfterLog)
+
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	// 替换根 handler 后旧的输出不会再被写入，此时关闭之前的 closer（忽略错误，因为我们正在替换它）
+	globalHandler.swap(logger.Handler())
 	if globalCloser != nil {
 		_ = globalCloser.Close()
 	}
 	globalCloser = closer
-	slog.SetDefault(logger)
+	current := *cfg
+	globalConfig = &current
+	slog.SetDefault(slog.New(globalHandler))
 	return nil
 }
 
+// SetOutput 将全局 logger 的输出替换为 w，保留其他配置和当前级别
+//
+// 相当于将 [Config].Writer 设置为 w：配置了多个输出时会全部替换为 w，多个格式时使用第一个。w 由调用者管理，不会被关闭；
+// 之前由 logger 打开的文件等输出会在替换后刷新并关闭。替换期间可以并发记录日志。
+//
+//	var buf bytes.Buffer
+//	logger.SetOutput(&buf)
+func SetOutput(w io.Writer) error {
+	if w == nil {
+		return errors.New("output writer is nil")
+	}
+	return reconfigure(func(cfg *Config) {
+		cfg.Writer = w
+	})
+}
+
+// SetFormat 修改全局 logger 的输出格式，保留其他配置和当前级别
+//
+// 无效的格式返回错误，且不改变当前配置
+func SetFormat(format string) error {
+	return reconfigure(func(cfg *Config) {
+		cfg.Format = format
+	})
+}
+
+// reconfigure 基于当前配置修改后重新初始化全局 logger，未初始化时基于默认配置
+func reconfigure(update func(cfg *Config)) error {
+	initMu.Lock()
+	defer initMu.Unlock()
+
+	cfg := defaultConfig()
+	if globalConfig != nil {
+		current := *globalConfig
+		cfg = &current
+	}
+	cfg.Level = GetLevel()
+	update(cfg)
+	return initCfg(cfg)
+}
+
 // InitEnv 从环境变量初始化全局日志系统
 //
 // 支持的环境变量：
 //   - IS_SANDBOX: 环境检测 (1/true 为开发环境，影响以下默认值)
-//   - LOG_LEVEL: 日志级别 (DEBUG, INFO, WARN, ERROR)
-//   - LOG_FORMAT: 输出格式 (json, text, color)
-//   - LOG_OUTPUT: 输出目标 (stdout, stderr, 文件路径)
+//   - LOG_LEVEL: 日志级别 (TRACE, DEBUG, INFO, WARN, ERROR，或别名 NOTICE、CRITICAL、FATAL 及数值如 -4)
+//   - LOG_FORMAT: 输出格式 (json, ndjson, json-pretty, json-color, text, color, logfmt, compact, gelf, ecs)，多个输出时可逗号分隔一一对应；
+//     color、json-color 仅在输出到终端时着色，可通过 NO_COLOR / FORCE_COLOR 禁用或强制启用
+//   - LOG_OUTPUT: 输出目标 (stdout, stderr, discard, 文件路径, syslog, syslog://host:514, journald, eventlog, https://..., loki://host:3100, cloudwatch://group/stream, kafka://broker:9092/topic, fluent://host:24224?tag=app)，可逗号分隔同时输出到多个目标
+//   - LOG_ERROR_OUTPUT: 额外写入 ERROR 及以上日志的输出目标 (例如 /var/log/app/errors.log，默认不单独输出)
+//   - LOG_ERROR_FORMAT: LOG_ERROR_OUTPUT 的格式 (默认与 LOG_FORMAT 的第一个相同)
+//   - LOG_LEVEL_ROUTES: 按级别写入不同的输出目标，代替 LOG_OUTPUT (例如 DEBUG=/var/log/app/debug.log,WARN=stderr)
+//   - LOG_SYSLOG_FACILITY: syslog facility (默认 user)
+//   - LOG_BATCH_SIZE: 网络输出每批发送的记录数 (默认 100)
+//   - LOG_FLUSH_INTERVAL: 网络输出的最长发送间隔 (默认 1s)
+//   - LOG_HEADERS: 网络输出的请求头 (例如 Authorization=Bearer xxx)
+//   - LOG_KAFKA_KEY: Kafka 输出的分区键属性名 (例如 tenant_id)
+//   - LOG_ALERT_WEBHOOK: 告警 webhook 地址 (例如 Slack Incoming Webhook)
+//   - LOG_ALERT_MIN_LEVEL: 触发告警的最低级别 (默认 ERROR)
+//   - LOG_ALERT_THROTTLE: 同一消息的最短告警间隔 (默认 1m)
+//   - LOG_MODULE_LEVELS: 具名 logger 的级别 (例如 db=DEBUG,http=WARN)
+//   - LOG_REPANIC_AFTER_LOG: Recover 记录 panic 后是否继续 panic (true, false，默认 false)
+//   - LOG_SLOW_REQUEST_THRESHOLD: HTTP 中间件的慢请求阈值，超过时记录为 WARN (例如 1s，默认 0，不检测)
+//   - LOG_RING_SIZE: 内存中保留的最近日志条数，通过 RecentLogs 读取 (默认 0，不保留)
+//   - LOG_METRICS: 是否按级别统计日志数量 (true, false)
+//   - LOG_STACKTRACE_LEVEL: 达到该级别的日志添加 stacktrace 属性 (例如 ERROR，默认不添加)
+//   - LOG_KEY_MAP: 重命名顶级字段 (例如 time=@timestamp,msg=message,level=severity)
+//   - LOG_COLORS: color 格式的配色 (例如 ERROR=brightred,msg=white,other=gray)
 //   - LOG_ADD_SOURCE: 是否添加源代码位置 (true, false)
-//   - LOG_TIME_FORMAT: 时间格式 (datetime, time, timems, rfc3339, rfc3339ms)
+//   - LOG_SOURCE_MODE: 源代码位置的显示方式 (short, package, full，默认 short)
+//   - LOG_SORT_KEYS: text 格式是否按键名排序属性 (true, false)
+//   - LOG_ALIGN_COLUMNS: text、color 格式是否对齐 level 和 msg 列 (true, false)
+//   - LOG_TIME_FORMAT: 时间格式 (datetime, time, timems, rfc3339, rfc3339ms, unix, unixms, relative，或自定义 Go 时间格式)
+//   - LOG_TIMEZONE: 时间戳时区 (例如 UTC, Local, America/New_York, +08:00，默认 Asia/Shanghai)
+//   - LOG_DISABLE_TIME: 是否不输出 time 字段，采集端已添加时间戳时使用 (true, false)
+//   - LOG_MAX_SIZE_MB: 单个日志文件最大大小（MB），超过后轮转 (默认 0，不轮转)
+//   - LOG_MAX_BACKUPS: 保留的轮转文件数量 (默认 0，全部保留)
+//   - LOG_ROTATE_INTERVAL: 按时间轮转 (daily, hourly，默认不按时间轮转)
+//   - LOG_COMPRESS: 是否将轮转后的备份压缩为 .gz (true, false)
+//   - LOG_FILE_PERM: 新建日志文件的权限 (例如 0640，默认 0644)
+//   - LOG_DIR_PERM: 自动创建日志目录的权限 (例如 0750，默认 0755)
+//   - LOG_MAX_FIELD_LEN: 字符串属性值和消息的最大字节数，超出部分截断 (默认 0，不限制)
+//   - LOG_MAX_LINE_BYTES: 格式化后每行的最大字节数，超出时缩减属性或截断整行 (默认 0，不限制)
+//   - LOG_ERROR_CHAIN: 是否将 error 属性展开为错误链 (true, false)
+//   - LOG_MAX_ATTRS: 每条日志的最大属性数，超出的属性被丢弃 (默认 0，不限制)
+//   - LOG_REDACT_KEYS: 需要脱敏的属性键，逗号分隔 (例如 password,token)
+//   - LOG_DEFAULT_ATTRS: 附加到每条日志的固定属性 (例如 service=api,version=1.2.3)
+//   - LOG_ADD_HOST_PID: 是否添加主机名和进程 ID (true, false)
+//   - LOG_ADD_GID: 是否添加 goroutine ID (true, false)
+//   - LOG_TRACE_CONTEXT: 是否从 context 提取 OpenTelemetry trace_id/span_id (true, false)
+//   - LOG_ASYNC: 是否异步写入 (true, false)
+//   - LOG_BUFFER_SIZE: 异步写入缓冲的记录数 (默认 1024)
+//   - LOG_OVERFLOW_POLICY: 缓冲区满时的策略 (block, drop，默认 block)
+//   - LOG_FLUSH_ON_LEVEL: 达到该级别的日志写出后立即刷新输出 (例如 ERROR，默认不刷新)
+//   - LOG_SAMPLE_EVERY: DEBUG/INFO 日志每 N 条输出 1 条 (默认 0，不采样)
+//   - LOG_SAMPLE_FIRST: 每个周期内相同级别和消息的日志只输出前 N 条 (默认 0，不按消息采样)
+//   - LOG_SAMPLE_THEREAFTER: 超过 LOG_SAMPLE_FIRST 后每 M 条输出 1 条 (默认 0，全部丢弃)
+//   - LOG_SAMPLE_TICK: LOG_SAMPLE_FIRST 计数器的重置间隔 (默认 1s)
+//   - LOG_RATE_LIMIT: 每秒最多输出的日志数 (默认 0，不限制)
+//   - LOG_DEDUP_WINDOW: 合并连续重复日志的窗口 (例如 5s，默认 0，不去重)
 //
 // 默认值：
 //
@@ -60,6 +196,15 @@ func InitCfg(cfg *Config) error {
 //	| LOG_ADD_SOURCE | true                    | false          |
 //	| LOG_TIME_FORMAT| time (15:04:05)         | datetime       |
 func InitEnv() error {
+	if err := InitCfg(envConfig()); err != nil {
+		return err
+	}
+	setReloadFile("")
+	return nil
+}
+
+// envConfig 按 InitEnv 的规则从环境变量生成配置
+func envConfig() *Config {
 	isSandbox := isSandboxEnv()
 
 	// 根据环境选择默认值
@@ -76,23 +221,109 @@ func InitEnv() error {
 	}
 
 	cfg := &Config{
-		Level:      getEnv("LOG_LEVEL", defaultLevel),
-		Format:     getEnv("LOG_FORMAT", defaultFormat),
-		Output:     getEnv("LOG_OUTPUT", "stdout"),
-		AddSource:  getEnvBool("LOG_ADD_SOURCE", defaultAddSource),
-		TimeFormat: getEnv("LOG_TIME_FORMAT", defaultTimeFormat),
+		Level:      defaultLevel,
+		Format:     defaultFormat,
+		Output:     "stdout",
+		AddSource:  defaultAddSource,
+		TimeFormat: defaultTimeFormat,
 		Timezone:   "Asia/Shanghai",
 	}
+	applyEnv(cfg)
+	return cfg
+}
 
-	return InitCfg(cfg)
+// applyEnv 用已设置的 LOG_* 环境变量覆盖配置，未设置的环境变量保留 cfg 中的原值
+func applyEnv(cfg *Config) {
+	cfg.Level = getEnv("LOG_LEVEL", cfg.Level)
+	cfg.Format = getEnv("LOG_FORMAT", cfg.Format)
+	cfg.Output = getEnv("LOG_OUTPUT", cfg.Output)
+	cfg.ErrorOutput = getEnv("LOG_ERROR_OUTPUT", cfg.ErrorOutput)
+	cfg.ErrorFormat = getEnv("LOG_ERROR_FORMAT", cfg.ErrorFormat)
+	cfg.AddSource = getEnvBool("LOG_ADD_SOURCE", cfg.AddSource)
+	cfg.SourceMode = getEnv("LOG_SOURCE_MODE", cfg.SourceMode)
+	cfg.SortKeys = getEnvBool("LOG_SORT_KEYS", cfg.SortKeys)
+	cfg.AlignColumns = getEnvBool("LOG_ALIGN_COLUMNS", cfg.AlignColumns)
+	cfg.TimeFormat = getEnv("LOG_TIME_FORMAT", cfg.TimeFormat)
+	cfg.Timezone = getEnv("LOG_TIMEZONE", cfg.Timezone)
+	cfg.DisableTime = getEnvBool("LOG_DISABLE_TIME", cfg.DisableTime)
+	cfg.MaxSizeMB = getEnvInt("LOG_MAX_SIZE_MB", cfg.MaxSizeMB)
+	cfg.MaxBackups = getEnvInt("LOG_MAX_BACKUPS", cfg.MaxBackups)
+	cfg.RotateInterval = getEnv("LOG_ROTATE_INTERVAL", cfg.RotateInterval)
+	cfg.Compress = getEnvBool("LOG_COMPRESS", cfg.Compress)
+	cfg.FilePerm = getEnv("LOG_FILE_PERM", cfg.FilePerm)
+	cfg.DirPerm = getEnv("LOG_DIR_PERM", cfg.DirPerm)
+	if v := getEnvList("LOG_REDACT_KEYS"); v != nil {
+		cfg.RedactKeys = v
+	}
+	cfg.MaxFieldLen = getEnvInt("LOG_MAX_FIELD_LEN", cfg.MaxFieldLen)
+	cfg.MaxLineBytes = getEnvInt("LOG_MAX_LINE_BYTES", cfg.MaxLineBytes)
+	cfg.ErrorChain = getEnvBool("LOG_ERROR_CHAIN", cfg.ErrorChain)
+	cfg.MaxAttrs = getEnvInt("LOG_MAX_ATTRS", cfg.MaxAttrs)
+	if v := getEnvMap("LOG_DEFAULT_ATTRS"); v != nil {
+		cfg.DefaultAttrs = v
+	}
+	cfg.AddHostPID = getEnvBool("LOG_ADD_HOST_PID", cfg.AddHostPID)
+	cfg.AddGoroutineID = getEnvBool("LOG_ADD_GID", cfg.AddGoroutineID)
+	cfg.TraceContext = getEnvBool("LOG_TRACE_CONTEXT", cfg.TraceContext)
+	cfg.Async = getEnvBool("LOG_ASYNC", cfg.Async)
+	cfg.BufferSize = getEnvInt("LOG_BUFFER_SIZE", cfg.BufferSize)
+	cfg.OverflowPolicy = getEnv("LOG_OVERFLOW_POLICY", cfg.OverflowPolicy)
+	cfg.FlushOnLevel = getEnv("LOG_FLUSH_ON_LEVEL", cfg.FlushOnLevel)
+	cfg.SampleEvery = getEnvInt("LOG_SAMPLE_EVERY", cfg.SampleEvery)
+	cfg.SampleFirst = getEnvInt("LOG_SAMPLE_FIRST", cfg.SampleFirst)
+	cfg.SampleThereafter = getEnvInt("LOG_SAMPLE_THEREAFTER", cfg.SampleThereafter)
+	cfg.SampleTick = getEnvDuration("LOG_SAMPLE_TICK", cfg.SampleTick)
+	cfg.RateLimit = getEnvInt("LOG_RATE_LIMIT", cfg.RateLimit)
+	cfg.DedupWindow = getEnvDuration("LOG_DEDUP_WINDOW", cfg.DedupWindow)
+	if v := getEnvMap("LOG_KEY_MAP"); v != nil {
+		cfg.KeyMap = v
+	}
+	if v := getEnvMap("LOG_COLORS"); v != nil {
+		cfg.Colors = v
+	}
+	cfg.SyslogFacility = getEnv("LOG_SYSLOG_FACILITY", cfg.SyslogFacility)
+	cfg.BatchSize = getEnvInt("LOG_BATCH_SIZE", cfg.BatchSize)
+	cfg.FlushInterval = getEnvDuration("LOG_FLUSH_INTERVAL", cfg.FlushInterval)
+	if v := getEnvMap("LOG_HEADERS"); v != nil {
+		cfg.Headers = v
+	}
+	cfg.KafkaKey = getEnv("LOG_KAFKA_KEY", cfg.KafkaKey)
+	cfg.AlertWebhook = getEnv("LOG_ALERT_WEBHOOK", cfg.AlertWebhook)
+	cfg.AlertMinLevel = getEnv("LOG_ALERT_MIN_LEVEL", cfg.AlertMinLevel)
+	cfg.AlertThrottle = getEnvDuration("LOG_ALERT_THROTTLE", cfg.AlertThrottle)
+	cfg.RingSize = getEnvInt("LOG_RING_SIZE", cfg.RingSize)
+	cfg.Metrics = getEnvBool("LOG_METRICS", cfg.Metrics)
+	cfg.StacktraceLevel = getEnv("LOG_STACKTRACE_LEVEL", cfg.StacktraceLevel)
+	if v := getEnvMap("LOG_LEVEL_ROUTES"); v != nil {
+		cfg.LevelRoutes = v
+	}
+	if v := getEnvMap("LOG_MODULE_LEVELS"); v != nil {
+		cfg.ModuleLevels = v
+	}
+	cfg.SlowRequestThreshold = getEnvDuration("LOG_SLOW_REQUEST_THRESHOLD", cfg.SlowRequestThreshold)
+	cfg.RepanicAfterLog = getEnvBool("LOG_REPANIC_AFTER_LOG", cfg.RepanicAfterLog)
+}
+
+// Sync 刷新全局 logger 的输出，确保已记录的日志写入目标
+//
+// 输出到 stdout/stderr 时为空操作；可在 main 中 defer logger.Sync()
+func Sync() error {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if globalCloser != nil {
+		return syncResource(globalCloser)
+	}
+	return nil
 }
 
 // Close 关闭全局 logger 的资源（如文件）
 //
-// 应在程序退出时调用，确保日志文件正确关闭
+// 应在程序退出时调用，关闭前会先刷新输出，确保日志文件完整写入
 func Close() error {
+	globalMu.Lock()
+	defer globalMu.Unlock()
 	if globalCloser != nil {
-		err := globalCloser.Close()
+		err := errors.Join(syncResource(globalCloser), globalCloser.Close())
 		globalCloser = nil
 		return err
 	}
@@ -121,3 +352,57 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return strings.ToLower(value) == "true" || value == "1"
 }
+
+// getEnvInt 获取整数类型的环境变量，无法解析时返回默认值
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return defaultValue
+	}
+	return n
+}
+
+// getEnvDuration 获取时长类型的环境变量 (例如 500ms, 5s)，无法解析时返回默认值
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return defaultValue
+	}
+	return d
+}
+
+// getEnvList 获取逗号分隔的列表类型环境变量，忽略空元素
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// getEnvMap 获取 "k1=v1,k2=v2" 形式的键值对环境变量，忽略格式不正确的项
+func getEnvMap(key string) map[string]string {
+	items := getEnvList(key)
+	if len(items) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(items))
+	for _, item := range items {
+		k, v, ok := strings.Cut(item, "=")
+		if k = strings.TrimSpace(k); !ok || k == "" {
+			continue
+		}
+		m[k] = strings.TrimSpace(v)
+	}
+	return m
+}
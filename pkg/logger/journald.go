@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// defaultJournaldSocket systemd-journald 原生协议的 socket 路径
+const defaultJournaldSocket = "/run/systemd/journal/socket"
+
+func init() {
+	registerOutput("journald", newJournaldOutput)
+}
+
+// newJournaldOutput 创建 journald 输出，使用原生协议发送结构化字段（仅 Linux）：
+//   - journald: 默认 socket /run/systemd/journal/socket
+//   - journald:///path/to/socket: 指定的 socket
+//
+// 每条记录发送 MESSAGE、PRIORITY（按 syslog 严重级别映射）、SYSLOG_IDENTIFIER，
+// 属性以大写字段名发送（分组内的属性为 GROUP_KEY），启用 AddSource 时附带 CODE_FILE、CODE_LINE、CODE_FUNC；
+// 字段本身即是结构，format 对该输出无效
+func newJournaldOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	if !journaldSupported {
+		return nil, nil, fmt.Errorf("journald output is not supported on this platform")
+	}
+
+	path := defaultJournaldSocket
+	if target != "journald" {
+		u, err := url.Parse(target)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid journald target %q: %w", target, err)
+		}
+		if u.Path != "" {
+			path = u.Path
+		}
+	}
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("connect to journald %s: %w", path, err)
+	}
+
+	h := &journaldHandler{
+		opts: &slog.HandlerOptions{
+			Level:       level,
+			AddSource:   cfg.AddSource,
+			ReplaceAttr: buildReplaceAttr(cfg),
+		},
+		conn:       conn,
+		identifier: filepath.Base(os.Args[0]),
+	}
+	return h, conn, nil
+}
+
+// journaldHandler 以 journald 原生协议发送记录的 handler，每条记录为一个数据报
+type journaldHandler struct {
+	opts       *slog.HandlerOptions
+	conn       net.Conn
+	identifier string
+	groups     []string // 当前 group 路径
+	preFields  []byte   // 预先编码的字段（已包含 group 前缀）
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *journaldHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *journaldHandler) Handle(ctx context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256+len(h.preFields))
+	buf = appendJournaldField(buf, "MESSAGE", replaceMessage(h.opts.ReplaceAttr, r.Message))
+	buf = appendJournaldField(buf, "PRIORITY", strconv.Itoa(syslogSeverity(r.Level)))
+	buf = appendJournaldField(buf, "SYSLOG_IDENTIFIER", h.identifier)
+
+	// 添加源代码位置（如果启用）
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		if f.File != "" {
+			buf = appendJournaldField(buf, "CODE_FILE", f.File)
+			buf = appendJournaldField(buf, "CODE_LINE", strconv.Itoa(f.Line))
+			buf = appendJournaldField(buf, "CODE_FUNC", f.Function)
+		}
+	}
+
+	buf = append(buf, h.preFields...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, a)
+		return true
+	})
+
+	// 数据报写入是原子的，无需加锁
+	_, err := h.conn.Write(buf)
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *journaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	preFields := append([]byte(nil), h.preFields...)
+	for _, attr := range attrs {
+		preFields = h.appendAttr(preFields, attr)
+	}
+
+	return &journaldHandler{
+		opts:       h.opts,
+		conn:       h.conn,
+		identifier: h.identifier,
+		groups:     h.groups,
+		preFields:  preFields,
+	}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *journaldHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &journaldHandler{
+		opts:       h.opts,
+		conn:       h.conn,
+		identifier: h.identifier,
+		groups:     append(h.groups[:len(h.groups):len(h.groups)], name),
+		preFields:  h.preFields,
+	}
+}
+
+// appendAttr 对属性应用 ReplaceAttr 后追加为当前 group 路径下的字段
+func (h *journaldHandler) appendAttr(buf []byte, a slog.Attr) []byte {
+	a, ok := replaceAttr(h.opts.ReplaceAttr, h.groups, a)
+	if !ok {
+		return buf
+	}
+	prefix := ""
+	if len(h.groups) > 0 {
+		prefix = strings.Join(h.groups, "_") + "_"
+	}
+	return appendJournaldAttr(buf, prefix, a)
+}
+
+// appendJournaldAttr 追加已处理过的属性，分组递归展开为 "GROUP_KEY"
+func appendJournaldAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	if a.Value.Kind() == slog.KindGroup {
+		// 空键分组内联到当前层级
+		if a.Key != "" {
+			prefix += a.Key + "_"
+		}
+		for _, attr := range a.Value.Group() {
+			buf = appendJournaldAttr(buf, prefix, attr)
+		}
+		return buf
+	}
+	return appendJournaldField(buf, journaldFieldName(prefix+a.Key), logfmtValue(a.Value))
+}
+
+// appendJournaldField 按原生协议追加一个字段
+//
+// 不含换行的值编码为 "KEY=value\n"；含换行的值编码为 "KEY\n"、64 位小端长度、值和 "\n"
+func appendJournaldField(buf []byte, key, value string) []byte {
+	buf = append(buf, key...)
+	if !strings.ContainsRune(value, '\n') {
+		buf = append(buf, '=')
+		buf = append(buf, value...)
+		return append(buf, '\n')
+	}
+	buf = append(buf, '\n')
+	buf = binary.LittleEndian.AppendUint64(buf, uint64(len(value)))
+	buf = append(buf, value...)
+	return append(buf, '\n')
+}
+
+// journaldFieldName 将属性键转换为 journald 字段名
+//
+// 字段名只能包含大写字母、数字和下划线，不能以下划线或数字开头（下划线开头的是 journald 的可信字段），
+// 最长 64 字节；其他字符替换为下划线，开头不合法时加 "X_" 前缀
+func journaldFieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "X_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,4 @@
+package logger
+
+// journaldSupported 当前平台是否支持 journald 输出
+const journaldSupported = true
@@ -0,0 +1,6 @@
+//go:build !linux
+
+package logger
+
+// journaldSupported 当前平台是否支持 journald 输出
+const journaldSupported = false
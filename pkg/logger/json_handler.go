@@ -1,13 +1,14 @@
 package logger
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log/slog"
 	"runtime"
-	"strconv"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -21,6 +22,9 @@ type customJSONHandler struct {
 	groups     []string       // 当前 group 路径
 	preAttrs   map[string]any // 预计算的属性（已考虑 group 嵌套）
 	location   *time.Location // 缓存的时区
+	ordered    bool           // 固定字段顺序（ndjson）：time、level、msg 在前，其余按字母顺序
+	indent     bool           // 缩进输出（json-pretty），仅用于本地调试
+	colored    bool           // 缩进并着色输出（json-color），仅在终端中启用
 }
 
 // newJSONHandler 创建自定义 JSON handler
@@ -44,6 +48,38 @@ func newJSONHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, t
 	}
 }
 
+// newNDJSONHandler 创建 NDJSON handler
+//
+// 每条记录为一行紧凑的 JSON，字段顺序固定：time、level、msg 在前，其余属性按字母顺序，
+// 便于 jq -c 和批量导入工具处理，多次运行的输出顺序一致
+func newNDJSONHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, timezone string) *customJSONHandler {
+	h := newJSONHandler(w, opts, timeFormat, timezone)
+	h.ordered = true
+	return h
+}
+
+// newPrettyJSONHandler 创建缩进输出的 JSON handler，字段顺序与 ndjson 相同
+//
+// 每条记录输出为一个缩进的 JSON 块，仅用于本地调试时阅读，不要用于生产环境
+func newPrettyJSONHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, timezone string) *customJSONHandler {
+	h := newNDJSONHandler(w, opts, timeFormat, timezone)
+	h.indent = true
+	return h
+}
+
+// newColorJSONHandler 创建着色的 JSON handler（json-color）
+//
+// w 为终端且未设置 NO_COLOR 时（规则同 color 格式，见 [colorEnabled]），输出与 json-pretty 相同的缩进 JSON，
+// 并为键、字符串、数字和 true/false/null 着色；否则退化为普通的紧凑 JSON（与 json 格式相同）
+func newColorJSONHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, timezone string) *customJSONHandler {
+	if !colorEnabled(w) {
+		return newJSONHandler(w, opts, timeFormat, timezone)
+	}
+	h := newPrettyJSONHandler(w, opts, timeFormat, timezone)
+	h.colored = true
+	return h
+}
+
 // Enabled 实现 slog.Handler 接口
 func (h *customJSONHandler) Enabled(ctx context.Context, level slog.Level) bool {
 	minLevel := slog.LevelInfo
@@ -61,22 +97,21 @@ func (h *customJSONHandler) Handle(ctx context.Context, r slog.Record) error {
 	// 构建 JSON 对象
 	m := make(map[string]any)
 
-	// 添加时间字段
-	m["time"] = h.formatTime(r.Time)
-
-	// 添加级别字段
-	m["level"] = r.Level.String()
-
-	// 添加消息字段
-	m["msg"] = r.Message
+	// 添加时间、级别和消息字段，记录 ReplaceAttr 之后的键名用于排序
+	var builtins [3]string
+	builtins[0] = h.addBuiltin(m, slog.TimeKey, h.formatTime(r.Time))
+	builtins[1] = h.addBuiltin(m, slog.LevelKey, levelName(r.Level))
+	builtins[2] = h.addBuiltin(m, slog.MessageKey, r.Message)
 
 	// 添加源代码位置（如果启用）
 	if h.opts.AddSource && r.PC != 0 {
 		fs := runtime.CallersFrames([]uintptr{r.PC})
 		f, _ := fs.Next()
 		if f.File != "" {
-			source := fmt.Sprintf("%s:%d", f.File, f.Line)
-			m["source"] = clipWorkspacePath(source)
+			src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			if a, ok := sourceAttr(h.opts.ReplaceAttr, src, clipWorkspacePath); ok {
+				m[a.Key] = jsonValue(a.Value)
+			}
 		}
 	}
 
@@ -87,15 +122,25 @@ func (h *customJSONHandler) Handle(ctx context.Context, r slog.Record) error {
 
 	// 添加记录中的属性（需要考虑当前 group 路径）
 	r.Attrs(func(a slog.Attr) bool {
-		h.setNestedAttr(m, h.groups, a.Key, a.Value.Any())
+		h.addAttr(m, a)
 		return true
 	})
 
 	// 序列化为 JSON
-	data, err := json.Marshal(m)
+	data, err := h.marshal(m, builtins[:])
 	if err != nil {
 		return err
 	}
+	if h.indent {
+		var out bytes.Buffer
+		if err := json.Indent(&out, data, "", "  "); err != nil {
+			return err
+		}
+		data = out.Bytes()
+	}
+	if h.colored {
+		data = colorizeJSON(data)
+	}
 
 	// 写入
 	_, err = h.writer.Write(append(data, '\n'))
@@ -112,7 +157,7 @@ func (h *customJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 
 	// 将新属性添加到当前 group 路径下
 	for _, attr := range attrs {
-		h.setNestedAttr(newPreAttrs, h.groups, attr.Key, attr.Value.Any())
+		h.addAttr(newPreAttrs, attr)
 	}
 
 	return &customJSONHandler{
@@ -122,6 +167,9 @@ func (h *customJSONHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		groups:     h.groups,
 		preAttrs:   newPreAttrs,
 		location:   h.location,
+		ordered:    h.ordered,
+		indent:     h.indent,
+		colored:    h.colored,
 	}
 }
 
@@ -148,7 +196,112 @@ func (h *customJSONHandler) WithGroup(name string) slog.Handler {
 		groups:     newGroups,
 		preAttrs:   newPreAttrs,
 		location:   h.location,
+		ordered:    h.ordered,
+		indent:     h.indent,
+		colored:    h.colored,
+	}
+}
+
+// marshal 序列化记录，ordered 时将 first 中的内置字段（可能已被 ReplaceAttr 重命名）按顺序提到最前
+//
+// json.Marshal 对 map 按键的字母顺序输出，因此只需将内置字段提到最前
+func (h *customJSONHandler) marshal(m map[string]any, first []string) ([]byte, error) {
+	if !h.ordered {
+		return json.Marshal(m)
+	}
+
+	buf := []byte{'{'}
+	appendField := func(key string, value any) error {
+		if len(buf) > 1 {
+			buf = append(buf, ',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return err
+		}
+		v, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		buf = append(append(append(buf, k...), ':'), v...)
+		return nil
+	}
+
+	for _, key := range first {
+		if v, ok := m[key]; ok && key != "" {
+			if err := appendField(key, v); err != nil {
+				return nil, err
+			}
+			delete(m, key)
+		}
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := appendField(key, m[key]); err != nil {
+			return nil, err
+		}
 	}
+	return append(buf, '}'), nil
+}
+
+// json-color 使用的颜色
+const (
+	jsonKeyColor     = "\033[94m" // 蓝色
+	jsonStringColor  = "\033[32m" // 绿色
+	jsonNumberColor  = "\033[33m" // 黄色
+	jsonLiteralColor = "\033[35m" // 紫色（true、false、null）
+)
+
+// colorizeJSON 为合法的 JSON 添加 ANSI 颜色：键、字符串值、数字和 true/false/null 使用不同颜色
+func colorizeJSON(data []byte) []byte {
+	out := make([]byte, 0, len(data)+len(data)/2)
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(data) && data[end] != '"' {
+				if data[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(data))
+			// 之后的第一个非空白字符为 ':' 时是键
+			next := end
+			for next < len(data) && (data[next] == ' ' || data[next] == '\n' || data[next] == '\t' || data[next] == '\r') {
+				next++
+			}
+			color := jsonStringColor
+			if next < len(data) && data[next] == ':' {
+				color = jsonKeyColor
+			}
+			out = append(append(append(out, color...), data[i:end]...), colorReset...)
+			i = end
+		case c == '-' || ('0' <= c && c <= '9'):
+			end := i + 1
+			for end < len(data) && strings.IndexByte("0123456789+-.eE", data[end]) >= 0 {
+				end++
+			}
+			out = append(append(append(out, jsonNumberColor...), data[i:end]...), colorReset...)
+			i = end
+		case c == 't' || c == 'f' || c == 'n':
+			end := i + 1
+			for end < len(data) && 'a' <= data[end] && data[end] <= 'z' {
+				end++
+			}
+			out = append(append(append(out, jsonLiteralColor...), data[i:end]...), colorReset...)
+			i = end
+		default:
+			out = append(out, c)
+			i++
+		}
+	}
+	return out
 }
 
 // formatTime 根据配置格式化时间
@@ -158,39 +311,70 @@ func (h *customJSONHandler) formatTime(t time.Time) interface{} {
 		t = t.In(h.location)
 	}
 
-	switch h.timeFormat {
-	case "unix":
-		// Unix 时间戳（秒）
-		return t.Unix()
-	case "unixms":
-		// Unix 时间戳（毫秒）
-		return t.UnixMilli()
-	case "unixnano":
-		// Unix 时间戳（纳秒）
-		return t.UnixNano()
-	case "rfc3339":
-		// RFC3339 格式（秒精度）
-		return t.Format(time.RFC3339)
-	case "rfc3339ms":
-		// RFC3339 格式（毫秒精度）
-		return t.Format("2006-01-02T15:04:05.000Z07:00")
-	case "datetime", "":
-		// 默认：简单的日期时间格式（秒精度）
-		return t.Format("2006-01-02 15:04:05")
-	case "unixfloat":
-		// Unix 时间戳（浮点数，秒+小数）
-		unixSec := t.Unix()
-		nanoRemainder := t.Nanosecond()
-		return strconv.FormatFloat(float64(unixSec)+float64(nanoRemainder)/1e9, 'f', 3, 64)
+	return formatTimeValue(t, h.timeFormat)
+}
+
+// addBuiltin 添加内置字段（time、level、msg、source），同样经过 ReplaceAttr 处理
+//
+// 传给 ReplaceAttr 的是已格式化的值，返回最终的键名，返回空键时丢弃该字段
+func (h *customJSONHandler) addBuiltin(m map[string]any, key string, value any) string {
+	a := slog.Any(key, value)
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		if a.Key == "" {
+			return ""
+		}
+	}
+	m[a.Key] = jsonValue(a.Value)
+	return a.Key
+}
+
+// addAttr 对属性应用 ReplaceAttr 后添加到当前 group 路径下
+func (h *customJSONHandler) addAttr(m map[string]any, a slog.Attr) {
+	a, ok := replaceAttr(h.opts.ReplaceAttr, h.groups, a)
+	if !ok {
+		return
+	}
+	// 空键分组内联到当前层级
+	if a.Key == "" && a.Value.Kind() == slog.KindGroup {
+		for _, attr := range a.Value.Group() {
+			setNestedAttr(m, h.groups, attr.Key, jsonValue(attr.Value))
+		}
+		return
+	}
+	setNestedAttr(m, h.groups, a.Key, jsonValue(a.Value))
+}
+
+// jsonValue 将 slog.Value 转换为可 JSON 序列化的值
+//
+// 分组转换为嵌套 map，error 转换为错误信息字符串（json.Marshal 会将其序列化为 {}）
+func jsonValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindGroup:
+		m := make(map[string]any)
+		for _, attr := range v.Group() {
+			if attr.Key == "" && attr.Value.Kind() == slog.KindGroup {
+				for k, val := range jsonValue(attr.Value).(map[string]any) {
+					m[k] = val
+				}
+				continue
+			}
+			m[attr.Key] = jsonValue(attr.Value)
+		}
+		return m
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
 	default:
-		// 默认使用日期时间格式
-		return t.Format("2006-01-02 15:04:05")
+		return v.Any()
 	}
 }
 
 // setNestedAttr 在嵌套的 map 中设置属性值
 // groups 指定了嵌套路径，例如 ["request", "headers"] 会将 key 设置在 m["request"]["headers"][key]
-func (h *customJSONHandler) setNestedAttr(m map[string]any, groups []string, key string, value any) {
+func setNestedAttr(m map[string]any, groups []string, key string, value any) {
 	if len(groups) == 0 {
 		m[key] = value
 		return
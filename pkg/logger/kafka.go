@@ -0,0 +1,190 @@
+package logger
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+func init() {
+	registerOutput("kafka", newKafkaOutput)
+}
+
+// KafkaMessage 一条待发送的 Kafka 消息
+type KafkaMessage struct {
+	Key   []byte // 分区键，为空时由客户端轮询分区
+	Value []byte
+}
+
+// KafkaProducer Kafka 的发送接口，由 kafka 子模块使用 Kafka 客户端实现
+//
+// Produce 返回发送失败的消息数和错误；Close 在发送完剩余记录后调用
+type KafkaProducer interface {
+	Produce(ctx context.Context, messages []KafkaMessage) (failed int, err error)
+	Close() error
+}
+
+// newKafkaProducer 由 [RegisterKafkaProducer] 设置，未导入 kafka 子模块时为 nil
+var newKafkaProducer func(broker, topic string) (KafkaProducer, error)
+
+// RegisterKafkaProducer 注册 Kafka 输出使用的客户端，由 kafka 子模块在 init 中调用：
+//
+//	import _ "github.com/lwmacct/251125-go-mod-logger/pkg/logger/kafka"
+//
+// Kafka 客户端只由该子模块引入，不使用 Kafka 输出的程序不会依赖它
+func RegisterKafkaProducer(newProducer func(broker, topic string) (KafkaProducer, error)) {
+	newKafkaProducer = newProducer
+}
+
+// newKafkaOutput 创建 Kafka 输出："kafka://broker:9092/topic"
+//
+// 需要导入 kafka 子模块（见 [RegisterKafkaProducer]）。每条记录以 JSON 编码为一条消息，
+// 配置了 KafkaKey 时以该属性的值作为分区键，相同键的记录写入同一分区。
+// 记录先放入有界队列，由后台 goroutine 按批发送（参数与 http 输出相同），
+// broker 不可用时队列满后丢弃新记录，不会阻塞记录日志的调用方。
+func newKafkaOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	broker, topic, _ := strings.Cut(strings.TrimPrefix(target, "kafka://"), "/")
+	if !strings.HasPrefix(target, "kafka://") || broker == "" || topic == "" {
+		return nil, nil, fmt.Errorf("kafka output requires kafka://broker:9092/topic: %q", target)
+	}
+
+	if newKafkaProducer == nil {
+		return nil, nil, errors.New("kafka output requires importing github.com/lwmacct/251125-go-mod-logger/pkg/logger/kafka")
+	}
+	producer, err := newKafkaProducer(broker, topic)
+	if err != nil {
+		return nil, nil, err
+	}
+	sink := &kafkaSink{producer: producer}
+	writer := newBatchWriter(target, sink.send, cfg.BatchSize, cfg.FlushInterval, cfg.BufferSize)
+	out := &kafkaWriter{out: writer}
+	handler := &kafkaHandler{
+		inner: createHandler(cfg, "json", out, level),
+		out:   out,
+		key:   cfg.KafkaKey,
+	}
+	return handler, newMultiCloser(writer, sink), nil
+}
+
+// kafkaHandler 提取分区键后交给 inner 格式化，分区键由 out 写在消息之前
+//
+// 分区键优先取记录自身的属性，其次取 With 添加的属性，只匹配分组之外的属性
+type kafkaHandler struct {
+	inner   slog.Handler
+	out     *kafkaWriter
+	key     string // 分区键属性名，为空表示不使用分区键
+	preset  string // With 添加的分区键的值
+	grouped bool
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *kafkaHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *kafkaHandler) Handle(ctx context.Context, r slog.Record) error {
+	key := h.preset
+	if h.key != "" && !h.grouped {
+		r.Attrs(func(a slog.Attr) bool {
+			if a.Key == h.key {
+				key = a.Value.Resolve().String()
+				return false
+			}
+			return true
+		})
+	}
+
+	h.out.mu.Lock()
+	defer h.out.mu.Unlock()
+
+	h.out.key = key
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *kafkaHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	if h.key != "" && !h.grouped {
+		for _, a := range attrs {
+			if a.Key == h.key {
+				clone.preset = a.Value.Resolve().String()
+			}
+		}
+	}
+	clone.inner = h.inner.WithAttrs(attrs)
+	return &clone
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *kafkaHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := *h
+	clone.inner = h.inner.WithGroup(name)
+	clone.grouped = true
+	return &clone
+}
+
+// kafkaWriter 将分区键（uvarint 长度 + 内容）和格式化后的记录一起放入发送队列
+type kafkaWriter struct {
+	mu  sync.Mutex // 保护 key，与 Handle 中的格式化一同持有
+	key string
+	out io.Writer
+}
+
+// Write 实现 io.Writer 接口
+func (w *kafkaWriter) Write(p []byte) (int, error) {
+	p = []byte(strings.TrimRight(string(p), "\n"))
+	entry := make([]byte, 0, binary.MaxVarintLen64+len(w.key)+len(p))
+	entry = binary.AppendUvarint(entry, uint64(len(w.key)))
+	entry = append(entry, w.key...)
+	entry = append(entry, p...)
+	if _, err := w.out.Write(entry); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// kafkaSink 解码 kafkaWriter 写入的记录并按批发送，统计发送失败的消息数
+type kafkaSink struct {
+	producer KafkaProducer
+	failed   atomic.Int64
+}
+
+// send 发送一批记录
+func (s *kafkaSink) send(batch [][]byte) error {
+	messages := make([]KafkaMessage, 0, len(batch))
+	for _, entry := range batch {
+		n, size := binary.Uvarint(entry)
+		if size <= 0 || uint64(len(entry)-size) < n {
+			continue
+		}
+		key := entry[size : size+int(n)]
+		if len(key) == 0 {
+			key = nil
+		}
+		messages = append(messages, KafkaMessage{Key: key, Value: entry[size+int(n):]})
+	}
+
+	failed, err := s.producer.Produce(context.Background(), messages)
+	s.failed.Add(int64(failed))
+	return err
+}
+
+// Failed 返回累计发送失败的消息数
+func (s *kafkaSink) Failed() int64 {
+	return s.failed.Load()
+}
+
+// Close 实现 io.Closer 接口，关闭 Kafka 客户端
+func (s *kafkaSink) Close() error {
+	return s.producer.Close()
+}
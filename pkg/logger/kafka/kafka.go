@@ -0,0 +1,69 @@
+// Package kafka 使用 github.com/segmentio/kafka-go 实现 logger 的 Kafka 输出
+//
+// 导入后即可使用 "kafka://broker:9092/topic" 输出：
+//
+//	import _ "github.com/lwmacct/251125-go-mod-logger/pkg/logger/kafka"
+//
+// 本包是独立的 Go 模块，Kafka 客户端只会进入导入了它的程序的依赖。
+package kafka
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+	"github.com/segmentio/kafka-go"
+)
+
+// writeTimeout 每批消息的发送超时时间
+const writeTimeout = 10 * time.Second
+
+func init() {
+	logger.RegisterKafkaProducer(newProducer)
+}
+
+// producer 使用 kafka.Writer 实现 logger.KafkaProducer
+type producer struct {
+	writer *kafka.Writer
+}
+
+// newProducer 创建发送到 topic 的客户端，连接在首次发送时建立
+//
+// 有分区键的消息按键哈希选择分区，没有分区键的消息轮询分区
+func newProducer(broker, topic string) (logger.KafkaProducer, error) {
+	return &producer{writer: &kafka.Writer{
+		Addr:         kafka.TCP(broker),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireOne,
+		MaxAttempts:  3,
+		BatchTimeout: 10 * time.Millisecond, // 记录已由 logger 攒批，无需再等待
+		WriteTimeout: writeTimeout,
+	}}, nil
+}
+
+// Produce 实现 logger.KafkaProducer 接口
+func (p *producer) Produce(ctx context.Context, messages []logger.KafkaMessage) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	msgs := make([]kafka.Message, len(messages))
+	for i, m := range messages {
+		msgs[i] = kafka.Message{Key: m.Key, Value: m.Value}
+	}
+	err := p.writer.WriteMessages(ctx, msgs...)
+	if err == nil {
+		return 0, nil
+	}
+	var writeErrs kafka.WriteErrors
+	if errors.As(err, &writeErrs) {
+		return writeErrs.Count(), err
+	}
+	return len(messages), err
+}
+
+// Close 实现 logger.KafkaProducer 接口
+func (p *producer) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,17 @@
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnreachableBroker(t *testing.T) {
+	// 连接不可用的 broker：发送失败只计数，记录日志和关闭都不会出错
+	log, closer, err := logger.NewWithCloser(&logger.Config{Level: "INFO", Format: "json", Output: "kafka://127.0.0.1:1/logs", FlushInterval: time.Hour})
+	require.NoError(t, err)
+	log.Info("lost")
+	require.NoError(t, closer.Close())
+}
@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+)
+
+// LevelTrace 在 slog 内置级别之外扩展的跟踪级别，比 Debug 更详细，用于输出报文等底层细节
+//
+// 没有单独的 Fatal 级别：FATAL 是 ERROR 的别名，[Fatal] 同样以 ERROR 级别记录
+const LevelTrace = slog.Level(-8)
+
+// levelVar 全局 logger 的日志级别，InitCfg 时设置，可通过 [SetLevel] 在运行时调整
+var levelVar = new(slog.LevelVar)
+
+// levelOptions 级别无法识别时错误信息中列出的可选值
+const levelOptions = "TRACE, DEBUG, INFO, WARN, ERROR, aliases NOTICE, CRITICAL, FATAL, or a number such as -4"
+
+// lookupLevel 解析日志级别字符串（大小写不敏感），无法识别时返回 false
+//
+// 为方便从 syslog 等迁移，别名映射到最接近的级别：NOTICE 为 INFO，ERR、CRIT、CRITICAL、FATAL 为 ERROR；
+// 也支持 slog 的数值级别（如 -4 即 DEBUG）和偏移写法（如 INFO+2，与 [GetLevel] 的输出一致）
+func lookupLevel(levelStr string) (slog.Level, bool) {
+	switch strings.ToUpper(levelStr) {
+	case "TRACE":
+		return LevelTrace, true
+	case "DEBUG":
+		return slog.LevelDebug, true
+	case "INFO", "NOTICE":
+		return slog.LevelInfo, true
+	case "WARN", "WARNING":
+		return slog.LevelWarn, true
+	case "ERROR", "ERR", "CRIT", "CRITICAL", "FATAL":
+		return slog.LevelError, true
+	}
+	if n, err := strconv.Atoi(levelStr); err == nil {
+		return slog.Level(n), true
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(levelStr)); err == nil {
+		return level, true
+	}
+	return slog.LevelInfo, false
+}
+
+// parseLevel 解析日志级别字符串（大小写不敏感），无法识别时默认为 INFO
+func parseLevel(levelStr string) slog.Level {
+	level, _ := lookupLevel(levelStr)
+	return level
+}
+
+// SetLevel 在运行时修改全局 logger 的日志级别
+//
+// 修改立即对之后的所有日志生效，包括包级别的辅助函数和 slog.Default()。
+// 无法识别的级别返回错误，且不改变当前级别。
+//
+//	if err := logger.SetLevel("DEBUG"); err != nil {
+//	    return err
+//	}
+func SetLevel(level string) error {
+	l, ok := lookupLevel(level)
+	if !ok {
+		return fmt.Errorf("invalid log level: %q, valid options: %s", level, levelOptions)
+	}
+	levelVar.Set(l)
+	return nil
+}
+
+// GetLevel 返回全局 logger 当前的日志级别名称，例如 "INFO"
+func GetLevel() string {
+	return levelName(levelVar.Level())
+}
+
+// levelName 返回日志级别的显示名称
+//
+// 扩展级别显示为 TRACE，其余沿用 slog 的命名（如 "INFO+2"）
+func levelName(level slog.Level) string {
+	if level == LevelTrace {
+		return "TRACE"
+	}
+	return level.String()
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// levelPayload LevelHandler 请求和响应的 JSON 结构
+type levelPayload struct {
+	Level string `json:"level,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// LevelHandler 返回用于查看和修改全局日志级别的 http.Handler
+//
+// 类似 zap 的 AtomicLevel HTTP 接口，适合挂载到管理端口：
+//   - GET: 返回当前级别，例如 {"level":"INFO"}
+//   - PUT/POST: 请求体 {"level":"DEBUG"} 修改级别，返回修改后的级别
+//
+// 无效的级别返回 400。级别保存在 slog.LevelVar 中，可与日志记录并发调用。
+//
+//	mux.Handle("/debug/log/level", logger.LevelHandler())
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			writeLevelPayload(w, http.StatusOK, levelPayload{Level: GetLevel()})
+
+		case http.MethodPut, http.MethodPost:
+			var req levelPayload
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: "invalid request body: " + err.Error()})
+				return
+			}
+			if err := SetLevel(req.Level); err != nil {
+				writeLevelPayload(w, http.StatusBadRequest, levelPayload{Error: err.Error()})
+				return
+			}
+			writeLevelPayload(w, http.StatusOK, levelPayload{Level: GetLevel()})
+
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeLevelPayload(w, http.StatusMethodNotAllowed, levelPayload{Error: "method not allowed: " + r.Method})
+		}
+	})
+}
+
+// writeLevelPayload 写入 JSON 响应
+func writeLevelPayload(w http.ResponseWriter, status int, payload levelPayload) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(payload)
+}
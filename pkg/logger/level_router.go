@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"maps"
+	"slices"
+)
+
+// levelRoute 级别路由中的一项：级别不低于 level 的记录写入 handler
+type levelRoute struct {
+	level   slog.Level
+	handler slog.Handler
+}
+
+// levelRouter 按级别将记录路由到不同输出的 handler
+//
+// 每条记录只写入一个输出：阈值不高于记录级别的路由中阈值最高的一个；
+// 低于所有阈值的记录被丢弃
+type levelRouter struct {
+	routes []levelRoute // 按 level 从高到低排列
+}
+
+// NewLevelRouter 创建按级别路由的 handler，每个 writer 使用 JSON 格式（配置同 New(nil)）
+//
+// routes 的键为阈值，每条记录写入阈值不高于其级别的最高阈值对应的 writer，例如：
+//
+//	handler := logger.NewLevelRouter(map[slog.Level]io.Writer{
+//		slog.LevelDebug: debugFile, // DEBUG、INFO
+//		slog.LevelWarn:  os.Stderr, // WARN 及以上
+//	})
+func NewLevelRouter(routes map[slog.Level]io.Writer) slog.Handler {
+	cfg := defaultConfig()
+	rs := make([]levelRoute, 0, len(routes))
+	for level, w := range routes {
+		rs = append(rs, levelRoute{level: level, handler: createHandler(cfg, "json", w, level)})
+	}
+	return newLevelRouter(rs)
+}
+
+// newLevelRouter 创建级别路由 handler，routes 无需排序
+func newLevelRouter(routes []levelRoute) *levelRouter {
+	routes = slices.Clone(routes)
+	slices.SortFunc(routes, func(a, b levelRoute) int { return int(b.level - a.level) })
+	return &levelRouter{routes: routes}
+}
+
+// openLevelRoutes 按 LevelRoutes 打开各输出，返回级别路由 handler
+//
+// 每个输出的级别为其阈值（且不低于 level）
+func openLevelRoutes(cfg *Config, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	routes := make([]levelRoute, 0, len(cfg.LevelRoutes))
+	closers := make([]io.Closer, 0, len(cfg.LevelRoutes))
+	for _, name := range slices.Sorted(maps.Keys(cfg.LevelRoutes)) {
+		threshold := parseLevel(name)
+		handler, closer, err := openOutput(cfg, cfg.LevelRoutes[name], format, maxLeveler{level, threshold})
+		if err != nil {
+			if c := newMultiCloser(closers...); c != nil {
+				_ = c.Close()
+			}
+			return nil, nil, err
+		}
+		routes = append(routes, levelRoute{level: threshold, handler: handler})
+		closers = append(closers, closer)
+	}
+	return newLevelRouter(routes), newMultiCloser(closers...), nil
+}
+
+// route 返回 level 对应的 handler，没有匹配的路由时返回 nil
+func (h *levelRouter) route(level slog.Level) slog.Handler {
+	for _, r := range h.routes {
+		if level >= r.level {
+			return r.handler
+		}
+	}
+	return nil
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *levelRouter) Enabled(ctx context.Context, level slog.Level) bool {
+	handler := h.route(level)
+	return handler != nil && handler.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *levelRouter) Handle(ctx context.Context, r slog.Record) error {
+	if handler := h.route(r.Level); handler != nil {
+		return handler.Handle(ctx, r)
+	}
+	return nil
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *levelRouter) WithAttrs(attrs []slog.Attr) slog.Handler {
+	routes := make([]levelRoute, len(h.routes))
+	for i, r := range h.routes {
+		routes[i] = levelRoute{level: r.level, handler: r.handler.WithAttrs(attrs)}
+	}
+	return &levelRouter{routes: routes}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *levelRouter) WithGroup(name string) slog.Handler {
+	routes := make([]levelRoute, len(h.routes))
+	for i, r := range h.routes {
+		routes[i] = levelRoute{level: r.level, handler: r.handler.WithGroup(name)}
+	}
+	return &levelRouter{routes: routes}
+}
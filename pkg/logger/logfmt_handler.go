@@ -0,0 +1,236 @@
+package logger
+
+import (
+	"context"
+	"encoding"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// logfmtHandler 输出 logfmt 格式（key=value）的 handler
+//
+// 输出示例：time="2025-01-02 15:04:05" level=info msg=hello user_id=42
+// 分组内的属性以 "group.key" 形式输出；包含空格、引号、等号或控制字符的值会加引号并转义。
+type logfmtHandler struct {
+	opts       *slog.HandlerOptions
+	writer     io.Writer
+	timeFormat string
+	mu         sync.Mutex
+	groups     []string       // 当前 group 路径
+	preAttrs   []byte         // 预先格式化的属性（已包含 group 前缀）
+	location   *time.Location // 缓存的时区
+}
+
+// newLogfmtHandler 创建 logfmt handler
+func newLogfmtHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, timezone string) *logfmtHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	if timeFormat == "" {
+		timeFormat = "datetime"
+	}
+
+	return &logfmtHandler{
+		opts:       opts,
+		writer:     w,
+		timeFormat: timeFormat,
+		location:   loadTimezone(timezone),
+	}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *logfmtHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	minLevel := slog.LevelInfo
+	if h.opts.Level != nil {
+		minLevel = h.opts.Level.Level()
+	}
+	return level >= minLevel
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *logfmtHandler) Handle(ctx context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 256)
+
+	t := r.Time
+	if h.location != nil {
+		t = t.In(h.location)
+	}
+	buf = h.appendBuiltin(buf, slog.TimeKey, formatTimeString(t, h.timeFormat))
+	buf = h.appendBuiltin(buf, slog.LevelKey, strings.ToLower(levelName(r.Level)))
+	buf = h.appendBuiltin(buf, slog.MessageKey, r.Message)
+
+	// 添加源代码位置（如果启用）
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		if f.File != "" {
+			src := &slog.Source{Function: f.Function, File: f.File, Line: f.Line}
+			if a, ok := sourceAttr(h.opts.ReplaceAttr, src, clipWorkspacePath); ok {
+				buf = appendLogfmtPair(buf, a.Key, logfmtValue(a.Value))
+			}
+		}
+	}
+
+	buf = append(buf, h.preAttrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		buf = h.appendAttr(buf, a)
+		return true
+	})
+
+	// 去掉开头的空格
+	if len(buf) > 0 && buf[0] == ' ' {
+		buf = buf[1:]
+	}
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.writer.Write(buf)
+	return err
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *logfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	preAttrs := append([]byte(nil), h.preAttrs...)
+	for _, attr := range attrs {
+		preAttrs = h.appendAttr(preAttrs, attr)
+	}
+
+	return &logfmtHandler{
+		opts:       h.opts,
+		writer:     h.writer,
+		timeFormat: h.timeFormat,
+		groups:     h.groups,
+		preAttrs:   preAttrs,
+		location:   h.location,
+	}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *logfmtHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	return &logfmtHandler{
+		opts:       h.opts,
+		writer:     h.writer,
+		timeFormat: h.timeFormat,
+		groups:     append(h.groups[:len(h.groups):len(h.groups)], name),
+		preAttrs:   h.preAttrs,
+		location:   h.location,
+	}
+}
+
+// appendBuiltin 追加内置字段（time、level、msg、source），同样经过 ReplaceAttr 处理
+//
+// 传给 ReplaceAttr 的是已格式化的字符串，返回空键时丢弃该字段
+func (h *logfmtHandler) appendBuiltin(buf []byte, key, value string) []byte {
+	a := slog.String(key, value)
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		if a.Key == "" {
+			return buf
+		}
+	}
+	return appendLogfmtPair(buf, a.Key, logfmtValue(a.Value.Resolve()))
+}
+
+// appendAttr 对属性应用 ReplaceAttr 后追加到当前 group 路径下
+func (h *logfmtHandler) appendAttr(buf []byte, a slog.Attr) []byte {
+	a, ok := replaceAttr(h.opts.ReplaceAttr, h.groups, a)
+	if !ok {
+		return buf
+	}
+	prefix := ""
+	if len(h.groups) > 0 {
+		prefix = strings.Join(h.groups, ".") + "."
+	}
+	return appendLogfmtAttr(buf, prefix, a)
+}
+
+// appendLogfmtAttr 追加已处理过的属性，分组递归展开为 "group.key"
+func appendLogfmtAttr(buf []byte, prefix string, a slog.Attr) []byte {
+	if a.Value.Kind() == slog.KindGroup {
+		// 空键分组内联到当前层级
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, attr := range a.Value.Group() {
+			buf = appendLogfmtAttr(buf, prefix, attr)
+		}
+		return buf
+	}
+	return appendLogfmtPair(buf, prefix+a.Key, logfmtValue(a.Value))
+}
+
+// appendLogfmtPair 追加 " key=value"，必要时为值加引号
+func appendLogfmtPair(buf []byte, key, value string) []byte {
+	buf = append(buf, ' ')
+	buf = append(buf, logfmtKey(key)...)
+	buf = append(buf, '=')
+	if logfmtNeedsQuote(value) {
+		return strconv.AppendQuote(buf, value)
+	}
+	return append(buf, value...)
+}
+
+// logfmtValue 将 slog.Value 转换为字符串
+func logfmtValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error:
+			return x.Error()
+		case encoding.TextMarshaler:
+			if data, err := x.MarshalText(); err == nil {
+				return string(data)
+			}
+		case []byte:
+			return string(x)
+		}
+		return fmt.Sprintf("%+v", v.Any())
+	default:
+		return v.String()
+	}
+}
+
+// logfmtNeedsQuote 判断值是否需要加引号：空值，或包含空格、等号、引号、控制字符
+func logfmtNeedsQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// logfmtKey 将键中 logfmt 不允许的字符（空格、等号、引号、控制字符）替换为下划线
+func logfmtKey(key string) string {
+	if key != "" && !logfmtNeedsQuote(key) {
+		return key
+	}
+	if key == "" {
+		return "_"
+	}
+	return strings.Map(func(r rune) rune {
+		if r == ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r) {
+			return '_'
+		}
+		return r
+	}, key)
+}
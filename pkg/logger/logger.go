@@ -8,27 +8,193 @@
 package logger
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 // Config 日志配置
 type Config struct {
-	// Level 日志级别: DEBUG, INFO, WARN, ERROR
-	Level string
-	// Format 输出格式: json, text, color
-	Format string
-	// Output 输出目标: stdout, stderr, 或文件路径
-	Output string
+	// Level 日志级别: TRACE, DEBUG, INFO, WARN, ERROR，也接受 NOTICE、CRITICAL、FATAL 等别名和数值（如 -4）
+	Level string `yaml:"level"`
+	// Format 输出格式: json, ndjson (字段顺序固定的 JSON), json-pretty (缩进的 JSON，仅用于本地调试), json-color (终端中着色的缩进 JSON，否则为紧凑 JSON), text, color, logfmt, compact (仅消息和属性，用于命令行工具), gelf (Graylog), ecs (Elastic Common Schema)
+	// 多个输出时可用逗号分隔为每个输出指定格式，例如 "color,json"；只写一个则所有输出共用
+	Format string `yaml:"format"`
+	// Output 输出目标: stdout, stderr, discard / none（丢弃全部日志）, 文件路径，syslog (syslog、syslog:///dev/log、syslog://host:514、syslog+tcp://host:514)，
+	// journald (journald、journald:///path/to/socket，原生协议，仅 Linux)，Windows 事件日志 (eventlog、eventlog://Source)，
+	// http/https 地址（按批 POST JSON 数组），loki://host:3100，
+	// cloudwatch://log-group/log-stream（需导入 pkg/logger/cloudwatch），kafka://broker:9092/topic（需导入 pkg/logger/kafka），fluent://host:24224?tag=app（需导入 pkg/logger/fluent）
+	// 可用逗号分隔同时写入多个目标，例如 "stdout,/var/log/app.json"
+	Output string `yaml:"output"`
+	// ErrorOutput 额外写入 ERROR 及以上记录的输出目标，例如 "/var/log/app/errors.log"，取值与 Output 相同（单个目标），空表示不单独输出
+	// 与主输出相互独立，一个写入失败不影响另一个；设置了 Writer 时同样生效
+	ErrorOutput string `yaml:"error_output"`
+	// ErrorFormat ErrorOutput 使用的格式，空表示使用 Format 中的第一个
+	ErrorFormat string `yaml:"error_format"`
+	// LevelRoutes 按级别将记录写入不同的输出目标，例如 {"DEBUG": "/var/log/app/debug.log", "WARN": "stderr"}：
+	// 每条记录只写入阈值不高于其级别的最高阈值对应的目标，低于所有阈值的记录被丢弃；
+	// 设置后代替 Output（设置了 Writer 时不生效），格式使用 Format 中的第一个，可与 ErrorOutput 同时使用
+	LevelRoutes map[string]string `yaml:"level_routes"`
+	// Writer 非 nil 时作为唯一的输出，忽略 Output，格式使用 Format 中的第一个（不能通过环境变量或配置文件设置）；
+	// Writer 由调用者管理，关闭 logger 时不会被关闭；实现了 Sync() error 时由 Sync、Close 调用，
+	// 实现了 Rotate() error（如 lumberjack.Logger）时在 WatchSignals 收到 SIGHUP 后调用
+	Writer io.Writer `yaml:"-"`
 	// AddSource 是否添加源代码位置信息
-	AddSource bool
-	// TimeFormat 时间格式: datetime (默认), rfc3339, rfc3339ms, unix, unixms
-	TimeFormat string
-	// Timezone 时区名称，例如 "Asia/Shanghai"，默认为 "Asia/Shanghai"
-	Timezone string
+	AddSource bool `yaml:"add_source"`
+	// SortKeys 是否按键名排序属性（每个分组内分别排序），time、level、msg、source 保持在固定位置
+	// 对 text 格式生效；color 格式始终按键名排序
+	SortKeys bool `yaml:"sort_keys"`
+	// AlignColumns 是否对齐列：level 补齐到固定宽度，使 msg 列起始位置一致，仅对 text、color 格式生效
+	AlignColumns bool `yaml:"align_columns"`
+	// SourceMode 源代码位置的显示方式: short (默认，文件名:行号), package (包目录/文件名:行号), full (完整路径)
+	SourceMode string `yaml:"source_mode"`
+	// TimeFormat 时间格式: datetime (默认), time, timems, rfc3339, rfc3339ms, unix, unixms，relative (相对进程启动，如 "+1.234s")，
+	// 或自定义 Go 时间格式（例如 "2006-01-02 15:04:05.000Z07:00"）
+	TimeFormat string `yaml:"time_format"`
+	// DisableTime 是否不输出 time 字段，适用于采集端（systemd、Docker、Kubernetes）已添加时间戳的场景；
+	// 启用后 TimeFormat 和 Timezone 不再生效，gelf 和 ecs 的协议时间字段不受影响
+	DisableTime bool `yaml:"disable_time"`
+	// Timezone 时区，默认为 "Asia/Shanghai"；支持 IANA 名称 (例如 "America/New_York")、
+	// "UTC"、"Local"（系统本地时区）和固定偏移 (例如 "+08:00")
+	Timezone string `yaml:"timezone"`
+	// MaxSizeMB 单个日志文件的最大大小（MB），超过后轮转，0 表示不轮转（仅文件输出有效）
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxBackups 保留的轮转文件数量，超出的最旧文件会被删除，0 表示全部保留
+	MaxBackups int `yaml:"max_backups"`
+	// RotateInterval 按时间轮转: daily (每天零点), hourly (每小时整点)，空表示不按时间轮转
+	RotateInterval string `yaml:"rotate_interval"`
+	// Compress 是否在轮转后将备份文件压缩为 .gz（后台进行，不阻塞写入），仅配置了轮转时有效
+	Compress bool `yaml:"compress"`
+	// FilePerm 新建日志文件的权限（八进制字符串），默认 "0644"
+	FilePerm string `yaml:"file_perm"`
+	// DirPerm 自动创建日志文件所在目录时使用的权限（八进制字符串），默认 "0755"
+	DirPerm string `yaml:"dir_perm"`
+	// MaxFieldLen 字符串和 []byte 属性值（包括消息）的最大字节数，超出部分截断并追加 "…(truncated N bytes)"，0 表示不限制
+	MaxFieldLen int `yaml:"max_field_len"`
+	// MaxLineBytes 格式化后每行（包括换行符）的最大字节数，0 表示不限制：超出时依次截断或丢弃最大的属性，
+	// 保证 JSON 等格式仍然有效，无法再缩减时直接截断整行并追加 "…(truncated N bytes)"
+	MaxLineBytes int `yaml:"max_line_bytes"`
+	// ErrorChain 是否将 error 类型的属性值展开为 {"message": ..., "chain": [...]}，
+	// chain 按 Unwrap 顺序列出每一层错误的信息及 AttrError 携带的属性，便于定位根因
+	ErrorChain bool `yaml:"error_chain"`
+	// MaxAttrs 每条记录的最大属性数（分组按其中的属性计数），超出的属性被丢弃并添加 _dropped_attrs=N，0 表示不限制
+	MaxAttrs int `yaml:"max_attrs"`
+	// RedactKeys 需要脱敏的属性键（大小写不敏感），匹配的值替换为 "***"，对分组内的属性同样生效
+	RedactKeys []string `yaml:"redact_keys"`
+	// RedactPatterns 需要脱敏的值的正则表达式，字符串属性值和消息中的匹配部分替换为 "***"，
+	// 例如 `Bearer [A-Za-z0-9]+`；表达式在初始化时编译，无效时初始化失败
+	RedactPatterns []string `yaml:"redact_patterns"`
+	// DefaultAttrs 附加到每条日志的固定属性，例如 {"service": "api", "version": "1.2.3"}
+	DefaultAttrs map[string]string `yaml:"default_attrs"`
+	// AddHostPID 是否为每条日志添加主机名 (host) 和进程 ID (pid)
+	AddHostPID bool `yaml:"add_host_pid"`
+	// AddGoroutineID 是否为每条日志添加当前 goroutine ID (gid)，用于排查并发问题
+	AddGoroutineID bool `yaml:"add_goroutine_id"`
+	// TraceContext 是否从 context 中提取 OpenTelemetry 的 trace_id 和 span_id
+	// 需要使用 InfoContext 等带 context 的方法记录日志
+	TraceContext bool `yaml:"trace_context"`
+	// Async 是否异步写入：记录先进入缓冲队列，由后台 goroutine 写入输出（对 stdout、stderr 和文件生效）
+	// 启用后应在退出前调用 Sync 或 Close，确保缓冲区中的日志写完
+	Async bool `yaml:"async"`
+	// BufferSize 异步写入时可缓冲的记录数，0 表示默认值 1024；
+	// 网络输出的队列长度同样由它指定，0 表示默认值 10000，队列满时丢弃新记录
+	BufferSize int `yaml:"buffer_size"`
+	// OverflowPolicy 缓冲区满时的处理策略: block（阻塞等待，默认）, drop（丢弃新记录）
+	OverflowPolicy string `yaml:"overflow_policy"`
+	// FlushOnLevel 达到该级别的记录（例如 ERROR）写出后立即刷新输出再返回，空表示不刷新
+	// 启用 Async 或网络输出时保证重要的记录在进程崩溃前已写入，代价是这些记录的写入变慢
+	FlushOnLevel string `yaml:"flush_on_level"`
+	// SampleEvery 采样间隔 N：DEBUG/INFO 级别每 N 条只输出 1 条，WARN 及以上不受影响，0 表示不采样
+	SampleEvery int `yaml:"sample_every"`
+	// SampleFirst 按消息采样：每个计数周期内相同级别和消息的记录只输出前 N 条（所有级别），0 表示不按消息采样
+	// 与 SampleEvery 相比，突发的重复日志仍保留开头的细节，不同的消息互不影响
+	SampleFirst int `yaml:"sample_first"`
+	// SampleThereafter 超过 SampleFirst 后每 M 条输出 1 条，0 表示全部丢弃
+	SampleThereafter int `yaml:"sample_thereafter"`
+	// SampleTick SampleFirst 计数器的重置间隔，0 表示默认值 1s
+	SampleTick time.Duration `yaml:"sample_tick"`
+	// RateLimit 每秒最多输出的记录数，超出的被丢弃，0 表示不限制
+	// 采样或限流丢弃记录后，会定期输出一条 "dropped N messages" 的 WARN 日志；
+	// Sync / Close 时还会输出一条 "log suppression summary: ..." 的 WARN 日志，汇总采样、限流丢弃和去重合并的记录数
+	RateLimit int `yaml:"rate_limit"`
+	// DedupWindow 去重窗口：窗口期内连续重复（级别、消息、属性都相同）的记录合并为一条，
+	// 并带上 count 属性表示重复次数，0 表示不去重；Sync / Close 时输出的 "log suppression summary" 包含合并的记录数
+	DedupWindow time.Duration `yaml:"dedup_window"`
+	// Colors 覆盖 color 格式的默认配色，例如 {"ERROR": "brightred", "msg": "white", "other": "gray"}
+	// 键为大写级别名称或字段名（msg 为消息，other 为其他属性），值为颜色名称、SGR 参数或 ANSI 转义序列
+	Colors map[string]string `yaml:"colors"`
+	// SyslogFacility 输出到 syslog 时使用的 facility: user (默认), daemon, local0 ... local7 等
+	SyslogFacility string `yaml:"syslog_facility"`
+	// BatchSize 网络输出（http/https）每批发送的记录数，0 表示默认值 100
+	BatchSize int `yaml:"batch_size"`
+	// FlushInterval 网络输出的最长发送间隔，0 表示默认值 1s
+	FlushInterval time.Duration `yaml:"flush_interval"`
+	// Headers 网络输出附带的请求头，例如 {"Authorization": "Bearer xxx"}
+	Headers map[string]string `yaml:"headers"`
+	// KafkaKey 输出到 Kafka 时作为分区键的属性名，例如 "tenant_id"，为空时轮询分区
+	KafkaKey string `yaml:"kafka_key"`
+	// AlertWebhook 告警 webhook 地址（例如 Slack Incoming Webhook），达到 AlertMinLevel 的记录会异步 POST 到该地址
+	AlertWebhook string `yaml:"alert_webhook"`
+	// AlertMinLevel 触发告警的最低级别，默认 ERROR
+	AlertMinLevel string `yaml:"alert_min_level"`
+	// AlertThrottle 同一消息的最短告警间隔，默认 1m
+	AlertThrottle time.Duration `yaml:"alert_throttle"`
+	// ModuleLevels 为具名 logger（见 [Named]）单独设置级别，例如 {"db": "DEBUG", "http": "WARN"}
+	// 未列出的模块使用 Level
+	ModuleLevels map[string]string `yaml:"module_levels"`
+	// RepanicAfterLog [Recover] 记录 panic 后是否继续 panic，默认吞掉 panic
+	// 仅对通过 InitCfg / InitEnv 初始化的全局配置生效
+	RepanicAfterLog bool `yaml:"repanic_after_log"`
+	// SlowRequestThreshold HTTP 中间件（见 [Middleware]）的慢请求阈值，耗时超过该值的请求记录为 WARN，0 表示不检测
+	// 仅对通过 InitCfg / InitEnv 初始化的全局配置生效
+	SlowRequestThreshold time.Duration `yaml:"slow_request_threshold"`
+	// RingSize 在内存中保留最近的 N 条记录，通过 [RecentLogs] 读取，0 表示不保留
+	// 仅对通过 InitCfg / InitEnv 初始化的全局配置生效；配合 MaxFieldLen 可限制每条记录的内存占用
+	RingSize int `yaml:"ring_size"`
+	// Metrics 是否按级别统计日志数量（包括采样和限流丢弃的记录，不包括被 Filter 丢弃的记录），通过 [Metrics] 或 [MetricsHandler] 读取
+	Metrics bool `yaml:"metrics"`
+	// Filter 记录过滤函数，返回 false 的记录被丢弃，例如丢弃所有 client=bot 的记录：
+	//	Filter: func(level slog.Level, msg string, attrs map[string]any) bool { return attrs["client"] != "bot" }
+	Filter FilterFunc `yaml:"-"`
+	// ReplaceAttr 自定义属性改写函数，语义与 slog.HandlerOptions.ReplaceAttr 相同，例如将 msg 重命名为 message
+	// 在本包的内置处理（时间和级别格式化、source 改写、脱敏、截断、KeyMap 重命名）之后调用，
+	// 因此接收到的 time、level 已是格式化后的字符串，脱敏后的值不会被还原
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr `yaml:"-"`
+	// KeyMap 重命名顶级字段，用于适配日志平台的字段约定，例如 {"time": "@timestamp", "msg": "message", "level": "severity"}
+	// 对所有格式的内置字段（time、level、msg、source）和顶级属性生效，在内置处理之后、ReplaceAttr 之前应用；
+	// gelf 和 ecs 的协议字段是固定的，不会被重命名
+	KeyMap map[string]string `yaml:"key_map"`
+	// StacktraceLevel 达到该级别的记录添加 stacktrace 属性（例如 ERROR），空表示不添加
+	// 获取堆栈开销较大，默认关闭
+	StacktraceLevel string `yaml:"stacktrace_level"`
+	// TimeFunc 返回当前时间的函数，nil 表示 time.Now（不能通过环境变量或配置文件设置）
+	// 用于记录的时间戳、按时间轮转的周期计算以及采样和限流的计时，测试中可注入固定的时钟
+	TimeFunc func() time.Time `yaml:"-"`
+}
+
+// now 返回 TimeFunc，未设置时返回 time.Now
+func (c *Config) now() func() time.Time {
+	if c.TimeFunc != nil {
+		return c.TimeFunc
+	}
+	return time.Now
+}
+
+// observesRecords 报告是否启用了不依赖输出的功能（告警、指标、最近日志缓冲区），
+// 此时即使所有输出都是 discard，记录也需要经过 handler 链
+func (c *Config) observesRecords() bool {
+	return c.AlertWebhook != "" || c.Metrics || c.RingSize > 0
 }
 
 // defaultConfig 返回默认配置（内部使用）
@@ -45,31 +211,180 @@ func defaultConfig() *Config {
 
 // validFormats 有效的输出格式
 var validFormats = map[string]bool{
-	"json": true, "text": true, "color": true, "colored": true,
-}
-
-// validLevels 有效的日志级别
-var validLevels = map[string]bool{
-	"DEBUG": true, "INFO": true, "WARN": true, "WARNING": true, "ERROR": true,
+	"json": true, "text": true, "color": true, "colored": true, "logfmt": true, "gelf": true, "ecs": true, "ndjson": true, "json-pretty": true, "json-color": true, "compact": true,
 }
 
 // Validate 验证配置是否有效
+//
+// 检查所有字段并返回合并后的错误（errors.Join），一次列出全部问题；配置有效时返回 nil。
+// InitCfg、New 等函数会先调用 Validate，配置无效时返回错误而不会创建 logger
 func (c *Config) Validate() error {
-	if c.Format != "" && !validFormats[c.Format] {
-		return fmt.Errorf("invalid log format: %q, valid options: json, text, color", c.Format)
+	var errs []error
+
+	formats := splitList(c.Format)
+	for _, format := range formats {
+		if format != "" && !validFormats[format] {
+			errs = append(errs, fmt.Errorf("invalid log format: %q, valid options: json, ndjson, json-pretty, json-color, text, color, logfmt, compact, gelf, ecs", format))
+		}
 	}
 
-	level := strings.ToUpper(c.Level)
-	if c.Level != "" && !validLevels[level] {
-		return fmt.Errorf("invalid log level: %q, valid options: DEBUG, INFO, WARN, ERROR", c.Level)
+	// 指定了 Writer 时忽略 Output
+	outputs := splitList(c.Output)
+	if c.Writer != nil {
+		outputs = nil
+	}
+	for _, output := range outputs {
+		if _, ok := lookupOutput(output); strings.Contains(output, "://") && !ok {
+			errs = append(errs, fmt.Errorf("unsupported output: %q, valid options: stdout, stderr, discard, file path, syslog, journald, eventlog, http(s), loki, cloudwatch, kafka, fluent URL", output))
+		}
+	}
+	if _, ok := lookupOutput(c.ErrorOutput); strings.Contains(c.ErrorOutput, "://") && !ok {
+		errs = append(errs, fmt.Errorf("unsupported error output: %q", c.ErrorOutput))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.LevelRoutes)) {
+		target := c.LevelRoutes[name]
+		if _, ok := lookupLevel(name); !ok {
+			errs = append(errs, fmt.Errorf("invalid level route %q: valid levels: %s", name, levelOptions))
+		}
+		if _, ok := lookupOutput(target); target == "" || (strings.Contains(target, "://") && !ok) {
+			errs = append(errs, fmt.Errorf("unsupported level route output for %q: %q", name, target))
+		}
+	}
+	if c.ErrorFormat != "" && !validFormats[c.ErrorFormat] {
+		errs = append(errs, fmt.Errorf("invalid error format: %q", c.ErrorFormat))
+	}
+	if _, err := parsePerm(c.FilePerm, defaultFilePerm); err != nil {
+		errs = append(errs, fmt.Errorf("invalid file perm: %w", err))
+	}
+	if _, err := parsePerm(c.DirPerm, defaultDirPerm); err != nil {
+		errs = append(errs, fmt.Errorf("invalid dir perm: %w", err))
+	}
+	if len(formats) > 1 && c.Writer == nil && len(formats) != len(outputs) {
+		errs = append(errs, fmt.Errorf("format count (%d) does not match output count (%d)", len(formats), len(outputs)))
 	}
 
-	return nil
+	if _, ok := lookupLevel(c.Level); c.Level != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid log level: %q, valid options: %s", c.Level, levelOptions))
+	}
+
+	if err := validateTimeFormat(c.TimeFormat); err != nil {
+		errs = append(errs, err)
+	}
+
+	if _, ok := lookupTimezone(c.Timezone); c.Timezone != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid timezone: %q, use an IANA name (e.g. UTC, Asia/Shanghai), Local, or an offset like +08:00", c.Timezone))
+	}
+
+	if err := validateColors(c.Colors); err != nil {
+		errs = append(errs, err)
+	}
+
+	for from, to := range c.KeyMap {
+		if from == "" || to == "" {
+			errs = append(errs, fmt.Errorf("invalid key map entry: %q=%q, keys must not be empty", from, to))
+		}
+	}
+
+	for _, pattern := range c.RedactPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("invalid redact pattern: %q: %w", pattern, err))
+		}
+	}
+
+	if c.MaxSizeMB < 0 {
+		errs = append(errs, fmt.Errorf("invalid max size: %d, must be >= 0", c.MaxSizeMB))
+	}
+	if c.MaxBackups < 0 {
+		errs = append(errs, fmt.Errorf("invalid max backups: %d, must be >= 0", c.MaxBackups))
+	}
+
+	switch c.RotateInterval {
+	case "", rotateDaily, rotateHourly:
+	default:
+		errs = append(errs, fmt.Errorf("invalid rotate interval: %q, valid options: daily, hourly", c.RotateInterval))
+	}
+
+	if c.MaxFieldLen < 0 {
+		errs = append(errs, fmt.Errorf("invalid max field len: %d, must be >= 0", c.MaxFieldLen))
+	}
+	if c.MaxLineBytes < 0 {
+		errs = append(errs, fmt.Errorf("invalid max line bytes: %d, must be >= 0", c.MaxLineBytes))
+	}
+	if c.MaxAttrs < 0 {
+		errs = append(errs, fmt.Errorf("invalid max attrs: %d, must be >= 0", c.MaxAttrs))
+	}
+	if c.RingSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid ring size: %d, must be >= 0", c.RingSize))
+	}
+	if c.BufferSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid buffer size: %d, must be >= 0", c.BufferSize))
+	}
+	if c.SampleEvery < 0 {
+		errs = append(errs, fmt.Errorf("invalid sample every: %d, must be >= 0", c.SampleEvery))
+	}
+	if c.SampleFirst < 0 {
+		errs = append(errs, fmt.Errorf("invalid sample first: %d, must be >= 0", c.SampleFirst))
+	}
+	if c.SampleThereafter < 0 {
+		errs = append(errs, fmt.Errorf("invalid sample thereafter: %d, must be >= 0", c.SampleThereafter))
+	}
+	if c.SampleTick < 0 {
+		errs = append(errs, fmt.Errorf("invalid sample tick: %s, must be >= 0", c.SampleTick))
+	}
+	if c.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("invalid rate limit: %d, must be >= 0", c.RateLimit))
+	}
+	if c.DedupWindow < 0 {
+		errs = append(errs, fmt.Errorf("invalid dedup window: %s, must be >= 0", c.DedupWindow))
+	}
+	if _, ok := syslogFacilities[c.SyslogFacility]; c.SyslogFacility != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid syslog facility: %q, valid options: kern, user, mail, daemon, auth, syslog, lpr, news, uucp, cron, authpriv, ftp, local0-local7", c.SyslogFacility))
+	}
+	if c.BatchSize < 0 {
+		errs = append(errs, fmt.Errorf("invalid batch size: %d, must be >= 0", c.BatchSize))
+	}
+	if c.FlushInterval < 0 {
+		errs = append(errs, fmt.Errorf("invalid flush interval: %s, must be >= 0", c.FlushInterval))
+	}
+	if _, ok := lookupLevel(c.AlertMinLevel); c.AlertMinLevel != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid alert min level: %q, valid options: %s", c.AlertMinLevel, levelOptions))
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.ModuleLevels)) {
+		if _, ok := lookupLevel(c.ModuleLevels[name]); !ok {
+			errs = append(errs, fmt.Errorf("invalid module level for %q: %q, valid options: %s", name, c.ModuleLevels[name], levelOptions))
+		}
+	}
+	if _, ok := lookupLevel(c.FlushOnLevel); c.FlushOnLevel != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid flush on level: %q, valid options: %s", c.FlushOnLevel, levelOptions))
+	}
+	if _, ok := lookupLevel(c.StacktraceLevel); c.StacktraceLevel != "" && !ok {
+		errs = append(errs, fmt.Errorf("invalid stacktrace level: %q, valid options: %s", c.StacktraceLevel, levelOptions))
+	}
+	if c.SlowRequestThreshold < 0 {
+		errs = append(errs, fmt.Errorf("invalid slow request threshold: %s, must be >= 0", c.SlowRequestThreshold))
+	}
+	if c.AlertThrottle < 0 {
+		errs = append(errs, fmt.Errorf("invalid alert throttle: %s, must be >= 0", c.AlertThrottle))
+	}
+	switch c.SourceMode {
+	case "", sourceModeFull, sourceModeShort, sourceModePackage:
+	default:
+		errs = append(errs, fmt.Errorf("invalid source mode: %q, valid options: full, short, package", c.SourceMode))
+	}
+	switch c.OverflowPolicy {
+	case "", overflowBlock, overflowDrop:
+	default:
+		errs = append(errs, fmt.Errorf("invalid overflow policy: %q, valid options: block, drop", c.OverflowPolicy))
+	}
+
+	return errors.Join(errs...)
 }
 
 // New 创建新的 logger 实例
 //
-// 用于需要独立配置的场景，例如为特定模块创建专用 logger
+// 用于需要独立配置的场景，例如为特定模块创建专用 logger，或在同一进程中使用多个互不影响的 logger。
+// 与 [InitCfg] 使用相同的构建过程，但不修改全局状态：不替换 slog.Default()，级别不受 [SetLevel] 影响，
+// 仅作用于全局 logger 的配置（RingSize、SlowRequestThreshold、RepanicAfterLog）会被忽略。
 // 注意：如果输出到文件，调用者需要使用 NewWithCloser 来获取 closer 并在适当时候关闭
 func New(cfg *Config) (*slog.Logger, error) {
 	logger, _, err := NewWithCloser(cfg)
@@ -79,81 +394,330 @@ func New(cfg *Config) (*slog.Logger, error) {
 // NewWithCloser 创建新的 logger 实例并返回 closer
 //
 // 如果输出到文件，closer 不为 nil，应在不再使用时调用 closer.Close()
-// 如果输出到 stdout/stderr，closer 为 nil
+// 如果输出到 stdout/stderr 且未启用 Async，closer 为 nil
 func NewWithCloser(cfg *Config) (*slog.Logger, io.Closer, error) {
 	if cfg == nil {
 		cfg = defaultConfig()
 	}
+	return newLogger(cfg, parseLevel(cfg.Level))
+}
 
+// newLogger 根据配置和日志级别创建 logger，level 可以是运行时可调整的 *slog.LevelVar
+func newLogger(cfg *Config, level slog.Leveler) (*slog.Logger, io.Closer, error) {
 	// 验证配置
 	if err := cfg.Validate(); err != nil {
 		return nil, nil, err
 	}
 
-	writer, closer, err := getWriter(cfg.Output)
-	if err != nil {
-		return nil, nil, err
+	outputs := splitList(cfg.Output)
+	formats := splitList(cfg.Format)
+	routed := cfg.Writer == nil && len(cfg.LevelRoutes) > 0
+	if cfg.Writer != nil {
+		// 由 getWriter 返回 Writer 作为唯一的输出
+		outputs = []string{""}
+	} else if routed {
+		// 按级别路由时不使用 Output，见 openLevelRoutes
+		outputs = nil
+	}
+	// 所有输出都是 discard 时直接丢弃，不经过任何 handler，记录日志没有额外开销；
+	// 启用了告警、指标或最近日志缓冲区时仍需处理记录，不走这一捷径
+	notDiscard := func(output string) bool { return !isDiscardOutput(output) }
+	if !cfg.observesRecords() && !slices.ContainsFunc(outputs, notDiscard) &&
+		(!routed || !slices.ContainsFunc(slices.Collect(maps.Values(cfg.LevelRoutes)), notDiscard)) &&
+		(cfg.ErrorOutput == "" || isDiscardOutput(cfg.ErrorOutput)) {
+		return slog.New(slog.DiscardHandler), nil, nil
+	}
+
+	// 配置了模块级别时，输出 handler 需放行最低的级别，由 moduleLevelHandler 按模块过滤
+	outputLevel := level
+	if len(cfg.ModuleLevels) > 0 {
+		levels := minLeveler{level}
+		for _, l := range cfg.ModuleLevels {
+			levels = append(levels, parseLevel(l))
+		}
+		outputLevel = levels
 	}
 
-	handler := createHandler(cfg, writer)
-	return slog.New(handler), closer, nil
+	handlers := make([]slog.Handler, 0, len(outputs))
+	closers := make([]io.Closer, 0, len(outputs))
+	for i, output := range outputs {
+		// 格式数量与输出一致时一一对应，否则共用第一个格式
+		format := formats[0]
+		if len(formats) == len(outputs) {
+			format = formats[i]
+		}
+
+		handler, closer, err := openOutput(cfg, output, format, outputLevel)
+		if err != nil {
+			// 关闭已经打开的输出
+			if c := newMultiCloser(closers...); c != nil {
+				_ = c.Close()
+			}
+			return nil, nil, err
+		}
+		handlers = append(handlers, handler)
+		closers = append(closers, closer)
+	}
+	if routed {
+		handler, closer, err := openLevelRoutes(cfg, formats[0], outputLevel)
+		if err != nil {
+			if c := newMultiCloser(closers...); c != nil {
+				_ = c.Close()
+			}
+			return nil, nil, err
+		}
+		handlers = append(handlers, handler)
+		closers = append(closers, closer)
+	}
+	if cfg.ErrorOutput != "" {
+		handler, closer, err := openErrorOutput(cfg, formats[0], outputLevel)
+		if err != nil {
+			if c := newMultiCloser(closers...); c != nil {
+				_ = c.Close()
+			}
+			return nil, nil, err
+		}
+		handlers = append(handlers, handler)
+		closers = append(closers, closer)
+	}
+
+	handler := newMultiHandler(handlers...)
+	// 只刷新输出本身，去重和告警暂存的记录不受影响
+	if outputs := newMultiCloser(closers...); cfg.FlushOnLevel != "" && outputs != nil {
+		handler = &flushHandler{inner: handler, minLevel: parseLevel(cfg.FlushOnLevel), outputs: outputs}
+	}
+	summary := &suppressionSummary{root: handler, now: cfg.now()}
+	if cfg.SampleEvery > 1 || cfg.SampleFirst > 0 || cfg.RateLimit > 0 {
+		sampler := newSamplingHandler(handler, cfg.SampleEvery, cfg.RateLimit)
+		if cfg.TimeFunc != nil {
+			sampler.state.now = cfg.TimeFunc
+		}
+		if cfg.SampleFirst > 0 {
+			sampler.state.sampleFirst(cfg.SampleFirst, cfg.SampleThereafter, cfg.SampleTick)
+		}
+		if cfg.Metrics {
+			sampler.state.metrics = &globalMetrics
+		}
+		handler = sampler
+		summary.sampling = sampler.state
+	}
+	// 去重在采样之前，保证重复次数准确；暂存的记录需在关闭输出前写出
+	if cfg.DedupWindow > 0 {
+		dedup := newDedupHandler(handler, cfg.DedupWindow)
+		handler = dedup
+		summary.dedup = dedup.state
+	}
+	// 汇总在去重写出暂存的记录之后、关闭输出之前输出
+	if summary.sampling != nil || summary.dedup != nil {
+		closers = append([]io.Closer{summary}, closers...)
+	}
+	if summary.dedup != nil {
+		closers = append([]io.Closer{summary.dedup}, closers...)
+	}
+	if cfg.AlertWebhook != "" {
+		minLevel := slog.LevelError
+		if cfg.AlertMinLevel != "" {
+			minLevel = parseLevel(cfg.AlertMinLevel)
+		}
+		alert := newAlertHandler(handler, cfg.AlertWebhook, minLevel, cfg.AlertThrottle, buildReplaceAttr(cfg))
+		handler = alert
+		closers = append([]io.Closer{alert.state}, closers...)
+	}
+	if cfg.TraceContext {
+		handler = &traceHandler{inner: handler}
+	}
+	if cfg.AddGoroutineID {
+		handler = &gidHandler{inner: handler}
+	}
+	// 提取函数可能在初始化之后注册，因此始终包装
+	handler = &contextHandler{inner: handler}
+	if cfg.StacktraceLevel != "" {
+		handler = &stackHandler{inner: handler, minLevel: parseLevel(cfg.StacktraceLevel)}
+	}
+	// 计数在过滤之后，被 Filter 丢弃的记录不计入
+	if cfg.Metrics {
+		handler = &metricsHandler{inner: handler, metrics: &globalMetrics}
+	}
+	// 过滤在 stacktrace 等开销较大的处理之前执行
+	if cfg.Filter != nil {
+		handler = &filterHandler{inner: handler, filter: cfg.Filter}
+	}
+	// 属性上限在最外层检查，trace_id、stacktrace 等由本包添加的属性不受限制
+	if cfg.MaxAttrs > 0 {
+		handler = &maxAttrsHandler{inner: handler, max: cfg.MaxAttrs}
+	}
+	if len(cfg.ModuleLevels) > 0 {
+		handler = newModuleLevelHandler(handler, cfg.ModuleLevels, level)
+	}
+	if cfg.TimeFunc != nil {
+		handler = &clockHandler{inner: handler, now: cfg.TimeFunc}
+	}
+
+	logger := slog.New(handler)
+	if attrs := baseAttrs(cfg); len(attrs) > 0 {
+		logger = logger.With(attrs...)
+	}
+	return logger, newMultiCloser(closers...), nil
+}
+
+// openErrorOutput 打开 ErrorOutput，只输出 ERROR 及以上（且不低于 level）的记录
+//
+// 不使用 Writer，它只作用于主输出
+func openErrorOutput(cfg *Config, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	errCfg := *cfg
+	errCfg.Writer = nil
+	if cfg.ErrorFormat != "" {
+		format = cfg.ErrorFormat
+	}
+	return openOutput(&errCfg, cfg.ErrorOutput, format, maxLeveler{level, slog.LevelError})
+}
+
+// baseAttrs 返回附加到每条日志的基础属性：主机名和进程 ID（如果启用），以及 DefaultAttrs
+//
+// DefaultAttrs 按键排序，保证输出顺序稳定
+func baseAttrs(cfg *Config) []any {
+	keys := make([]string, 0, len(cfg.DefaultAttrs))
+	for key := range cfg.DefaultAttrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	attrs := make([]any, 0, len(keys)+2)
+	if cfg.AddHostPID {
+		attrs = append(attrs, slog.String("host", hostname()), slog.Int("pid", os.Getpid()))
+	}
+	for _, key := range keys {
+		attrs = append(attrs, slog.String(key, cfg.DefaultAttrs[key]))
+	}
+	return attrs
+}
+
+// osHostname 获取主机名，测试中可替换
+var osHostname = os.Hostname
+
+// hostname 返回主机名，只在首次调用时查询一次，失败时返回 "unknown"
+var hostname = sync.OnceValue(lookupHostname)
+
+// lookupHostname 查询主机名
+func lookupHostname() string {
+	name, err := osHostname()
+	if err != nil || name == "" {
+		return "unknown"
+	}
+	return name
 }
 
-// createHandler 根据配置创建 slog.Handler
-func createHandler(cfg *Config, writer io.Writer) slog.Handler {
-	level := parseLevel(cfg.Level)
+// splitList 按逗号拆分配置值并去除空白，空字符串返回包含一个空元素的切片
+func splitList(value string) []string {
+	parts := strings.Split(value, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// createHandler 根据配置创建 slog.Handler，设置了 MaxLineBytes 时包装为 maxLineHandler
+func createHandler(cfg *Config, format string, writer io.Writer, level slog.Leveler) slog.Handler {
+	if cfg.MaxLineBytes > 0 {
+		return newMaxLineHandler(writer, cfg.MaxLineBytes, func(w io.Writer) slog.Handler {
+			return createFormatHandler(cfg, format, w, level)
+		})
+	}
+	return createFormatHandler(cfg, format, writer, level)
+}
+
+// createFormatHandler 创建指定格式的 handler
+func createFormatHandler(cfg *Config, format string, writer io.Writer, level slog.Leveler) slog.Handler {
+	replace := buildReplaceAttr(cfg)
 	opts := &slog.HandlerOptions{
-		Level:     level,
-		AddSource: cfg.AddSource,
+		Level:       level,
+		AddSource:   cfg.AddSource,
+		ReplaceAttr: replace,
 	}
 
-	switch cfg.Format {
+	switch format {
 	case "json":
 		return newJSONHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
+	case "ndjson":
+		return newNDJSONHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
+	case "json-pretty":
+		return newPrettyJSONHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
+	case "json-color":
+		return newColorJSONHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
 	case "color", "colored":
 		colorConfig := &ColoredHandlerConfig{
 			Level:        level,
 			AddSource:    cfg.AddSource,
-			EnableColor:  true,
+			EnableColor:  colorEnabled(writer),
 			CallerClip:   "",
 			PriorityKeys: []string{"time", "level", "msg"},
 			TrailingKeys: []string{"source"},
 			TimeFormat:   cfg.TimeFormat,
 			Timezone:     cfg.Timezone,
+			ReplaceAttr:  replace,
+			Colors:       cfg.Colors,
+			AlignColumns: cfg.AlignColumns,
 		}
 		return NewColoredHandler(writer, colorConfig)
+	case "logfmt":
+		return newLogfmtHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
+	case "compact":
+		return newCompactHandler(writer, opts)
+	case "gelf":
+		return newGELFHandler(writer, opts)
+	case "ecs":
+		return newECSHandler(writer, opts)
 	default: // text
-		return newTextHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
-	}
-}
-
-// parseLevel 解析日志级别字符串（大小写不敏感）
-func parseLevel(levelStr string) slog.Level {
-	switch strings.ToUpper(levelStr) {
-	case "DEBUG":
-		return slog.LevelDebug
-	case "INFO":
-		return slog.LevelInfo
-	case "WARN", "WARNING":
-		return slog.LevelWarn
-	case "ERROR":
-		return slog.LevelError
-	default:
-		return slog.LevelInfo
+		var handler slog.Handler
+		if cfg.AlignColumns {
+			handler = newAlignedTextHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
+		} else {
+			handler = newTextHandler(writer, opts, cfg.TimeFormat, cfg.Timezone)
+		}
+		if cfg.SortKeys {
+			return newSortHandler(handler)
+		}
+		return handler
 	}
 }
 
 // getWriter 获取输出写入器
 // 返回 writer 和 closer（如果是文件则 closer 不为 nil）
-func getWriter(output string) (io.Writer, io.Closer, error) {
+func getWriter(cfg *Config, output string) (io.Writer, io.Closer, error) {
+	// Writer 由调用者管理，不需要关闭，只转发 Sync 和 Rotate
+	if cfg.Writer != nil {
+		return cfg.Writer, newWriterResource(cfg.Writer), nil
+	}
 	switch output {
 	case "stdout", "":
-		return os.Stdout, nil, nil
+		return newPipeWriter(os.Stdout, "stdout"), nil, nil
 	case "stderr":
-		return os.Stderr, nil, nil
+		return newPipeWriter(os.Stderr, "stderr"), nil, nil
 	default:
-		// 文件路径
-		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		// 文件路径，目录不存在时自动创建；权限已由 Validate 检查
+		filePerm, _ := parsePerm(cfg.FilePerm, defaultFilePerm)
+		dirPerm, _ := parsePerm(cfg.DirPerm, defaultDirPerm)
+		if err := os.MkdirAll(filepath.Dir(output), dirPerm); err != nil {
+			return nil, nil, err
+		}
+
+		// 配置了轮转时使用轮转写入器
+		if cfg.MaxSizeMB > 0 || cfg.RotateInterval != "" {
+			w, err := newRotatingWriter(output, rotateOptions{
+				maxSize:    int64(cfg.MaxSizeMB) * 1024 * 1024,
+				maxBackups: cfg.MaxBackups,
+				interval:   cfg.RotateInterval,
+				location:   loadTimezone(cfg.Timezone),
+				compress:   cfg.Compress,
+				perm:       filePerm,
+				now:        cfg.now(),
+			})
+			if err != nil {
+				return nil, nil, err
+			}
+			return w, w, nil
+		}
+		file, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_APPEND, filePerm)
 		if err != nil {
 			return nil, nil, err
 		}
@@ -161,6 +725,65 @@ func getWriter(output string) (io.Writer, io.Closer, error) {
 	}
 }
 
+// writerResource 将 Config.Writer 可选的 Sync 和 Rotate 暴露给 logger 的资源管理，Close 为空操作
+type writerResource struct {
+	w io.Writer
+}
+
+// newWriterResource 为 Writer 创建资源；不支持 Sync 和 Rotate，或者是 stdout/stderr 时返回 nil
+//
+// *os.File 的 Sync 对终端和管道会返回 EINVAL，因此不刷新 stdout/stderr
+func newWriterResource(w io.Writer) io.Closer {
+	if w == os.Stdout || w == os.Stderr {
+		return nil
+	}
+	_, canSync := w.(syncer)
+	_, canRotate := w.(rotator)
+	if !canSync && !canRotate {
+		return nil
+	}
+	return writerResource{w: w}
+}
+
+// Sync 调用 Writer 的 Sync（如果支持）
+func (r writerResource) Sync() error {
+	if s, ok := r.w.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// Rotate 调用 Writer 的 Rotate（如果支持）
+func (r writerResource) Rotate() error {
+	if rot, ok := r.w.(rotator); ok {
+		return rot.Rotate()
+	}
+	return nil
+}
+
+// Close 实现 io.Closer 接口，Writer 由调用者关闭
+func (r writerResource) Close() error {
+	return nil
+}
+
+// 日志文件和目录的默认权限
+const (
+	defaultFilePerm os.FileMode = 0644
+	defaultDirPerm  os.FileMode = 0755
+)
+
+// parsePerm 解析八进制权限字符串（例如 "0640"），空字符串返回 def
+func parsePerm(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	perm, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || perm > 0777 {
+		return 0, fmt.Errorf("%q, must be an octal permission like 0640", s)
+	}
+	return os.FileMode(perm), nil
+}
+
 // WithAttrs 创建带有额外属性的 logger
 //
 // 用于为特定上下文添加固定的日志字段，例如：
@@ -1,14 +1,37 @@
 package logger
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
 	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
 )
 
 func TestInit(t *testing.T) {
@@ -66,6 +89,80 @@ func TestNew(t *testing.T) {
 	assert.NotNil(t, logger)
 }
 
+func TestNewIndependent(t *testing.T) {
+	var global bytes.Buffer
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Writer: &global}))
+	defer Close()
+
+	var jsonBuf, textBuf bytes.Buffer
+	jsonLogger, err := New(&Config{Level: "DEBUG", Format: "json", Writer: &jsonBuf, DefaultAttrs: map[string]string{"service": "a"}})
+	require.NoError(t, err)
+	textLogger, err := New(&Config{Level: "WARN", Format: "logfmt", Writer: &textBuf})
+	require.NoError(t, err)
+
+	jsonLogger.Debug("from json", "n", 1)
+	textLogger.Info("dropped by level")
+	textLogger.Warn("from text", "n", 2)
+	require.NoError(t, SetLevel("ERROR")) // 只影响全局 logger
+
+	jsonLogger.Info("still enabled")
+	assert.Contains(t, jsonBuf.String(), `"msg":"from json"`)
+	assert.Contains(t, jsonBuf.String(), `"service":"a"`)
+	assert.Contains(t, jsonBuf.String(), `"msg":"still enabled"`)
+	assert.NotContains(t, jsonBuf.String(), "from text")
+	assert.Contains(t, textBuf.String(), `msg="from text" n=2`)
+	assert.NotContains(t, textBuf.String(), "dropped by level")
+	assert.Empty(t, global.String(), "New should not touch the global logger")
+	assert.Same(t, globalHandler, slog.Default().Handler())
+}
+
+func TestNewWithOptions(t *testing.T) {
+	dropTime := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	var structBuf, optionBuf bytes.Buffer
+	structLogger, err := New(&Config{
+		Level:        "DEBUG",
+		Format:       "json",
+		Writer:       &structBuf,
+		TimeFormat:   "unix",
+		Timezone:     "Asia/Shanghai",
+		AddSource:    true,
+		DefaultAttrs: map[string]string{"service": "api", "version": "1.2.3"},
+		RedactKeys:   []string{"password"},
+		ReplaceAttr:  dropTime,
+	})
+	require.NoError(t, err)
+	optionLogger, err := NewWithOptions(
+		WithLevel("DEBUG"),
+		WithFormat("json"),
+		WithWriter(&optionBuf),
+		WithTimeFormat("unix"),
+		WithSource(true),
+		WithDefaultAttrs(map[string]string{"service": "api"}),
+		WithDefaultAttrs(map[string]string{"version": "1.2.3"}),
+		WithRedactKeys("password"),
+		WithReplaceAttr(dropTime),
+	)
+	require.NoError(t, err)
+
+	for _, logger := range []*slog.Logger{structLogger, optionLogger} {
+		logger.Debug("hello", "password", "secret", "n", 1)
+	}
+	assert.Equal(t, structBuf.String(), optionBuf.String())
+	assert.Contains(t, optionBuf.String(), `"password":"***"`)
+
+	cfg := NewConfig(WithOutput("stderr"))
+	assert.Equal(t, "stderr", cfg.Output)
+	assert.Equal(t, defaultConfig().Level, cfg.Level, "unset options keep defaults")
+
+	_, err = NewWithOptions(WithFormat("xml"))
+	assert.ErrorContains(t, err, "invalid log format")
+}
+
 func TestParseLevel(t *testing.T) {
 	tests := []struct {
 		input string
@@ -76,7 +173,21 @@ func TestParseLevel(t *testing.T) {
 		{"WARN", slog.LevelWarn},
 		{"WARNING", slog.LevelWarn},
 		{"ERROR", slog.LevelError},
+		{"TRACE", LevelTrace},
+		{"trace", LevelTrace},
 		{"UNKNOWN", slog.LevelInfo}, // default
+		// 别名和数值
+		{"NOTICE", slog.LevelInfo},
+		{"notice", slog.LevelInfo},
+		{"ERR", slog.LevelError},
+		{"CRIT", slog.LevelError},
+		{"CRITICAL", slog.LevelError},
+		{"Fatal", slog.LevelError},
+		{"-4", slog.LevelDebug},
+		{"2", slog.Level(2)},
+		{"-8", LevelTrace},
+		{"INFO+2", slog.Level(2)},
+		{"warn-1", slog.Level(3)},
 		// 小写支持
 		{"debug", slog.LevelDebug},
 		{"info", slog.LevelInfo},
@@ -120,6 +231,19 @@ func TestFromContext(t *testing.T) {
 	ctxWithLogger := WithLogger(ctx, customLogger)
 	retrievedLogger := FromContext(ctxWithLogger)
 	assert.Equal(t, customLogger, retrievedLogger)
+
+	// nil context 返回默认 logger，使用 context 的辅助函数不会 panic
+	var nilCtx context.Context
+	assert.Equal(t, slog.Default(), FromContext(nilCtx))
+	errBoom := errors.New("boom")
+	assert.NotPanics(t, func() {
+		ctx := nilCtx
+		assert.Equal(t, errBoom, LogError(ctx, "failed", errBoom))
+		assert.ErrorIs(t, LogAndWrapCtx(ctx, "failed", errBoom), errBoom)
+		LogIfContextDone(ctx, "done")
+		assert.Equal(t, errBoom, Monitored(ctx, "op", func() error { return errBoom }))
+		assert.Equal(t, errBoom, Measure(ctx, "op", func() error { return errBoom }))
+	})
 }
 
 func TestWithRequestID(t *testing.T) {
@@ -154,6 +278,11 @@ func TestFormatBytes(t *testing.T) {
 		{1024 * 1024, "1.0 MB"},
 		{1536 * 1024, "1.5 MB"},
 		{1024 * 1024 * 1024, "1.0 GB"},
+		{-1, "-1 B"},
+		{-1024, "-1.0 KB"},
+		{-1536 * 1024, "-1.5 MB"},
+		{math.MaxInt64, "8.0 EB"},
+		{math.MinInt64, "-8.0 EB"},
 	}
 
 	for _, tt := range tests {
@@ -164,6 +293,86 @@ func TestFormatBytes(t *testing.T) {
 	}
 }
 
+func TestFormatBytesSI(t *testing.T) {
+	tests := []struct {
+		input  int64
+		si     string
+		binary string
+	}{
+		{0, "0 B", "0 B"},
+		{999, "999 B", "999 B"},
+		{1000, "1.0 kB", "1000 B"},
+		{1024, "1.0 kB", "1.0 KB"},
+		{1500 * 1000, "1.5 MB", "1.4 MB"},
+		{1000 * 1000 * 1000, "1.0 GB", "953.7 MB"},
+		{-1000, "-1.0 kB", "-1000 B"},
+		{math.MinInt64, "-9.2 EB", "-8.0 EB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.si, func(t *testing.T) {
+			assert.Equal(t, tt.si, FormatBytesSI(tt.input))
+			assert.Equal(t, tt.binary, FormatBytes(tt.input))
+		})
+	}
+}
+
+func TestFormatRate(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		d     time.Duration
+		want  string
+	}{
+		{0, time.Second, "0 B/s"},
+		{850, time.Second, "850 B/s"},
+		{12300 * 1000, time.Second, "12.3 MB/s"},
+		{500, 100 * time.Millisecond, "5.0 kB/s"},
+		{1, time.Nanosecond, "1.0 GB/s"},
+		{3 * 1000 * 1000, 2 * time.Second, "1.5 MB/s"},
+		{1000, 0, "∞ B/s"},
+		{1000, -time.Second, "∞ B/s"},
+		{0, 0, "0 B/s"},
+		{math.MaxInt64, time.Nanosecond, "9.2 EB/s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatRate(tt.bytes, tt.d))
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		input time.Duration
+		want  string
+	}{
+		{0, "0ns"},
+		{850 * time.Nanosecond, "850ns"},
+		{time.Microsecond, "1µs"},
+		{12500 * time.Nanosecond, "12.5µs"},
+		{250 * time.Millisecond, "250ms"},
+		{1500 * time.Microsecond, "1.5ms"},
+		{1500 * time.Millisecond, "1.5s"},
+		{59*time.Second + 999*time.Millisecond, "59.9s"},
+		{3*time.Minute + 4*time.Second, "3m04s"},
+		{time.Hour - time.Nanosecond, "59m59s"},
+		{2 * time.Hour, "2h"},
+		{2*time.Hour + 5*time.Minute + 30*time.Second, "2h05m"},
+		{-1500 * time.Millisecond, "-1.5s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, FormatDuration(tt.input))
+		})
+	}
+
+	assert.NotEmpty(t, FormatDuration(math.MinInt64))
+	allocs := testing.AllocsPerRun(100, func() { _ = FormatDuration(3*time.Minute + 4*time.Second) })
+	assert.LessOrEqual(t, allocs, 1.0)
+}
+
 func TestJSONHandlerAddSource(t *testing.T) {
 	var buf bytes.Buffer
 	handler := newJSONHandler(&buf, &slog.HandlerOptions{
@@ -349,6 +558,92 @@ func TestJSONHandlerTimeFormats(t *testing.T) {
 	}
 }
 
+func TestCustomTimeFormat(t *testing.T) {
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_OUTPUT", filepath.Join(t.TempDir(), "app.log"))
+	t.Setenv("LOG_TIME_FORMAT", "2006-01-02 15:04:05.000Z07:00")
+	require.NoError(t, InitEnv())
+	defer Close()
+
+	ts := time.Date(2025, 1, 15, 10, 30, 0, 123e6, time.UTC)
+	for _, format := range []string{"json", "text", "color"} {
+		var buf bytes.Buffer
+		handler := createHandler(&Config{TimeFormat: "2006/01/02 15:04:05.000", Timezone: "UTC"}, format, &buf, slog.LevelInfo)
+		require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(ts, slog.LevelInfo, "test", 0)))
+		assert.Contains(t, buf.String(), "2025/01/15 10:30:00.123", format)
+	}
+
+	t.Setenv("LOG_TIME_FORMAT", "not-a-layout")
+	err := InitEnv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid time format")
+}
+
+func TestRelativeTimeFormat(t *testing.T) {
+	start := processStart
+	processStart = time.Now()
+	defer func() { processStart = start }()
+
+	cfg := &Config{TimeFormat: "relative"}
+	require.NoError(t, cfg.Validate())
+	for _, format := range []string{"json", "text", "color", "logfmt"} {
+		var buf bytes.Buffer
+		handler := createHandler(cfg, format, &buf, slog.LevelInfo)
+		logger := slog.New(handler)
+		logger.Info("first")
+		require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(processStart.Add(1234*time.Millisecond), slog.LevelInfo, "later", 0)))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 2, format)
+		assert.Contains(t, lines[0], "+0.0", format)
+		assert.Contains(t, lines[1], "+1.234s", format)
+	}
+}
+
+func TestDisableTime(t *testing.T) {
+	t.Setenv("LOG_DISABLE_TIME", "true")
+	envCfg := defaultConfig()
+	applyEnv(envCfg)
+	assert.True(t, envCfg.DisableTime)
+
+	cfg := &Config{DisableTime: true, TimeFormat: "unix"}
+	for _, format := range []string{"json", "ndjson", "text", "color", "logfmt"} {
+		var buf bytes.Buffer
+		slog.New(createHandler(cfg, format, &buf, slog.LevelInfo)).Info("hello", "user", 42)
+		assert.NotContains(t, buf.String(), "time", format)
+		assert.Contains(t, buf.String(), "hello", format)
+	}
+
+	var buf bytes.Buffer
+	slog.New(createHandler(cfg, "json", &buf, slog.LevelInfo)).Info("hello", slog.Group("req", "time", "12ms"))
+	assert.Equal(t, `{"level":"INFO","msg":"hello","req":{"time":"12ms"}}`+"\n", buf.String(), "only the top-level time is dropped")
+}
+
+func TestTimezone(t *testing.T) {
+	ts := time.Date(2025, 1, 15, 2, 30, 0, 0, time.UTC)
+	render := func(timezone, timeFormat string) string {
+		var buf bytes.Buffer
+		handler := createHandler(&Config{TimeFormat: timeFormat, Timezone: timezone}, "json", &buf, slog.LevelInfo)
+		require.NoError(t, handler.Handle(context.Background(), slog.NewRecord(ts, slog.LevelInfo, "test", 0)))
+		return buf.String()
+	}
+
+	assert.Contains(t, render("UTC", "rfc3339"), `"time":"2025-01-15T02:30:00Z"`)
+	assert.Contains(t, render("+05:30", "rfc3339"), `"time":"2025-01-15T08:00:00+05:30"`)
+	assert.Contains(t, render("Asia/Tokyo", "datetime"), `"time":"2025-01-15 11:30:00"`)
+	assert.Equal(t, render("UTC", "unix"), render("Asia/Tokyo", "unix"), "unix timestamps are zone-independent")
+
+	for _, tz := range []string{"UTC", "Local", "America/New_York", "+08:00"} {
+		assert.NoError(t, (&Config{Format: "json", Timezone: tz, TimeFormat: "unixms"}).Validate(), tz)
+	}
+
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_TIMEZONE", "Mars/Olympus")
+	err := InitEnv()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid timezone")
+}
+
 func TestNewWithCloser(t *testing.T) {
 	cfg := &Config{
 		Level:  "INFO",
@@ -429,6 +724,192 @@ func TestLogAndWrap(t *testing.T) {
 	assert.Contains(t, output, "fetch failed")
 }
 
+func TestLogErrorNil(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	ctx := WithContext(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)))
+
+	assert.NoError(t, LogError(ctx, "operation failed", nil, "user_id", 1))
+	assert.NoError(t, LogAndWrap("fetch failed", nil))
+	assert.NoError(t, LogAndWrapCtx(ctx, "fetch failed", nil))
+	assert.Empty(t, buf.String())
+}
+
+func TestAttrSet(t *testing.T) {
+	noTime := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			return slog.Attr{}
+		}
+		return a
+	}
+	var got, want bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&got, &slog.HandlerOptions{ReplaceAttr: noTime})))
+	raw := slog.New(slog.NewJSONHandler(&want, &slog.HandlerOptions{ReplaceAttr: noTime}))
+
+	err := errors.New("boom")
+	at := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	InfoAttrs("done", Fields().
+		Str("user", "alice").Int("retry", 3).Int64("size", 1<<40).Uint64("id", 7).
+		Float("ratio", 0.5).Bool("ok", true).Dur("took", 1500*time.Millisecond).
+		Time("at", at).Any("tags", []string{"a"}).Err(err).Err(nil).
+		Attr(slog.Group("req", slog.String("method", "GET"))).
+		Build()...)
+	raw.LogAttrs(context.Background(), slog.LevelInfo, "done",
+		slog.String("user", "alice"), slog.Int("retry", 3), slog.Int64("size", 1<<40), slog.Uint64("id", 7),
+		slog.Float64("ratio", 0.5), slog.Bool("ok", true), slog.Duration("took", 1500*time.Millisecond),
+		slog.Time("at", at), slog.Any("tags", []string{"a"}), slog.Any("error", err),
+		slog.Group("req", slog.String("method", "GET")))
+	assert.Equal(t, want.String(), got.String())
+
+	got.Reset()
+	DebugAttrs("hidden", Fields().Str("k", "v").Build()...)
+	assert.Empty(t, got.String())
+	WarnAttrs("warn", Fields().Str("k", "v").Build()...)
+	ErrorAttrs("error")
+	assert.Contains(t, got.String(), `"level":"WARN","msg":"warn","k":"v"`)
+	assert.Contains(t, got.String(), `"level":"ERROR","msg":"error"`)
+}
+
+func TestMonitored(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	slowErr := errors.New("slow")
+	err := Monitored(ctx, "sync-orders", func() error {
+		<-ctx.Done()
+		time.Sleep(5 * time.Millisecond)
+		return slowErr
+	})
+	assert.ErrorIs(t, err, slowErr)
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), `msg="context done before operation finished" op=sync-orders error="context deadline exceeded" duration=`)
+
+	// fn 在取消之前完成时不记录
+	buf.Reset()
+	ctx, cancel = context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	require.NoError(t, Monitored(ctx, "fast", func() error { return nil }))
+	assert.Empty(t, buf.String())
+
+	// LogIfContextDone 使用 context 中的 logger，并记录取消原因
+	LogIfContextDone(ctx, "not done")
+	assert.Empty(t, buf.String())
+	var ctxBuf bytes.Buffer
+	ctx, cancelCause := context.WithCancelCause(WithContext(context.Background(), slog.New(slog.NewTextHandler(&ctxBuf, nil))))
+	cancelCause(errors.New("shutdown"))
+	LogIfContextDone(ctx, "import interrupted", "imported", 42)
+	assert.Empty(t, buf.String())
+	assert.Contains(t, ctxBuf.String(), `level=WARN msg="import interrupted" error=shutdown imported=42`)
+}
+
+func TestMeasure(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithContext(context.Background(), slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+
+	require.NoError(t, Measure(ctx, "load", func() error { return nil }))
+	failure := NewError("query failed", "table", "orders")
+	err := Measure(ctx, "sync", func() error { return failure })
+	assert.ErrorIs(t, err, failure, "Measure should return the error from fn")
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		records = append(records, m)
+	}
+	require.Len(t, records, 4)
+
+	assert.Equal(t, "DEBUG", records[0]["level"])
+	assert.Equal(t, "operation started", records[0]["msg"])
+	assert.Equal(t, "load", records[0]["op"])
+	assert.NotContains(t, records[0], "duration")
+
+	assert.Equal(t, "INFO", records[1]["level"])
+	assert.Equal(t, "operation finished", records[1]["msg"])
+	assert.NotEmpty(t, records[1]["duration"])
+	assert.NotContains(t, records[1], "error")
+
+	assert.Equal(t, "DEBUG", records[2]["level"])
+	assert.Equal(t, "ERROR", records[3]["level"])
+	assert.Equal(t, "operation failed", records[3]["msg"])
+	assert.Equal(t, "sync", records[3]["op"])
+	assert.NotEmpty(t, records[3]["duration"])
+	assert.Equal(t, "query failed", records[3]["error"])
+	assert.Equal(t, "orders", records[3]["table"])
+}
+
+func TestAttrError(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	base := NewError("row not found", "table", "orders")
+	assert.Equal(t, "row not found", base.Error())
+	err := fmt.Errorf("repository: %w", WrapError(base, "load order", "order_id", 42))
+	assert.Equal(t, "repository: load order: row not found", err.Error())
+	assert.ErrorIs(t, err, base)
+	assert.NoError(t, WrapError(nil, "load order"))
+
+	assert.Equal(t, err, LogError(context.Background(), "request failed", err, "user_id", 7))
+	assert.Contains(t, buf.String(), `msg="request failed" error="repository: load order: row not found" order_id=42 table=orders user_id=7`)
+
+	buf.Reset()
+	_ = LogAndWrap("request failed", WrapError(io.EOF, "", "peer", "10.0.0.1"))
+	assert.Contains(t, buf.String(), `error=EOF peer=10.0.0.1`)
+}
+
+func TestErrorChain(t *testing.T) {
+	root := errors.New("connection refused")
+	err := fmt.Errorf("handle request: %w", WrapError(root, "query failed", "table", "users", "password", "secret"))
+
+	var buf bytes.Buffer
+	cfg := &Config{ErrorChain: true, RedactKeys: []string{"password"}}
+	slog.New(createHandler(cfg, "json", &buf, slog.LevelInfo)).Error("failed", "error", err)
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, map[string]any{
+		"message": "handle request: query failed: connection refused",
+		"chain": []any{
+			map[string]any{"message": "handle request"},
+			map[string]any{"message": "query failed", "table": "users", "password": "***"},
+			map[string]any{"message": "connection refused"},
+		},
+	}, m["error"])
+
+	for _, format := range []string{"text", "color", "logfmt", "ecs"} {
+		buf.Reset()
+		slog.New(createHandler(cfg, format, &buf, slog.LevelInfo)).Error("failed", "error", err)
+		assert.Contains(t, buf.String(), "connection refused", format)
+		assert.Contains(t, buf.String(), "users", format)
+		assert.NotContains(t, buf.String(), "secret", format)
+	}
+
+	// 未启用时保持原有的错误信息字符串
+	buf.Reset()
+	slog.New(createHandler(&Config{}, "json", &buf, slog.LevelInfo)).Error("failed", "error", err)
+	assert.Contains(t, buf.String(), `"error":"handle request: query failed: connection refused"`)
+}
+
+func TestLogAndWrapCtx(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithContext(context.Background(), slog.New(slog.NewTextHandler(&buf, nil)).With("request_id", "req-7"))
+
+	err := LogAndWrapCtx(ctx, "fetch failed", context.DeadlineExceeded, "url", "http://example.com")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "fetch failed: context deadline exceeded", err.Error())
+	assert.Contains(t, buf.String(), "request_id=req-7")
+	assert.Contains(t, buf.String(), "url=http://example.com")
+	assert.Contains(t, buf.String(), `error="context deadline exceeded"`)
+
+	// context 中没有 logger 时使用默认 logger
+	var def bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&def, nil)))
+	_ = LogAndWrapCtx(context.Background(), "fetch failed", context.Canceled, "url", "http://example.com")
+	assert.Contains(t, def.String(), "url=http://example.com")
+}
+
 func TestColoredHandlerNonJSONString(t *testing.T) {
 	var buf bytes.Buffer
 	config := &ColoredHandlerConfig{
@@ -614,7 +1095,7 @@ func TestConfigValidate(t *testing.T) {
 		{
 			name: "invalid level",
 			config: &Config{
-				Level:  "TRACE",
+				Level:  "VERBOSE",
 				Format: "json",
 			},
 			wantErr: true,
@@ -684,7 +1165,7 @@ func TestTextHandlerTimeFormats(t *testing.T) {
 		contains    string
 		notContains string
 	}{
-		{"datetime", "2025-", "T"},
+		{"datetime", time.Now().Format("2006-"), "T"},
 		{"rfc3339", "T", ""},
 	}
 
@@ -767,3 +1248,3350 @@ func TestClipWorkspacePath(t *testing.T) {
 		})
 	}
 }
+
+func TestRotatingWriterSizeRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := newRotatingWriter(path, rotateOptions{maxSize: 100, maxBackups: 2})
+	require.NoError(t, err)
+	defer w.Close()
+
+	line := []byte(strings.Repeat("x", 39) + "\n")
+	for i := 0; i < 20; i++ {
+		_, err := w.Write(line)
+		require.NoError(t, err)
+	}
+
+	backups := w.backups()
+	require.Len(t, backups, 2, "old backups should be pruned to MaxBackups")
+	assert.Equal(t, path+".8", backups[0].path)
+	assert.Equal(t, path+".9", backups[1].path)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, info.Size(), int64(100))
+}
+
+func TestRotatingWriterRenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := newRotatingWriter(path, rotateOptions{maxSize: 100})
+	require.NoError(t, err)
+	defer w.Close()
+
+	// 备份文件名被非空目录占用，重命名失败
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "sub"), 0o755))
+
+	line := []byte(strings.Repeat("x", 39) + "\n")
+	for i := 0; i < 4; i++ {
+		_, err := w.Write(line)
+		require.NoError(t, err)
+	}
+	assert.Error(t, w.rotate())
+
+	// 轮转失败后当前文件重新打开，后续写入仍然落盘
+	_, err = w.Write([]byte("after failure\n"))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 4, strings.Count(string(data), "x\n"))
+	assert.True(t, strings.HasSuffix(string(data), "after failure\n"))
+
+	// 恢复后再次超限时正常轮转
+	require.NoError(t, os.RemoveAll(path+".1"))
+	_, err = w.Write(line)
+	require.NoError(t, err)
+	backup, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.Contains(t, string(backup), "after failure")
+}
+
+func TestRotatingWriterCompress(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w, err := newRotatingWriter(path, rotateOptions{maxSize: 100, maxBackups: 3, compress: true})
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		_, err := fmt.Fprintf(w, "%s%02d\n", strings.Repeat("x", 37), i)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	backups := w.backups()
+	require.Len(t, backups, 3, "compressed backups count towards MaxBackups")
+	for i, b := range backups {
+		assert.True(t, b.compressed)
+		assert.Equal(t, fmt.Sprintf("%s.%d.gz", path, i+7), b.path)
+	}
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 4, "only the current file and compressed backups remain")
+
+	f, err := os.Open(backups[2].path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", 37)+"16\n"+strings.Repeat("x", 37)+"17\n", string(data))
+}
+
+func TestRotatingWriterConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := newRotatingWriter(path, rotateOptions{maxSize: 1000})
+	require.NoError(t, err)
+	defer w.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_, _ = w.Write([]byte("0123456789\n"))
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 所有备份文件加当前文件的总大小应等于写入的总字节数
+	total := int64(0)
+	for _, b := range w.backups() {
+		info, err := os.Stat(b.path)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(1000))
+		total += info.Size()
+	}
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	total += info.Size()
+	assert.Equal(t, int64(8*100*11), total)
+}
+
+func TestNewWithCloserRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewWithCloser(&Config{
+		Level:      "INFO",
+		Format:     "json",
+		Output:     path,
+		MaxSizeMB:  10,
+		MaxBackups: 3,
+	})
+	require.NoError(t, err)
+	require.NotNil(t, closer)
+	_, ok := closer.(*rotatingWriter)
+	assert.True(t, ok, "MaxSizeMB should enable the rotating writer")
+
+	logger.Info("hello")
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"hello"`)
+}
+
+func TestRotatingWriterDailyRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2025, 1, 2, 23, 59, 0, 0, time.UTC)
+	w, err := newRotatingWriter(path, rotateOptions{interval: "daily", location: time.UTC})
+	require.NoError(t, err)
+	defer w.Close()
+	w.now = func() time.Time { return now }
+	w.periodStart = w.periodOf(now)
+
+	_, err = w.Write([]byte("day1\n"))
+	require.NoError(t, err)
+
+	// 跨过零点：旧文件按所属日期归档
+	now = now.Add(2 * time.Minute)
+	_, err = w.Write([]byte("day2\n"))
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(strings.TrimSuffix(path, ".log") + "-2025-01-02.log")
+	require.NoError(t, err)
+	assert.Equal(t, "day1\n", string(data))
+
+	// 连续数天无日志：只归档有内容的那一天，不产生空文件
+	now = now.Add(72 * time.Hour)
+	_, err = w.Write([]byte("day6\n"))
+	require.NoError(t, err)
+
+	backups := w.backups()
+	require.Len(t, backups, 2)
+	assert.Equal(t, "2025-01-02", backups[0].stamp)
+	assert.Equal(t, "2025-01-03", backups[1].stamp)
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "day6\n", string(data))
+}
+
+func TestTimeFunc(t *testing.T) {
+	now := time.Date(2025, 1, 2, 10, 59, 30, 0, time.UTC)
+	var mu sync.Mutex
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	var buf bytes.Buffer
+	logger, err := New(&Config{Format: "json", Writer: &buf, TimeFormat: "rfc3339ms", Timezone: "UTC", TimeFunc: clock})
+	require.NoError(t, err)
+	logger.Info("first")
+	time.Sleep(2 * time.Millisecond)
+	logger.With("k", "v").Info("second")
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Contains(t, line, `"time":"2025-01-02T10:59:30.000Z"`)
+	}
+
+	// 按小时轮转的周期边界由注入的时钟决定
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: path, RotateInterval: "hourly", Timezone: "UTC", TimeFunc: clock})
+	require.NoError(t, err)
+	defer closer.Close()
+	logger.Info("before boundary")
+	advance(20 * time.Second)
+	logger.Info("still 10:59")
+	_, err = os.Stat(filepath.Join(dir, "app-2025-01-02T10.log"))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	advance(20 * time.Second)
+	logger.Info("after boundary")
+	data, err := os.ReadFile(filepath.Join(dir, "app-2025-01-02T10.log"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "after boundary")
+	assert.Contains(t, string(data), `"time":"2025-01-02 11:00:10"`)
+
+	// 最近记录缓冲区同样使用注入的时钟
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: "discard", RingSize: 10, TimeFunc: clock}))
+	defer func() { require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: "discard"})) }()
+	Info("recent")
+	recent := RecentLogs()
+	require.Len(t, recent, 1)
+	assert.True(t, clock().Equal(recent[0].Time), recent[0].Time)
+}
+
+func TestRotatingWriterHourlyWithSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	now := time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)
+	w, err := newRotatingWriter(path, rotateOptions{
+		maxSize:    10,
+		maxBackups: 3,
+		interval:   "hourly",
+		location:   time.UTC,
+	})
+	require.NoError(t, err)
+	defer w.Close()
+	w.now = func() time.Time { return now }
+	w.periodStart = w.periodOf(now)
+
+	// 同一小时内按大小轮转，备份名带周期和序号
+	for i := 0; i < 3; i++ {
+		_, err = w.Write([]byte("12345678\n"))
+		require.NoError(t, err)
+	}
+	now = now.Add(time.Hour)
+	_, err = w.Write([]byte("next\n"))
+	require.NoError(t, err)
+
+	var names []string
+	for _, b := range w.backups() {
+		names = append(names, filepath.Base(b.path))
+	}
+	assert.Equal(t, []string{
+		"app-2025-01-02T10.log",
+		"app-2025-01-02T10.1.log",
+		"app-2025-01-02T10.2.log",
+	}, names)
+}
+
+func TestLogErrorUsesContextLogger(t *testing.T) {
+	var buf bytes.Buffer
+	ctxLogger := slog.New(slog.NewTextHandler(&buf, nil)).With("request_id", "req-42")
+	ctx := WithContext(context.Background(), ctxLogger)
+
+	assert.Same(t, ctxLogger, FromContext(ctx))
+
+	err := LogError(ctx, "operation failed", context.Canceled)
+	assert.Equal(t, context.Canceled, err)
+	assert.Contains(t, buf.String(), "request_id=req-42")
+	assert.Contains(t, buf.String(), "operation failed")
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, os.ErrClosed }
+
+func TestMultiHandlerFanOut(t *testing.T) {
+	var textBuf, jsonBuf bytes.Buffer
+	handler := newMultiHandler(
+		newTextHandler(failingWriter{}, nil, "datetime", ""),
+		newTextHandler(&textBuf, nil, "datetime", ""),
+		newJSONHandler(&jsonBuf, nil, "datetime", ""),
+	)
+	logger := slog.New(handler).With("service", "api")
+
+	err := logger.Handler().Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "hello", 0))
+	assert.Error(t, err, "failing writer error should be reported")
+
+	assert.Contains(t, textBuf.String(), "service=api", "other writers should still receive the record")
+	assert.Contains(t, jsonBuf.String(), `"service":"api"`)
+}
+
+func TestNewWithCloserMultipleOutputs(t *testing.T) {
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "app.log")
+	jsonPath := filepath.Join(dir, "app.json")
+
+	logger, closer, err := NewWithCloser(&Config{
+		Level:  "INFO",
+		Format: "text, json",
+		Output: textPath + ", " + jsonPath,
+	})
+	require.NoError(t, err)
+	logger.Info("tee", "user_id", 42)
+	require.NoError(t, closer.Close())
+
+	text, err := os.ReadFile(textPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(text), "user_id=42")
+
+	data, err := os.ReadFile(jsonPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"user_id":42`)
+}
+
+func TestLevelRouter(t *testing.T) {
+	var debug, warn, errs bytes.Buffer
+	logger := slog.New(NewLevelRouter(map[slog.Level]io.Writer{
+		slog.LevelDebug: &debug,
+		slog.LevelWarn:  &warn,
+		slog.LevelError: &errs,
+	})).With("service", "api")
+	logger.Log(context.Background(), LevelTrace, "below all routes")
+	logger.Debug("debug message")
+	logger.Info("info message")
+	logger.Warn("warn message")
+	logger.Error("error message")
+
+	assert.Equal(t, 2, strings.Count(debug.String(), "\n"))
+	assert.Contains(t, debug.String(), `"msg":"debug message"`)
+	assert.Contains(t, debug.String(), `"msg":"info message"`)
+	assert.Contains(t, debug.String(), `"service":"api"`)
+	assert.Equal(t, 1, strings.Count(warn.String(), "\n"))
+	assert.Contains(t, warn.String(), `"msg":"warn message"`)
+	assert.Equal(t, 1, strings.Count(errs.String(), "\n"))
+	assert.Contains(t, errs.String(), `"msg":"error message"`)
+	assert.NotContains(t, debug.String()+warn.String()+errs.String(), "below all routes")
+	assert.False(t, logger.Enabled(context.Background(), LevelTrace))
+
+	// 通过 Config 配置
+	dir := t.TempDir()
+	debugPath, warnPath := filepath.Join(dir, "debug.log"), filepath.Join(dir, "warn.log")
+	cfgLogger, closer, err := NewWithCloser(&Config{Level: "DEBUG", Format: "json", Output: filepath.Join(dir, "unused.log"),
+		LevelRoutes: map[string]string{"DEBUG": debugPath, "WARN": warnPath}})
+	require.NoError(t, err)
+	cfgLogger.Debug("debug message")
+	cfgLogger.Warn("warn message")
+	cfgLogger.Error("error message")
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(debugPath)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), "debug message")
+	data, err = os.ReadFile(warnPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	assert.NoFileExists(t, filepath.Join(dir, "unused.log"), "LevelRoutes replaces Output")
+
+	err = (&Config{LevelRoutes: map[string]string{"LOUD": "stderr", "WARN": ""}}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid level route "LOUD"`)
+	assert.Contains(t, err.Error(), `unsupported level route output for "WARN"`)
+}
+
+func TestErrorOutput(t *testing.T) {
+	dir := t.TempDir()
+	mainPath := filepath.Join(dir, "app.log")
+	errPath := filepath.Join(dir, "errors.log")
+
+	logger, closer, err := NewWithCloser(&Config{Level: "DEBUG", Format: "json", Output: mainPath, ErrorOutput: errPath})
+	require.NoError(t, err)
+	logger.Info("started")
+	logger.Error("query failed", "table", "orders")
+	logger.Log(context.Background(), slog.LevelError+4, "disk gone")
+	require.NoError(t, closer.Close())
+
+	main, err := os.ReadFile(mainPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(main), `"msg":"started"`)
+	assert.Contains(t, string(main), `"msg":"query failed"`)
+
+	errs, err := os.ReadFile(errPath)
+	require.NoError(t, err)
+	assert.NotContains(t, string(errs), "started")
+	assert.Contains(t, string(errs), `"msg":"query failed","table":"orders"`)
+	assert.Contains(t, string(errs), `"msg":"disk gone"`)
+
+	// ErrorFormat 单独指定格式；主输出写入失败不影响 ErrorOutput
+	errPath = filepath.Join(dir, "errors.txt")
+	logger, closer, err = NewWithCloser(&Config{Format: "json", Writer: failingWriter{}, ErrorOutput: errPath, ErrorFormat: "logfmt"})
+	require.NoError(t, err)
+	logger.Error("query failed")
+	require.NoError(t, closer.Close())
+	errs, err = os.ReadFile(errPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(errs), `msg="query failed"`)
+
+	assert.Error(t, (&Config{Format: "json", ErrorOutput: "nats://x"}).Validate())
+	assert.Error(t, (&Config{Format: "json", ErrorOutput: errPath, ErrorFormat: "xml"}).Validate())
+}
+
+func TestConfigValidateOutputFormatCount(t *testing.T) {
+	// 单一格式应用到所有输出
+	cfg := &Config{Format: "json", Output: "stdout,stderr"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg = &Config{Format: "json,text", Output: "stdout,stderr"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg = &Config{Format: "json,text", Output: "stdout,stderr,/tmp/a.log"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "format count")
+
+	cfg = &Config{Format: "json,yaml", Output: "stdout,stderr"}
+	assert.Error(t, cfg.Validate())
+}
+
+func TestTraceLevel(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(newTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}, "datetime", "")))
+
+	Trace("wire frame", "bytes", 128)
+	assert.Empty(t, buf.String(), "TRACE should be suppressed at INFO level")
+
+	slog.SetDefault(slog.New(newTextHandler(&buf, &slog.HandlerOptions{Level: LevelTrace}, "datetime", "")))
+	Trace("wire frame", "bytes", 128)
+	assert.Contains(t, buf.String(), "level=TRACE")
+	assert.Contains(t, buf.String(), "bytes=128")
+}
+
+func TestFatal(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(newJSONHandler(&buf, nil, "datetime", "")))
+
+	exitCode := -1
+	osExit = func(code int) { exitCode = code }
+	defer func() { osExit = os.Exit }()
+
+	Fatal("cannot start", "reason", "port in use")
+
+	assert.Equal(t, 1, exitCode)
+	assert.Contains(t, buf.String(), `"level":"ERROR"`)
+	assert.Contains(t, buf.String(), `"reason":"port in use"`)
+}
+
+func TestColoredHandlerExtendedLevels(t *testing.T) {
+	var buf bytes.Buffer
+	handler := NewColoredHandler(&buf, &ColoredHandlerConfig{Level: LevelTrace, EnableColor: true})
+	logger := slog.New(handler)
+
+	logger.Log(context.Background(), LevelTrace, "trace")
+	logger.Log(context.Background(), slog.LevelError+4, "above error")
+
+	output := buf.String()
+	assert.Contains(t, output, levelColors[LevelTrace]+"TRACE"+colorReset)
+	assert.Contains(t, output, levelColors[slog.LevelError]+"ERROR+4"+colorReset)
+}
+
+func TestRedactKeys(t *testing.T) {
+	cfg := &Config{RedactKeys: []string{"password", "Token"}}
+
+	for _, format := range []string{"json", "text", "color"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(createHandler(cfg, format, &buf, slog.LevelInfo))
+
+			logger.With("token", "abc").Info("login",
+				"user", "alice",
+				"PASSWORD", "hunter2",
+				slog.Group("auth", "password", "nested-secret"),
+			)
+
+			output := buf.String()
+			t.Logf("Output: %s", output)
+			assert.NotContains(t, output, "hunter2")
+			assert.NotContains(t, output, "nested-secret")
+			assert.NotContains(t, output, "abc")
+			assert.Contains(t, output, "***")
+			assert.Contains(t, output, "alice")
+		})
+	}
+}
+
+func TestJSONHandlerSlogGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newJSONHandler(&buf, nil, "datetime", ""))
+
+	logger.Info("test", slog.Group("request", "method", "GET"), "error", context.Canceled)
+
+	output := buf.String()
+	assert.Contains(t, output, `"request":{"method":"GET"}`)
+	assert.Contains(t, output, `"error":"context canceled"`)
+}
+
+func TestSetLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path}))
+	defer Close()
+
+	assert.Equal(t, "INFO", GetLevel())
+	slog.Debug("before")
+
+	require.NoError(t, SetLevel("debug"))
+	assert.Equal(t, "DEBUG", GetLevel())
+	slog.Debug("after")
+
+	err := SetLevel("LOUD")
+	require.Error(t, err)
+	assert.Equal(t, "DEBUG", GetLevel(), "invalid level should leave level unchanged")
+
+	require.NoError(t, Close())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), `"msg":"before"`)
+	assert.Contains(t, string(data), `"msg":"after"`)
+}
+
+func TestNewHasIndependentLevel(t *testing.T) {
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "text"}))
+	logger, err := New(&Config{Level: "WARN", Format: "text"})
+	require.NoError(t, err)
+
+	require.NoError(t, SetLevel("DEBUG"))
+	assert.False(t, logger.Enabled(context.Background(), slog.LevelInfo), "SetLevel should not affect loggers created by New")
+}
+
+func TestLevelHandler(t *testing.T) {
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "text"}))
+	handler := LevelHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/level", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"level":"INFO"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/level", strings.NewReader(`{"level":"DEBUG"}`)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/level", nil))
+	assert.JSONEq(t, `{"level":"DEBUG"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/level", strings.NewReader(`{"level":"LOUD"}`)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "invalid log level")
+	assert.Equal(t, "DEBUG", GetLevel())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/level", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestDefaultAttrs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	logger, closer, err := NewWithCloser(&Config{
+		Format:       "json",
+		Output:       path,
+		DefaultAttrs: map[string]string{"service": "api", "version": "1.2.3"},
+	})
+	require.NoError(t, err)
+
+	logger.Info("x")
+	logger.Error("y", "foo", "bar")
+	logger.Info("z", "service", "override")
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+	for _, line := range lines[:2] {
+		assert.Contains(t, line, `"service":"api"`)
+		assert.Contains(t, line, `"version":"1.2.3"`)
+	}
+	assert.Contains(t, lines[1], `"foo":"bar"`)
+	assert.Contains(t, lines[2], `"service":"override"`, "explicit attr should take precedence")
+	assert.Contains(t, lines[2], `"version":"1.2.3"`, "other defaults should be kept")
+}
+
+func TestGetEnvMap(t *testing.T) {
+	t.Setenv("LOG_DEFAULT_ATTRS", "service=api, version=1.2.3,broken,=empty")
+	assert.Equal(t, map[string]string{"service": "api", "version": "1.2.3"}, getEnvMap("LOG_DEFAULT_ATTRS"))
+}
+
+func TestAddHostPID(t *testing.T) {
+	lookups := 0
+	osHostname = func() (string, error) {
+		lookups++
+		return "node-1", nil
+	}
+	hostname = sync.OnceValue(lookupHostname)
+	defer func() {
+		osHostname = os.Hostname
+		hostname = sync.OnceValue(lookupHostname)
+	}()
+
+	for _, format := range []string{"json", "text", "color"} {
+		path := filepath.Join(t.TempDir(), "app.log")
+		logger, closer, err := NewWithCloser(&Config{Format: format, Output: path, AddHostPID: true})
+		require.NoError(t, err)
+		for i := 0; i < 10; i++ {
+			logger.Info("hello")
+		}
+		require.NoError(t, closer.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "node-1", format)
+		assert.Contains(t, string(data), strconv.Itoa(os.Getpid()), format)
+	}
+	assert.Equal(t, 1, lookups, "hostname should be resolved only once")
+}
+
+func TestAddGoroutineID(t *testing.T) {
+	gidPattern := regexp.MustCompile(`gid[=":\s\x1b\[0-9;m]*?(\d+)`)
+	for _, format := range []string{"json", "text", "color", "logfmt"} {
+		path := filepath.Join(t.TempDir(), "app.log")
+		logger, closer, err := NewWithCloser(&Config{Format: format, Output: path, AddGoroutineID: true})
+		require.NoError(t, err)
+		logger.Info("main")
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Info("worker")
+		}()
+		wg.Wait()
+		require.NoError(t, closer.Close())
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+		require.Len(t, lines, 2, format)
+		main, worker := gidPattern.FindStringSubmatch(lines[0]), gidPattern.FindStringSubmatch(lines[1])
+		require.NotNil(t, main, "%s: %s", format, lines[0])
+		require.NotNil(t, worker, "%s: %s", format, lines[1])
+		assert.NotEqual(t, main[1], worker[1], format)
+		assert.NotEqual(t, "0", main[1], format)
+	}
+	assert.Zero(t, testing.AllocsPerRun(100, func() { goroutineID() }))
+}
+
+// countingStringer 记录 String 被调用的次数，用于确认消息是否被格式化
+type countingStringer struct{ calls *int }
+
+func (s countingStringer) String() string {
+	*s.calls++
+	return "formatted"
+}
+
+func TestPrintfHelpers(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Writer: &buf}))
+	defer Close()
+
+	calls := 0
+	arg := countingStringer{calls: &calls}
+	Debugf("debug %s", arg)
+	assert.Zero(t, calls, "disabled levels should not format the message")
+	assert.Empty(t, buf.String())
+
+	Infof("processed %d items by %s", 3, arg)
+	Warnf("warn %s", "w")
+	Errorf("error %v", errors.New("boom"))
+	assert.Equal(t, 1, calls)
+	assert.Contains(t, buf.String(), `"level":"INFO","msg":"processed 3 items by formatted"`)
+	assert.Contains(t, buf.String(), `"level":"WARN","msg":"warn w"`)
+	assert.Contains(t, buf.String(), `"level":"ERROR","msg":"error boom"`)
+
+	assert.Zero(t, testing.AllocsPerRun(100, func() { Debugf("debug %d", 42) }))
+}
+
+func TestHostnameFallback(t *testing.T) {
+	osHostname = func() (string, error) { return "", os.ErrNotExist }
+	defer func() { osHostname = os.Hostname }()
+
+	assert.Equal(t, "unknown", lookupHostname())
+}
+
+func TestTraceContext(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &Config{TraceContext: true}
+	handler := &traceHandler{inner: createHandler(cfg, "json", &buf, slog.LevelInfo)}
+	slog.SetDefault(slog.New(handler))
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+
+	InfoContext(ctx, "with span")
+	assert.Contains(t, buf.String(), `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`)
+	assert.Contains(t, buf.String(), `"span_id":"00f067aa0ba902b7"`)
+
+	buf.Reset()
+	InfoContext(context.Background(), "without span")
+	assert.NotContains(t, buf.String(), "trace_id")
+}
+
+func TestSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, MaxSizeMB: 1}))
+	defer Close()
+
+	for i := 0; i < 100; i++ {
+		slog.Info("message", "i", i)
+	}
+	require.NoError(t, Sync())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 100, strings.Count(string(data), "\n"))
+
+	require.NoError(t, Close())
+	assert.NoError(t, Sync(), "Sync after Close should be a no-op")
+
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "text", Output: "stdout"}))
+	assert.NoError(t, Sync(), "Sync on stdout should be a no-op")
+}
+
+func TestAsyncWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: path, Async: true, BufferSize: 16})
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 250; i++ {
+				logger.Info("message", "i", i)
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.NoError(t, closer.(interface{ Sync() error }).Sync())
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1000, strings.Count(string(data), "\n"), "Sync should drain the buffer")
+
+	logger.Info("last")
+	require.NoError(t, closer.Close())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"last"`, "Close should drain the buffer")
+}
+
+func TestAsyncWriterDrop(t *testing.T) {
+	block := make(chan struct{})
+	out := &blockingWriter{block: block}
+	w := newAsyncWriter(out, nil, 2, overflowDrop)
+
+	for i := 0; i < 10; i++ {
+		n, err := w.Write([]byte("x\n"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+	}
+	close(block)
+	require.NoError(t, w.Close())
+
+	// 后台 goroutine 最多持有 1 条，队列最多缓冲 2 条
+	assert.LessOrEqual(t, out.writes, 3)
+	_, err := w.Write([]byte("x\n"))
+	assert.ErrorIs(t, err, os.ErrClosed)
+}
+
+func TestFlushOnLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: path, Async: true, BufferSize: 100000, FlushOnLevel: "ERROR"})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	for i := 0; i < 1000; i++ {
+		logger.Info("message", "i", i)
+	}
+	logger.Error("disk full")
+
+	// 未调用 Sync，ERROR 及其之前的记录已写入文件
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1001, strings.Count(string(data), "\n"))
+	assert.Contains(t, string(data), `"msg":"disk full"`)
+
+	assert.Error(t, (&Config{Format: "json", FlushOnLevel: "LOUD"}).Validate())
+}
+
+func TestAsyncConfigValidate(t *testing.T) {
+	assert.Error(t, (&Config{Format: "json", OverflowPolicy: "wait"}).Validate())
+	assert.Error(t, (&Config{Format: "json", BufferSize: -1}).Validate())
+	assert.NoError(t, (&Config{Format: "json", Async: true, OverflowPolicy: "drop"}).Validate())
+}
+
+func TestRecover(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, Async: true}))
+	defer Close()
+
+	assert.NotPanics(t, func() {
+		defer Recover()
+		slog.Info("before panic")
+		panic("boom")
+	})
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"before panic"`)
+	assert.Contains(t, string(data), `"error":"boom"`)
+	assert.Contains(t, string(data), `"level":"ERROR"`)
+	assert.Contains(t, string(data), `"stack":"goroutine `)
+
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, Async: true, RepanicAfterLog: true}))
+	assert.PanicsWithValue(t, "again", func() {
+		defer Recover()
+		panic("again")
+	})
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"error":"again"`)
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path}))
+	defer Close()
+
+	handler := RecoverMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("handler exploded")
+	}))
+	rec := httptest.NewRecorder()
+	assert.NotPanics(t, func() {
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/explode", nil))
+	})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "ERROR", m["level"])
+	assert.Equal(t, "handler exploded", m["error"])
+	assert.Equal(t, "/explode", m["path"])
+	assert.Contains(t, m["stack"], "TestRecoverMiddleware")
+}
+
+// blockingWriter 在 block 关闭前阻塞写入，用于模拟慢速输出
+type blockingWriter struct {
+	block  chan struct{}
+	writes int
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.block
+	w.writes++
+	return len(p), nil
+}
+
+func benchmarkFileLogger(b *testing.B, async bool) {
+	path := filepath.Join(b.TempDir(), "bench.log")
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: path, Async: async, BufferSize: 4096})
+	require.NoError(b, err)
+	defer closer.Close()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("benchmark message", "key", "value", "n", 42)
+		}
+	})
+}
+
+func BenchmarkSyncWrite(b *testing.B) {
+	benchmarkFileLogger(b, false)
+}
+
+func BenchmarkAsyncWrite(b *testing.B) {
+	benchmarkFileLogger(b, true)
+}
+
+func TestEnabled(t *testing.T) {
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: filepath.Join(t.TempDir(), "app.log")}))
+	defer Close()
+
+	assert.False(t, Enabled(slog.LevelDebug))
+	assert.True(t, Enabled(slog.LevelInfo))
+	assert.True(t, Enabled(slog.LevelError))
+
+	// 未启用的级别不分配内存
+	allocs := testing.AllocsPerRun(100, func() {
+		Debug("disabled")
+		Trace("disabled")
+	})
+	assert.Zero(t, allocs)
+}
+
+func benchmarkDisabled(b *testing.B, f func()) {
+	require.NoError(b, InitCfg(&Config{Level: "INFO", Format: "json", Output: filepath.Join(b.TempDir(), "bench.log")}))
+	defer Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for b.Loop() {
+		f()
+	}
+}
+
+func BenchmarkDebugDisabled(b *testing.B) {
+	benchmarkDisabled(b, func() { Debug("disabled") })
+}
+
+func BenchmarkDebugDisabledAttrs(b *testing.B) {
+	benchmarkDisabled(b, func() { Debug("disabled", "key", "value", "n", 42) })
+}
+
+func BenchmarkDebugDisabledGuard(b *testing.B) {
+	benchmarkDisabled(b, func() {
+		if Enabled(slog.LevelDebug) {
+			Debug("disabled", "key", "value", "n", 42)
+		}
+	})
+}
+
+func TestDiscardOutput(t *testing.T) {
+	for _, output := range []string{"discard", "none"} {
+		require.NoError(t, InitCfg(&Config{Level: "DEBUG", Format: "json", Output: output, AddSource: true, DefaultAttrs: map[string]string{"service": "api"}}))
+		assert.False(t, Enabled(slog.LevelError), output)
+		allocs := testing.AllocsPerRun(100, func() {
+			Info("discarded", "key", "value", "n", 42)
+		})
+		assert.Zero(t, allocs, output)
+	}
+	require.NoError(t, Close())
+
+	// 与其他输出一起使用时只是不写入
+	var buf bytes.Buffer
+	logger, err := New(&Config{Format: "json", Output: "discard,stdout"})
+	require.NoError(t, err)
+	assert.True(t, logger.Enabled(context.Background(), slog.LevelInfo))
+	logger, err = New(&Config{Format: "json", Output: "discard", Writer: &buf})
+	require.NoError(t, err)
+	logger.Info("writer wins")
+	assert.Contains(t, buf.String(), "writer wins")
+
+	// 告警、指标和最近日志缓冲区不依赖输出，discard 时仍然生效
+	alerts := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload alertPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		alerts <- payload.Text
+	}))
+	defer server.Close()
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: "discard", AlertWebhook: server.URL, Metrics: true, RingSize: 10}))
+	before := Metrics()
+	Debug("below level")
+	Error("disk full")
+	recent := RecentLogs()
+	require.Len(t, recent, 1)
+	assert.Equal(t, "disk full", recent[0].Message)
+	require.NoError(t, Close())
+	assert.Equal(t, "[ERROR] disk full", <-alerts)
+	assert.Equal(t, uint64(1), Metrics().Messages["ERROR"]-before.Messages["ERROR"])
+	assert.Zero(t, Metrics().Messages["DEBUG"]-before.Messages["DEBUG"])
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: "discard"}))
+}
+
+func BenchmarkDebugfDisabled(b *testing.B) {
+	require.NoError(b, InitCfg(&Config{Level: "INFO", Format: "json", Writer: io.Discard}))
+	defer Close()
+
+	calls := 0
+	arg := countingStringer{calls: &calls}
+	b.ReportAllocs()
+	for b.Loop() {
+		Debugf("processed %d items by %s", 42, arg)
+	}
+	if calls > 0 {
+		b.Fatalf("Debugf formatted the message %d times at INFO level", calls)
+	}
+}
+
+func BenchmarkInfoDiscard(b *testing.B) {
+	require.NoError(b, InitCfg(&Config{Level: "DEBUG", Format: "json", Output: "discard"}))
+	defer Close()
+
+	b.ReportAllocs()
+	for b.Loop() {
+		Info("discarded", "key", "value", "n", 42)
+	}
+}
+
+func TestSampleEvery(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(newSamplingHandler(newJSONHandler(&buf, nil, "datetime", ""), 100, 0))
+
+	for i := 0; i < 10000; i++ {
+		logger.Info("flood", "i", i)
+	}
+	logger.Error("important")
+
+	assert.Equal(t, 100, strings.Count(buf.String(), `"msg":"flood"`))
+	assert.Contains(t, buf.String(), `"msg":"important"`, "errors should never be sampled")
+}
+
+func TestSuppressionSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, InitCfg(&Config{Level: "DEBUG", Format: "json", Output: path, SampleEvery: 4, RateLimit: 10, DedupWindow: time.Hour}))
+	defer Close()
+
+	// 40 条 DEBUG 采样后保留 10 条，全部在限额内；之后的 5 条 WARN 超出限额
+	for i := 0; i < 40; i++ {
+		Debug("sampled", "i", i)
+	}
+	for i := 0; i < 5; i++ {
+		Warn("limited", "i", i)
+	}
+	// 去重在采样之前：3 条重复记录合并为 1 条，该条再被限流丢弃
+	for i := 0; i < 3; i++ {
+		Error("repeated")
+	}
+	require.NoError(t, Sync())
+
+	summary := func() string {
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var found []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if strings.Contains(line, "log suppression summary") {
+				found = append(found, line)
+			}
+		}
+		require.Len(t, found, 1)
+		return found[0]
+	}
+	line := summary()
+	assert.Contains(t, line, `"msg":"log suppression summary: dropped=30 by sampling, 6 by rate limit, merged=2 by dedup"`)
+	assert.Contains(t, line, `"sampled":30`)
+	assert.Contains(t, line, `"rate_limited":6`)
+	assert.Contains(t, line, `"deduplicated":2`)
+
+	// 计数已清零，没有新的丢弃时 Close 不再输出
+	require.NoError(t, Close())
+	assert.Equal(t, line, summary())
+}
+
+func TestSampleFirst(t *testing.T) {
+	var buf bytes.Buffer
+	handler := newSamplingHandler(newJSONHandler(&buf, nil, "datetime", ""), 0, 0)
+	handler.state.sampleFirst(100, 1000, time.Second)
+	now := time.Now()
+	handler.state.now = func() time.Time { return now }
+	logger := slog.New(handler)
+
+	for i := 0; i < 5000; i++ {
+		logger.Info("flood", "i", i)
+	}
+	logger.Info("other")
+	logger.Error("flood")
+
+	out := buf.String()
+	// 前 100 条，之后第 101、1101、2101、3101、4101 条
+	assert.Equal(t, 106, strings.Count(out, `"msg":"flood"`))
+	assert.Contains(t, out, `"i":99,`)
+	assert.Contains(t, out, `"i":100,`)
+	assert.NotContains(t, out, `"i":101,`)
+	assert.Contains(t, out, `"i":4100,`)
+	assert.Contains(t, out, `"msg":"other"`, "different messages are counted separately")
+	assert.Contains(t, out, `"level":"ERROR"`, "different levels are counted separately")
+
+	// 下一个周期重新计数
+	buf.Reset()
+	now = now.Add(time.Second)
+	for i := 0; i < 150; i++ {
+		logger.Info("flood")
+	}
+	assert.Equal(t, 101, strings.Count(buf.String(), `"msg":"flood"`))
+
+	// SampleThereafter 为 0 时超出部分全部丢弃
+	buf.Reset()
+	logger, err := New(&Config{Format: "json", Writer: &buf, SampleFirst: 3})
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		logger.Warn("retry")
+	}
+	assert.Equal(t, 3, strings.Count(buf.String(), `"msg":"retry"`))
+}
+
+func TestRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	handler := newSamplingHandler(newJSONHandler(&buf, nil, "datetime", ""), 0, 5)
+	now := time.Now()
+	handler.state.now = func() time.Time { return now }
+	logger := slog.New(handler).With("component", "worker")
+
+	for i := 0; i < 20; i++ {
+		logger.Error("spam")
+	}
+	assert.Equal(t, 5, strings.Count(buf.String(), `"msg":"spam"`))
+	assert.NotContains(t, buf.String(), "dropped")
+
+	// 下一个窗口重新计数，且超过统计间隔后输出丢弃统计
+	now = now.Add(samplingSummaryInterval)
+	logger.Error("spam")
+	assert.Equal(t, 6, strings.Count(buf.String(), `"msg":"spam"`))
+	assert.Contains(t, buf.String(), `"msg":"dropped 15 messages"`)
+	assert.Contains(t, buf.String(), `"dropped":15`)
+}
+
+func TestDedupWindow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: path, DedupWindow: time.Minute})
+	require.NoError(t, err)
+
+	for i := 0; i < 50; i++ {
+		logger.Warn("upstream unavailable", "host", "db")
+	}
+	logger.Warn("upstream unavailable", "host", "cache")
+	logger.Warn("upstream unavailable", "host", "cache")
+	logger.Info("recovered")
+	require.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+	assert.Contains(t, lines[0], `"host":"db"`)
+	assert.Contains(t, lines[0], `"count":50`)
+	assert.Contains(t, lines[1], `"host":"cache"`, "differing attrs should be distinct")
+	assert.Contains(t, lines[1], `"count":2`)
+	assert.Contains(t, lines[2], `"msg":"recovered"`)
+	assert.NotContains(t, lines[2], `"count"`)
+	// 只启用去重时 Close 同样输出汇总，只包含合并的记录数
+	assert.Contains(t, lines[3], `"msg":"log suppression summary: merged=50 by dedup"`)
+	assert.Contains(t, lines[3], `"deduplicated":50`)
+	assert.NotContains(t, lines[3], `"sampled"`)
+}
+
+func TestDedupWindowElapsed(t *testing.T) {
+	var buf syncBuffer
+	logger := slog.New(newDedupHandler(newJSONHandler(&buf, nil, "datetime", ""), 20*time.Millisecond))
+
+	logger.Warn("retry")
+	logger.Warn("retry")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(buf.String(), `"count":2`)
+	}, time.Second, 5*time.Millisecond, "pending record should be flushed when the window elapses")
+}
+
+// syncBuffer 并发安全的 bytes.Buffer
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestColorDetection(t *testing.T) {
+	render := func() string {
+		path := filepath.Join(t.TempDir(), "app.log")
+		logger, closer, err := NewWithCloser(&Config{Format: "color", Output: path})
+		require.NoError(t, err)
+		logger.Error("piped")
+		require.NoError(t, closer.Close())
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	output := render()
+	assert.Contains(t, output, "piped")
+	assert.NotContains(t, output, "\033[", "files are not terminals")
+
+	t.Setenv("FORCE_COLOR", "1")
+	assert.Contains(t, render(), "\033[")
+
+	t.Setenv("NO_COLOR", "1")
+	assert.NotContains(t, render(), "\033[", "NO_COLOR takes precedence")
+}
+
+func TestColorTheme(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "1")
+
+	var buf bytes.Buffer
+	handler := createHandler(&Config{Colors: map[string]string{
+		"ERROR": "brightmagenta",
+		"msg":   "1;37",
+		"other": "\033[33m",
+	}}, "color", &buf, slog.LevelInfo)
+	logger := slog.New(handler).With("component", "db")
+
+	logger.Error("failed", "attempt", 3)
+	logger.Info("ok")
+
+	output := buf.String()
+	assert.Contains(t, output, "\033[95mERROR"+colorReset)
+	assert.Contains(t, output, levelColors[slog.LevelInfo]+"INFO"+colorReset, "unspecified levels keep defaults")
+	assert.Contains(t, output, "\033[1;37mfailed"+colorReset)
+	assert.Contains(t, output, "\033[33mdb"+colorReset)
+	assert.Contains(t, output, "\033[33m3"+colorReset)
+
+	assert.Error(t, (&Config{Format: "color", Colors: map[string]string{"ERROR": "reddish"}}).Validate())
+}
+
+func TestLogfmtHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := createHandler(&Config{TimeFormat: "rfc3339", Timezone: "UTC"}, "logfmt", &buf, slog.LevelInfo)
+	logger := slog.New(handler).With("service", "api").WithGroup("req")
+
+	logger.Info("hello world",
+		"user_id", 42,
+		"path", "/a b",
+		"quote", `say "hi"`,
+		"empty", "",
+		"multi\nline key", "x",
+		slog.Group("headers", "accept", "text/html"),
+		"error", errors.New("boom"),
+	)
+
+	line := strings.TrimSpace(buf.String())
+	assert.Regexp(t, `^time=\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z level=info msg="hello world" `, line)
+	assert.Contains(t, line, ` service=api `)
+	assert.Contains(t, line, ` req.user_id=42 `)
+	assert.Contains(t, line, ` req.path="/a b" `)
+	assert.Contains(t, line, ` req.quote="say \"hi\"" `)
+	assert.Contains(t, line, ` req.empty="" `)
+	assert.Contains(t, line, ` req.multi_line_key=x `)
+	assert.Contains(t, line, ` req.headers.accept=text/html `)
+	assert.True(t, strings.HasSuffix(line, ` req.error=boom`))
+	assert.NoError(t, (&Config{Format: "logfmt"}).Validate())
+}
+
+func TestCompactHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(createHandler(&Config{TimeFormat: "rfc3339"}, "compact", &buf, slog.LevelInfo))
+
+	logger.Info("hello", "user", 42)
+	assert.Equal(t, "hello user=42\n", buf.String())
+
+	buf.Reset()
+	logger.With("cmd", "deploy").WithGroup("req").Warn("slow", "path", "/a b")
+	logger.Error("failed", "error", errors.New("boom"))
+	logger.Info("", "n", 1)
+	logger.Debug("hidden")
+	assert.Equal(t, "warning: slow cmd=deploy req.path=\"/a b\"\nerror: failed error=boom\nn=1\n", buf.String())
+	assert.NoError(t, (&Config{Format: "compact"}).Validate())
+}
+
+func TestGELFHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := createHandler(&Config{}, "gelf", &buf, slog.LevelInfo)
+	logger := slog.New(handler).With("service", "api").WithGroup("req")
+
+	ts := time.Date(2025, 1, 15, 10, 30, 0, 123e6, time.UTC)
+	r := slog.NewRecord(ts, slog.LevelWarn, "slow request", 0)
+	r.AddAttrs(slog.Int("status", 200), slog.String("id", "abc"), slog.Group("user", "name", "bob"))
+	require.NoError(t, logger.Handler().Handle(context.Background(), r))
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, "1.1", m["version"])
+	assert.Equal(t, hostname(), m["host"])
+	assert.Equal(t, "slow request", m["short_message"])
+	assert.Equal(t, 1736937000.123, m["timestamp"])
+	assert.Equal(t, float64(4), m["level"])
+	assert.Equal(t, "api", m["_service"])
+	assert.Equal(t, float64(200), m["_req.status"])
+	assert.Equal(t, "abc", m["_req.id"])
+	assert.Equal(t, "bob", m["_req.user.name"])
+
+	buf.Reset()
+	slog.New(handler).Info("x", "id", 1, "bad key!", true)
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, float64(1), m["_id_"], "_id is reserved by GELF")
+	assert.Equal(t, true, m["_bad_key_"])
+
+	// 消息同样经过 ReplaceAttr 脱敏
+	buf.Reset()
+	slog.New(createHandler(&Config{RedactPatterns: []string{`Bearer [A-Za-z0-9]+`}}, "gelf", &buf, slog.LevelInfo)).Info("token Bearer abc123")
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, "token ***", m["short_message"])
+}
+
+func TestECSHandler(t *testing.T) {
+	var buf bytes.Buffer
+	handler := createHandler(&Config{AddSource: true}, "ecs", &buf, slog.LevelInfo)
+	handler = &stackHandler{inner: handler, minLevel: slog.LevelError}
+	logger := slog.New(handler).With("service", "api")
+
+	ts := time.Date(2025, 1, 15, 18, 30, 0, 123e6, time.FixedZone("CST", 8*3600))
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	r := slog.NewRecord(ts, slog.LevelError, "query failed", pcs[0])
+	r.AddAttrs(slog.Any("error", errors.New("connection refused")), slog.Group("db", "table", "users"))
+	require.NoError(t, logger.Handler().Handle(context.Background(), r))
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, "2025-01-15T10:30:00.123Z", m["@timestamp"])
+	assert.Equal(t, "query failed", m["message"])
+	assert.Equal(t, map[string]any{"version": ecsVersion}, m["ecs"])
+	assert.Equal(t, "api", m["service"])
+	assert.Equal(t, map[string]any{"table": "users"}, m["db"])
+
+	log, _ := m["log"].(map[string]any)
+	assert.Equal(t, "error", log["level"])
+	origin, _ := log["origin"].(map[string]any)
+	assert.Contains(t, origin["function"], "TestECSHandler")
+	file, _ := origin["file"].(map[string]any)
+	assert.Contains(t, file["name"], "logger_test.go")
+	assert.NotZero(t, file["line"])
+
+	// error 属性和 stacktrace 展开为 ECS 的 error 对象
+	errObj, _ := m["error"].(map[string]any)
+	assert.Equal(t, "connection refused", errObj["message"])
+	assert.Equal(t, "*errors.errorString", errObj["type"])
+	assert.Contains(t, errObj["stack_trace"], "TestECSHandler")
+	assert.NotContains(t, m, "stacktrace")
+
+	// 分组内的 error 不是 ECS 字段，With 添加的 error 同样展开
+	buf.Reset()
+	slog.New(handler).With("error", "timeout").WithGroup("retry").Info("retrying", "error", "eof")
+	m = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, map[string]any{"message": "timeout"}, m["error"])
+	assert.Equal(t, map[string]any{"error": "eof"}, m["retry"])
+	assert.Equal(t, "info", m["log"].(map[string]any)["level"])
+
+	// 消息同样经过 ReplaceAttr 脱敏
+	buf.Reset()
+	slog.New(createHandler(&Config{RedactPatterns: []string{`Bearer [A-Za-z0-9]+`}}, "ecs", &buf, slog.LevelInfo)).Info("token Bearer abc123")
+	m = nil
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+	assert.Equal(t, "token ***", m["message"])
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		level slog.Level
+		want  int
+	}{
+		{LevelTrace, 7},
+		{slog.LevelDebug, 7},
+		{slog.LevelInfo, 6},
+		{slog.LevelInfo + 1, 6},
+		{slog.LevelWarn, 4},
+		{slog.LevelError, 3},
+		{slog.LevelError + 4, 3},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, syslogSeverity(tt.level), tt.level.String())
+	}
+}
+
+func TestJournaldOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.sock")
+	if !journaldSupported {
+		_, _, err := NewWithCloser(&Config{Output: "journald://" + path})
+		assert.ErrorContains(t, err, "not supported")
+		return
+	}
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	logger, closer, err := NewWithCloser(&Config{
+		Level:          "DEBUG",
+		Format:         "json",
+		Output:         "journald://" + path,
+		AddSource:      true,
+		RedactPatterns: []string{`Bearer [A-Za-z0-9]+`},
+		MaxFieldLen:    32,
+	})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	logger.With("component", "db").WithGroup("req").Error("connection lost", "retry-count", 3, "_id", "x")
+	msg := read()
+	assert.True(t, strings.HasPrefix(msg, "MESSAGE=connection lost\nPRIORITY=3\nSYSLOG_IDENTIFIER="), msg)
+	assert.Contains(t, msg, "\nCODE_FILE=")
+	assert.Contains(t, msg, "\nCOMPONENT=db\nREQ_RETRY_COUNT=3\nREQ__ID=x\n")
+
+	logger.Debug("line one\nline two")
+	msg = read()
+	want := "MESSAGE\n\x11\x00\x00\x00\x00\x00\x00\x00line one\nline two\nPRIORITY=7\n"
+	assert.True(t, strings.HasPrefix(msg, want), "%q", msg)
+
+	// MESSAGE 同样经过 ReplaceAttr 脱敏和截断
+	logger.Info("token Bearer abc123")
+	assert.True(t, strings.HasPrefix(read(), "MESSAGE=token ***\n"))
+	logger.Info(strings.Repeat("x", 100))
+	assert.True(t, strings.HasPrefix(read(), "MESSAGE="+strings.Repeat("x", 32)+"…(truncated 68 bytes)\n"))
+
+	assert.Equal(t, "X__HIDDEN", journaldFieldName("_hidden"))
+	assert.Equal(t, "X_1ST", journaldFieldName("1st"))
+}
+
+type fakeEventLog struct {
+	entries []string
+	closed  bool
+}
+
+func (f *fakeEventLog) Info(eid uint32, msg string) error    { return f.add("info", msg) }
+func (f *fakeEventLog) Warning(eid uint32, msg string) error { return f.add("warning", msg) }
+func (f *fakeEventLog) Error(eid uint32, msg string) error   { return f.add("error", msg) }
+func (f *fakeEventLog) Close() error                         { f.closed = true; return nil }
+
+func (f *fakeEventLog) add(severity, msg string) error {
+	f.entries = append(f.entries, severity+" "+msg)
+	return nil
+}
+
+func TestEventLogOutput(t *testing.T) {
+	if runtime.GOOS != "windows" {
+		_, _, err := NewWithCloser(&Config{Output: "eventlog"})
+		assert.ErrorContains(t, err, "not supported")
+	}
+
+	fake := &fakeEventLog{}
+	sink := &eventLogSink{log: fake}
+	cfg := &Config{Format: "logfmt"}
+	logger := slog.New(&eventLogHandler{inner: createHandler(cfg, "logfmt", sink, LevelTrace), sink: sink})
+
+	logger.Debug("debug")
+	logger.Info("info", "k", 1)
+	logger.Warn("warn")
+	logger.Error("error")
+	logger.Log(context.Background(), slog.LevelError+4, "above error")
+	require.NoError(t, sink.Close())
+
+	require.Len(t, fake.entries, 5)
+	for i, severity := range []string{"info", "info", "warning", "error", "error"} {
+		assert.True(t, strings.HasPrefix(fake.entries[i], severity+" time="), fake.entries[i])
+		assert.False(t, strings.HasSuffix(fake.entries[i], "\n"))
+	}
+	assert.Contains(t, fake.entries[1], "msg=info k=1")
+	assert.True(t, fake.closed)
+}
+
+func TestSyslogOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	logger, closer, err := NewWithCloser(&Config{
+		Level:          "DEBUG",
+		Format:         "logfmt",
+		Output:         "syslog://" + path,
+		SyslogFacility: "local3",
+	})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	read := func() string {
+		buf := make([]byte, 4096)
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := conn.Read(buf)
+		require.NoError(t, err)
+		return string(buf[:n])
+	}
+
+	logger.With("component", "db").Error("connection lost", "retry", 3)
+	msg := read()
+	// local3 (19) * 8 + error (3) = 155
+	assert.Regexp(t, `^<155>1 \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}\.\d{6}\S+ \S+ \S+ \d+ - - time=`, msg)
+	assert.Contains(t, msg, `msg="connection lost" component=db retry=3`)
+	assert.False(t, strings.HasSuffix(msg, "\n"))
+
+	logger.Debug("details")
+	assert.True(t, strings.HasPrefix(read(), "<159>1 "), "local3 * 8 + debug (7)")
+}
+
+func TestSyslogOutputReconnect(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "syslog.sock")
+	listen := func() *net.UnixConn {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+		require.NoError(t, err)
+		return conn
+	}
+	conn := listen()
+
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: "syslog://" + path})
+	require.NoError(t, err)
+	defer closer.Close()
+
+	// 模拟 syslog 守护进程重启
+	require.NoError(t, conn.Close())
+	require.NoError(t, os.Remove(path))
+	conn = listen()
+	defer conn.Close()
+
+	logger.Info("after restart")
+	buf := make([]byte, 4096)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(buf[:n]), "<14>1 "), "user (1) * 8 + info (6)")
+	assert.Contains(t, string(buf[:n]), "after restart")
+}
+
+func TestSyslogTarget(t *testing.T) {
+	tests := []struct {
+		target  string
+		network string
+		addr    string
+	}{
+		{"syslog", "", ""},
+		{"syslog:///dev/log", "unixgram", "/dev/log"},
+		{"syslog://logs.example.com", "udp", "logs.example.com:514"},
+		{"syslog+udp://10.0.0.1:1514", "udp", "10.0.0.1:1514"},
+		{"syslog+tcp://logs.example.com:6514", "tcp", "logs.example.com:6514"},
+	}
+	for _, tt := range tests {
+		network, addr, err := parseSyslogTarget(tt.target)
+		require.NoError(t, err, tt.target)
+		assert.Equal(t, tt.network, network, tt.target)
+		assert.Equal(t, tt.addr, addr, tt.target)
+	}
+
+	assert.Error(t, (&Config{Format: "json", SyslogFacility: "local9"}).Validate())
+}
+
+func TestHTTPOutput(t *testing.T) {
+	var mu sync.Mutex
+	var batches [][]map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		var batch []map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&batch))
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	logger, closer, err := NewWithCloser(&Config{
+		Format:        "color",
+		Output:        server.URL + "/ingest",
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Headers:       map[string]string{"Authorization": "Bearer secret"},
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 25; i++ {
+		logger.Info("shipped", "i", i)
+	}
+	require.NoError(t, closer.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, batches, 3)
+	assert.Len(t, batches[0], 10)
+	assert.Len(t, batches[2], 5)
+	assert.Equal(t, "shipped", batches[0][0]["msg"], "non-JSON formats are sent as json")
+	assert.Equal(t, float64(24), batches[2][4]["i"])
+}
+
+func TestLokiOutput(t *testing.T) {
+	type push struct {
+		Streams []struct {
+			Stream map[string]string `json:"stream"`
+			Values [][2]string       `json:"values"`
+		} `json:"streams"`
+	}
+	var mu sync.Mutex
+	var pushes []push
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, lokiPushPath, r.URL.Path)
+		assert.Equal(t, "tenant-1", r.Header.Get("X-Scope-OrgID"))
+		var p push
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+		mu.Lock()
+		pushes = append(pushes, p)
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	logger, closer, err := NewWithCloser(&Config{
+		Output:        "loki://" + strings.TrimPrefix(server.URL, "http://"),
+		DefaultAttrs:  map[string]string{"service": "api", "app.env": "prod"},
+		BatchSize:     10,
+		FlushInterval: time.Hour,
+		Headers:       map[string]string{"X-Scope-OrgID": "tenant-1"},
+	})
+	require.NoError(t, err)
+
+	before := time.Now().UnixNano()
+	for i := 0; i < 12; i++ {
+		logger.Info("shipped", "i", i)
+	}
+	logger.WithGroup("req").Info("grouped", "service", "nested")
+	require.NoError(t, closer.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, pushes, 2)
+	require.Len(t, pushes[0].Streams, 1)
+	stream := pushes[0].Streams[0]
+	assert.Equal(t, map[string]string{"service": "api", "app_env": "prod"}, stream.Stream)
+	require.Len(t, stream.Values, 10)
+	require.Len(t, pushes[1].Streams[0].Values, 3)
+
+	ts, err := strconv.ParseInt(stream.Values[0][0], 10, 64)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, ts, before)
+	var line map[string]any
+	require.NoError(t, json.Unmarshal([]byte(stream.Values[0][1]), &line))
+	assert.Equal(t, "shipped", line["msg"])
+	assert.NotContains(t, line, "service", "labels should not be repeated in the line")
+	assert.NotContains(t, line, "app.env")
+	assert.Contains(t, pushes[1].Streams[0].Values[2][1], `"req":{"service":"nested"}`)
+}
+
+func TestLokiLabels(t *testing.T) {
+	assert.Equal(t, map[string]string{"job": filepath.Base(os.Args[0])}, lokiLabels(nil))
+	assert.Equal(t, "app_env", lokiLabelName("app.env"))
+	assert.Equal(t, "_9lives", lokiLabelName("9lives"))
+	assert.Equal(t, "k8s_pod", lokiLabelName("k8s-pod"))
+
+	_, _, err := newLokiOutput(&Config{}, "loki://", "json", slog.LevelInfo)
+	assert.Error(t, err)
+}
+
+// fakeCloudWatch 记录每次 PutLogEvents 的事件，errs 依次作为前几次调用的错误返回
+type fakeCloudWatch struct {
+	calls  [][]CloudWatchEvent
+	tokens []*string
+	errs   []error
+}
+
+func (c *fakeCloudWatch) PutLogEvents(ctx context.Context, events []CloudWatchEvent, token *string) (*string, error) {
+	c.calls = append(c.calls, events)
+	c.tokens = append(c.tokens, token)
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	next := strconv.Itoa(len(c.calls))
+	return &next, nil
+}
+
+func TestCloudWatchSplit(t *testing.T) {
+	events := make([]CloudWatchEvent, cloudWatchMaxBatchEvents+5)
+	chunks := splitCloudWatchEvents(events)
+	require.Len(t, chunks, 2)
+	assert.Len(t, chunks[0], cloudWatchMaxBatchEvents)
+	assert.Len(t, chunks[1], 5)
+
+	// 每条 100 KiB，10 条超过 1 MiB（含每条 26 字节的额外开销）
+	big := CloudWatchEvent{Message: strings.Repeat("x", 100<<10)}
+	events = []CloudWatchEvent{big, big, big, big, big, big, big, big, big, big, big, big}
+	chunks = splitCloudWatchEvents(events)
+	require.Len(t, chunks, 2)
+	assert.Len(t, chunks[0], 10)
+	assert.Len(t, chunks[1], 2)
+	for _, chunk := range chunks {
+		size := 0
+		for _, e := range chunk {
+			size += len(e.Message) + cloudWatchEventOverhead
+		}
+		assert.LessOrEqual(t, size, cloudWatchMaxBatchBytes)
+	}
+
+	assert.Empty(t, splitCloudWatchEvents(nil))
+}
+
+func TestCloudWatchSink(t *testing.T) {
+	client := &fakeCloudWatch{}
+	sink := &cloudWatchSink{client: client, backoff: time.Millisecond}
+	encode := func(line string) []byte {
+		var buf bytes.Buffer
+		writer := &cloudWatchWriter{out: &buf, now: func() time.Time { return time.UnixMilli(1700000000123) }}
+		_, _ = writer.Write([]byte(line + "\n"))
+		return buf.Bytes()
+	}
+	require.NoError(t, sink.send([][]byte{encode(`{"msg":"a"}`), encode(strings.Repeat("é", cloudWatchMaxEventBytes))}))
+	require.Len(t, client.calls, 1)
+	assert.Equal(t, CloudWatchEvent{Timestamp: 1700000000123, Message: `{"msg":"a"}`}, client.calls[0][0])
+	assert.LessOrEqual(t, len(client.calls[0][1].Message), cloudWatchMaxEventBytes)
+	assert.True(t, utf8.ValidString(client.calls[0][1].Message), "oversized events are truncated on a rune boundary")
+	assert.Nil(t, client.tokens[0])
+
+	// 使用上一次返回的序列号；序列号无效时改用服务端期望的序列号，限流时退避重试
+	expected := "expected"
+	client.errs = []error{&CloudWatchTokenError{Expected: &expected}, fmt.Errorf("%w: slow down", ErrCloudWatchThrottled)}
+	require.NoError(t, sink.send([][]byte{encode("b")}))
+	require.Len(t, client.calls, 4)
+	assert.Equal(t, "1", *client.tokens[1])
+	assert.Equal(t, "expected", *client.tokens[2])
+	assert.Equal(t, "expected", *client.tokens[3])
+
+	// 其他错误不重试
+	client.errs = []error{errors.New("access denied")}
+	assert.Error(t, sink.send([][]byte{encode("c")}))
+	assert.Len(t, client.calls, 5)
+
+	// 未导入 cloudwatch 子模块时不支持
+	_, _, err := newCloudWatchOutput(&Config{}, "cloudwatch://group/stream", "json", slog.LevelInfo)
+	assert.ErrorContains(t, err, "pkg/logger/cloudwatch")
+	_, _, err = newCloudWatchOutput(&Config{}, "cloudwatch://group-only", "json", slog.LevelInfo)
+	assert.ErrorContains(t, err, "log-group/log-stream")
+}
+
+// fakeKafka 记录发送的消息，err 不为 nil 时所有消息发送失败，block 不为 nil 时发送前等待
+type fakeKafka struct {
+	mu       sync.Mutex
+	messages []KafkaMessage
+	err      error
+	block    chan struct{}
+}
+
+func (p *fakeKafka) Produce(ctx context.Context, messages []KafkaMessage) (int, error) {
+	if p.block != nil {
+		<-p.block
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return len(messages), p.err
+	}
+	for _, m := range messages {
+		p.messages = append(p.messages, KafkaMessage{Key: bytes.Clone(m.Key), Value: bytes.Clone(m.Value)})
+	}
+	return 0, nil
+}
+
+func (p *fakeKafka) Close() error { return nil }
+
+// newFakeKafkaLogger 与 newKafkaOutput 相同，但使用 producer 发送
+func newFakeKafkaLogger(cfg *Config, producer KafkaProducer) (*slog.Logger, *kafkaSink, *batchWriter) {
+	sink := &kafkaSink{producer: producer}
+	writer := newBatchWriter("kafka-test", sink.send, cfg.BatchSize, time.Hour, cfg.BufferSize)
+	out := &kafkaWriter{out: writer}
+	handler := &kafkaHandler{inner: createHandler(cfg, "json", out, slog.LevelInfo), out: out, key: cfg.KafkaKey}
+	return slog.New(handler), sink, writer
+}
+
+func TestKafkaKey(t *testing.T) {
+	producer := &fakeKafka{}
+	logger, sink, writer := newFakeKafkaLogger(&Config{KafkaKey: "tenant_id"}, producer)
+
+	logger.Info("no key")
+	logger.Info("record key", "tenant_id", "t1")
+	tenant := logger.With("tenant_id", "t2")
+	tenant.Info("with key")
+	tenant.Info("record overrides", "tenant_id", 3)
+	tenant.WithGroup("req").Info("grouped", "tenant_id", "ignored")
+	require.NoError(t, writer.Close())
+
+	keys := make([]string, 0, len(producer.messages))
+	for _, m := range producer.messages {
+		keys = append(keys, string(m.Key))
+	}
+	assert.Equal(t, []string{"", "t1", "t2", "3", "t2"}, keys)
+	assert.Nil(t, producer.messages[0].Key)
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(producer.messages[1].Value, &record))
+	assert.Equal(t, "record key", record["msg"])
+	assert.Equal(t, "t1", record["tenant_id"])
+	assert.Zero(t, sink.Failed())
+}
+
+func TestKafkaUnreachable(t *testing.T) {
+	// broker 不可用时发送失败计数，队列满后丢弃，记录日志不阻塞
+	producer := &fakeKafka{err: errors.New("dial tcp: connection refused"), block: make(chan struct{})}
+	logger, sink, writer := newFakeKafkaLogger(&Config{BatchSize: 1, BufferSize: 2}, producer)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			logger.Info("lost", "i", i)
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("logging blocked while the broker was unreachable")
+	}
+	assert.Positive(t, writer.Dropped())
+
+	close(producer.block)
+	require.NoError(t, writer.Close())
+	assert.Positive(t, sink.Failed())
+	assert.Empty(t, producer.messages)
+
+	_, _, err := newKafkaOutput(&Config{}, "kafka://broker:9092", "json", slog.LevelInfo)
+	assert.ErrorContains(t, err, "kafka://broker:9092/topic")
+	// 未导入 kafka 子模块时不支持
+	_, _, err = newKafkaOutput(&Config{}, "kafka://broker:9092/logs", "json", slog.LevelInfo)
+	assert.ErrorContains(t, err, "pkg/logger/kafka")
+}
+
+// fluentServer 模拟 forward 协议的聚合器，按行接收 fakeFluentEncode 编码的消息，
+// 每个连接收到第一条消息后关闭该连接
+type fluentServer struct {
+	ln       net.Listener
+	conns    atomic.Int32
+	messages chan string
+}
+
+func newFluentServer(t *testing.T) *fluentServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fluentServer{ln: ln, messages: make(chan string, 100)}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			n := s.conns.Add(1)
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			if line != "" {
+				s.messages <- fmt.Sprintf("conn%d %s", n, strings.TrimSpace(line))
+			}
+			_ = conn.Close()
+		}
+	}()
+	t.Cleanup(func() { _ = ln.Close() })
+	return s
+}
+
+// fakeFluentEncode 将一批事件编码为一行 "tag msg1,msg2"
+func fakeFluentEncode(tag string, entries []FluentEntry) ([]byte, error) {
+	msgs := make([]string, len(entries))
+	for i, entry := range entries {
+		msgs[i] = fmt.Sprint(entry.Record["msg"])
+	}
+	return []byte(tag + " " + strings.Join(msgs, ",") + "\n"), nil
+}
+
+func TestFluentSink(t *testing.T) {
+	server := newFluentServer(t)
+	sink := &fluentSink{
+		address: server.ln.Addr().String(),
+		tag:     "myapp",
+		dial:    func(address string) (net.Conn, error) { return net.Dial("tcp", address) },
+		encode:  fakeFluentEncode,
+	}
+	var batch bytes.Buffer
+	out := &fluentWriter{out: &batch, now: time.Now}
+	entry := func(msg string) [][]byte {
+		batch.Reset()
+		slog.New(createHandler(&Config{}, "json", out, slog.LevelInfo)).Info(msg)
+		return [][]byte{bytes.Clone(batch.Bytes())}
+	}
+
+	require.NoError(t, sink.send(entry("first")))
+	assert.Equal(t, "conn1 myapp first", <-server.messages)
+
+	// 聚合器关闭了连接：写入失败后重连，之后的记录通过新连接发送
+	var got string
+	for i := 0; i < 50 && got == ""; i++ {
+		_ = sink.send(entry(fmt.Sprintf("retry-%d", i)))
+		select {
+		case got = <-server.messages:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	assert.Regexp(t, `^conn2 myapp retry-\d+$`, got)
+	require.NoError(t, sink.Close())
+
+	// 聚合器不可用时返回错误，下次发送前重新连接
+	require.NoError(t, server.ln.Close())
+	assert.ErrorContains(t, sink.send(entry("lost")), "fluent:")
+	assert.Nil(t, sink.conn)
+
+	record, err := fluentRecord([]byte(`{"n":1,"f":1.5,"big":1e3,"nested":{"id":2},"list":[3]}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"n": int64(1), "f": 1.5, "big": 1000.0, "nested": map[string]any{"id": int64(2)}, "list": []any{int64(3)}}, record)
+
+	_, _, err = newFluentOutput(&Config{}, "fluent://", "json", slog.LevelInfo)
+	assert.ErrorContains(t, err, "fluent://host:24224")
+	// 未导入 fluent 子模块时不支持
+	_, _, err = newFluentOutput(&Config{}, "fluent://localhost:24224", "json", slog.LevelInfo)
+	assert.ErrorContains(t, err, "pkg/logger/fluent")
+}
+
+func TestHTTPOutputRetry(t *testing.T) {
+	var attempts atomic.Int32
+	var status atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if code := status.Load(); code != 0 {
+			w.WriteHeader(int(code))
+		}
+	}))
+	defer server.Close()
+
+	sink := &httpSink{url: server.URL, client: server.Client(), backoff: time.Millisecond}
+	require.NoError(t, sink.send([][]byte{[]byte(`{"msg":"a"}` + "\n")}))
+	assert.Equal(t, int32(3), attempts.Load(), "5xx responses should be retried")
+
+	status.Store(http.StatusBadRequest)
+	require.Error(t, sink.send([][]byte{[]byte(`{}`)}))
+	assert.Equal(t, int32(4), attempts.Load(), "4xx responses should not be retried")
+}
+
+func TestBatchWriterDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	var sent atomic.Int32
+	w := newBatchWriter("test", func(batch [][]byte) error {
+		<-block
+		sent.Add(int32(len(batch)))
+		return nil
+	}, 1, time.Hour, 5)
+
+	for i := 0; i < 20; i++ {
+		_, err := w.Write([]byte("x"))
+		require.NoError(t, err)
+	}
+	assert.Greater(t, w.Dropped(), int64(0))
+	close(block)
+	require.NoError(t, w.Close())
+	assert.Equal(t, int64(20), int64(sent.Load())+w.Dropped())
+}
+
+func TestAlertWebhook(t *testing.T) {
+	var mu sync.Mutex
+	var alerts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload alertPayload
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		mu.Lock()
+		alerts = append(alerts, payload.Text)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewWithCloser(&Config{Format: "json", Output: path, AlertWebhook: server.URL})
+	require.NoError(t, err)
+
+	logger = logger.With("service", "api")
+	for i := 0; i < 20; i++ {
+		logger.Error("db unavailable", "attempt", i)
+	}
+	logger.Error("cache unavailable")
+	logger.Warn("not an alert")
+	require.NoError(t, closer.Close())
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, alerts, 2, "bursts of the same message should be throttled")
+	assert.Equal(t, "[ERROR] db unavailable\nservice=api\nattempt=0", alerts[0])
+	assert.Equal(t, "[ERROR] cache unavailable\nservice=api", alerts[1])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 22, strings.Count(string(data), "\n"), "all records are still logged")
+
+	// 告警内容与输出一样脱敏
+	alerts = nil
+	mu.Unlock()
+	logger, closer, err = NewWithCloser(&Config{
+		Format:         "json",
+		Output:         path,
+		AlertWebhook:   server.URL,
+		RedactKeys:     []string{"password"},
+		RedactPatterns: []string{`Bearer [A-Za-z0-9]+`},
+	})
+	require.NoError(t, err)
+	logger.With("password", "hunter2").WithGroup("req").Error("login failed Bearer abc123", "password", "hunter2", "note", "Bearer xyz789")
+	require.NoError(t, closer.Close())
+	mu.Lock()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "[ERROR] login failed ***\npassword=***\nreq.password=***\nreq.note=***", alerts[0])
+}
+
+func TestAlertWebhookFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	var buf syncBuffer
+	handler := newAlertHandler(newJSONHandler(&buf, nil, "datetime", ""), server.URL, slog.LevelError, time.Minute, nil)
+	slog.New(handler).Error("boom")
+	require.NoError(t, handler.state.Close())
+
+	output := buf.String()
+	assert.Equal(t, 1, strings.Count(output, `"msg":"alert webhook failed"`), "failure is logged once without recursion")
+	assert.Contains(t, output, `"level":"WARN"`)
+}
+
+func TestStacktraceLevel(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, StacktraceLevel: "ERROR"}))
+	defer Close()
+
+	Error("failed")
+	slog.Warn("no stack")
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &m))
+	stack, _ := m["stacktrace"].(string)
+	assert.True(t, strings.HasPrefix(stack, "github.com/lwmacct/251125-go-mod-logger/pkg/logger.TestStacktraceLevel\n\t"),
+		"top frame should be the caller, got:\n%s", stack)
+	assert.Contains(t, stack, "logger_test.go:")
+	assert.NotContains(t, stack, "helpers.go")
+	assert.NotContains(t, stack, "log/slog")
+	assert.NotContains(t, lines[1], "stacktrace")
+}
+
+func TestSourceMode(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{"full", file},
+		{"short", "logger_test.go"},
+		{"", "logger_test.go"},
+		{"package", "logger/logger_test.go"},
+	}
+
+	for _, tt := range tests {
+		for _, format := range []string{"json", "text", "logfmt", "color"} {
+			t.Run(tt.mode+"/"+format, func(t *testing.T) {
+				var buf bytes.Buffer
+				cfg := &Config{Level: "INFO", Format: format, AddSource: true, SourceMode: tt.mode}
+				logger := slog.New(createHandler(cfg, format, &buf, slog.LevelInfo))
+				_, _, line, _ := runtime.Caller(0)
+				logger.Info("hello")
+
+				output := buf.String()
+				want := fmt.Sprintf("%s:%d", tt.expected, line+1)
+				assert.Contains(t, output, want)
+				if tt.mode != "full" {
+					assert.NotContains(t, output, file)
+				}
+			})
+		}
+	}
+
+	assert.Error(t, (&Config{Level: "INFO", Format: "json", SourceMode: "long"}).Validate())
+}
+
+func TestHelperSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "DEBUG", Format: "json", Output: path, AddSource: true}))
+	defer Close()
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	var lines []int
+	call := func(f func()) {
+		_, _, line, _ := runtime.Caller(1)
+		lines = append(lines, line)
+		f()
+	}
+	call(func() { Debug("debug") })
+	call(func() { Info("info") })
+	call(func() { Warn("warn") })
+	call(func() { Error("error") })
+	call(func() { _ = LogError(context.Background(), "log error", errors.New("boom")) })
+	call(func() { _ = LogAndWrapCtx(context.Background(), "wrap error", errors.New("boom")) })
+	call(func() { InfoContext(context.Background(), "info context") })
+	call(func() { InfoAttrs("info attrs", slog.Int("n", 1)) })
+	call(func() { Infof("info %s", "printf") })
+	call(func() { LogIfContextDone(canceled, "context done") })
+	call(func() { _ = Monitored(canceled, "op", func() error { return nil }) })
+	call(func() { _ = Measure(context.Background(), "op", func() error { return nil }) })
+	lines = append(lines, lines[len(lines)-1]) // Measure 记录开始和结束两条
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	records := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, records, len(lines))
+	for i, record := range records {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(record), &m))
+		assert.Equal(t, fmt.Sprintf("logger_test.go:%d", lines[i]), m["source"], "record %d: %s", i, record)
+	}
+}
+
+func TestModuleLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{
+		Level:        "INFO",
+		Format:       "json",
+		Output:       path,
+		ModuleLevels: map[string]string{"db": "DEBUG", "http": "WARN"},
+	}))
+	defer Close()
+
+	Named("db").Debug("db debug")
+	Named("http").Debug("http debug")
+	Named("http").Info("http info")
+	Named("http").Warn("http warn")
+	Named("cache").Debug("cache debug")
+	Named("cache").Info("cache info")
+	Debug("global debug")
+	Info("global info")
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	output := string(data)
+	assert.Contains(t, output, `"msg":"db debug"`)
+	assert.Contains(t, output, `"logger":"db"`)
+	assert.NotContains(t, output, "http debug")
+	assert.NotContains(t, output, "http info")
+	assert.Contains(t, output, "http warn")
+	assert.NotContains(t, output, "cache debug")
+	assert.Contains(t, output, "cache info")
+	assert.NotContains(t, output, "global debug")
+	assert.Contains(t, output, "global info")
+
+	assert.Error(t, (&Config{Level: "INFO", Format: "json", ModuleLevels: map[string]string{"db": "LOUD"}}).Validate())
+}
+
+func TestNewLogr(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "DEBUG", Format: "json", Output: path, AddSource: true}))
+	defer Close()
+
+	log := NewLogr().WithName("controller").WithValues("kind", "Pod")
+	_, _, line, _ := runtime.Caller(0)
+	log.Info("reconciling", "name", "web")
+	log.V(1).Info("details")
+	log.V(2).Info("too verbose")
+	log.Error(errors.New("boom"), "reconcile failed")
+	assert.True(t, log.Enabled())
+	assert.False(t, log.V(2).Enabled())
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+
+	var info, debug, failed map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &info))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &debug))
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &failed))
+
+	assert.Equal(t, "INFO", info["level"])
+	assert.Equal(t, "reconciling", info["msg"])
+	assert.Equal(t, "controller", info["logger"])
+	assert.Equal(t, "Pod", info["kind"])
+	assert.Equal(t, "web", info["name"])
+	assert.Equal(t, fmt.Sprintf("logger_test.go:%d", line+1), info["source"])
+	assert.Equal(t, "DEBUG", debug["level"])
+	assert.Equal(t, "ERROR", failed["level"])
+	assert.Equal(t, "boom", failed["error"])
+}
+
+func TestStdLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, AddSource: true}))
+	defer Close()
+
+	flags, prefix, out := log.Flags(), log.Prefix(), log.Writer()
+	defer func() {
+		log.SetFlags(flags)
+		log.SetPrefix(prefix)
+		log.SetOutput(out)
+	}()
+
+	RedirectStdLog()
+	_, _, line, _ := runtime.Caller(0)
+	log.Println("x")
+	NewStdLogger("ERROR").Printf("first line\nsecond line")
+	NewStdLogger("DEBUG").Print("dropped")
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+
+	var first, second map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "INFO", first["level"])
+	assert.Equal(t, "x", first["msg"])
+	assert.Equal(t, fmt.Sprintf("logger_test.go:%d", line+1), first["source"])
+	assert.Equal(t, "ERROR", second["level"])
+	assert.Equal(t, "first line\nsecond line", second["msg"])
+}
+
+func TestMiddleware(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, SlowRequestThreshold: 20 * time.Millisecond}))
+	defer Close()
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fail":
+			FromContext(r.Context()).Info("downstream")
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(30 * time.Millisecond)
+			_, _ = w.Write([]byte("ok"))
+		default:
+			_, _ = w.Write([]byte("hello"))
+		}
+	}))
+
+	for _, target := range []string{"/fail", "/slow", "/ok"} {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if target == "/ok" {
+			req.Header.Set("X-Request-ID", "abc")
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.NotEmpty(t, rec.Header().Get("X-Request-ID"))
+	}
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 4)
+
+	records := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &records[i]))
+	}
+
+	downstream, failed, slow, ok := records[0], records[1], records[2], records[3]
+	assert.Equal(t, "downstream", downstream["msg"])
+	assert.Equal(t, failed["request_id"], downstream["request_id"])
+
+	assert.Equal(t, "ERROR", failed["level"])
+	assert.Equal(t, "http request", failed["msg"])
+	assert.Equal(t, "GET", failed["method"])
+	assert.Equal(t, "/fail", failed["path"])
+	assert.Equal(t, float64(500), failed["status"])
+	assert.NotEmpty(t, failed["duration"])
+	assert.Equal(t, float64(5), failed["bytes"])
+
+	assert.Equal(t, "WARN", slow["level"])
+	assert.Equal(t, "INFO", ok["level"])
+	assert.Equal(t, "abc", ok["request_id"])
+	assert.Equal(t, float64(5), ok["bytes"])
+}
+
+func TestCorrelationID(t *testing.T) {
+	id := NewCorrelationID()
+	assert.Regexp(t, `^[a-z2-7]{16}$`, id)
+	assert.NotEqual(t, id, NewCorrelationID())
+	assert.Empty(t, CorrelationID(context.Background()))
+
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path}))
+	defer Close()
+
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		InfoContext(ctx, "step one")
+		FromContext(ctx).WarnContext(ctx, "step two")
+		_ = LogError(ctx, "step three", errors.New("boom"))
+	}))
+
+	var ids []string
+	for _, header := range []string{"", "upstream-id"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if header != "" {
+			req.Header.Set("X-Correlation-ID", header)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		ids = append(ids, rec.Header().Get("X-Correlation-ID"))
+	}
+	require.NoError(t, Close())
+	assert.Regexp(t, `^[a-z2-7]{16}$`, ids[0])
+	assert.Equal(t, "upstream-id", ids[1])
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 8)
+	for i, line := range lines {
+		var m map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &m))
+		assert.Equal(t, ids[i/4], m["correlation_id"], line)
+	}
+}
+
+func TestTimer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, AddSource: true}))
+	defer Close()
+
+	func() {
+		defer Timer("fast", "step", 1)()
+	}()
+	func() {
+		defer TimerWithThreshold(time.Millisecond, "slow")()
+		time.Sleep(5 * time.Millisecond)
+	}()
+	func() {
+		defer TimerWithThreshold(time.Hour, "within threshold")()
+	}()
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+
+	records := make([]map[string]any, len(lines))
+	for i, line := range lines {
+		require.NoError(t, json.Unmarshal([]byte(line), &records[i]))
+	}
+	assert.Equal(t, "INFO", records[0]["level"])
+	assert.Equal(t, float64(1), records[0]["step"])
+	assert.Contains(t, records[0]["source"], "logger_test.go:")
+
+	assert.Equal(t, "WARN", records[1]["level"])
+	elapsed, err := time.ParseDuration(records[1]["duration"].(string))
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, elapsed, 5*time.Millisecond)
+	assert.Less(t, elapsed, time.Second)
+
+	assert.Equal(t, "INFO", records[2]["level"])
+}
+
+func TestMaxFieldLen(t *testing.T) {
+	huge := strings.Repeat("x", 2<<20)
+
+	for _, format := range []string{"json", "text", "logfmt", "color"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &Config{Level: "INFO", Format: format, MaxFieldLen: 16}
+			logger := slog.New(createHandler(cfg, format, &buf, slog.LevelInfo))
+			logger.Info(huge, "body", huge, "raw", []byte(huge), "group", slog.GroupValue(slog.String("nested", huge)))
+
+			output := buf.String()
+			assert.Less(t, len(output), 1024, "output should be bounded")
+			assert.Contains(t, output, strings.Repeat("x", 16)+"…(truncated 2097136 bytes)")
+			assert.Equal(t, 4, strings.Count(output, "truncated"))
+		})
+	}
+
+	a := truncateAttr(slog.String("k", "你好世界"), 4)
+	assert.Equal(t, "你…(truncated 9 bytes)", a.Value.String(), "should cut at a rune boundary")
+	a = truncateAttr(slog.Int("k", 123456), 2)
+	assert.Equal(t, int64(123456), a.Value.Int64())
+}
+
+func TestMaxLineBytes(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &Config{MaxLineBytes: 512}
+	logger := slog.New(createHandler(cfg, "json", &buf, slog.LevelInfo))
+
+	logger.Info("small", "user", 42)
+	assert.Contains(t, buf.String(), `"user":42`, "lines under the limit are unchanged")
+
+	huge := make([]int, 1000)
+	for _, format := range []string{"json", "ecs"} {
+		buf.Reset()
+		logger := slog.New(createHandler(cfg, format, &buf, slog.LevelInfo))
+		logger.Info("upload", "user", 42, "body", strings.Repeat("x\"", 5000), "ids", huge, "path", "/a")
+
+		line := buf.Bytes()
+		assert.LessOrEqual(t, len(line), 512, format)
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(line, &m), format)
+		assert.EqualValues(t, 42, m["user"], format)
+		assert.Equal(t, "/a", m["path"], format)
+		assert.Contains(t, m["body"], "…(truncated ", format)
+		assert.NotContains(t, m, "ids", format)
+		assert.EqualValues(t, 1, m[droppedAttrsKey], format)
+	}
+
+	buf.Reset()
+	slog.New(createHandler(cfg, "logfmt", &buf, slog.LevelInfo)).Info(strings.Repeat("m", 2000), "n", 1)
+	assert.LessOrEqual(t, buf.Len(), 512)
+	assert.Contains(t, buf.String(), " n=1\n")
+
+	// With 预设的属性无法缩减，直接截断整行
+	buf.Reset()
+	logger.With("preset", strings.Repeat("p", 2000)).Info("hello")
+	assert.LessOrEqual(t, buf.Len(), 512)
+	assert.Regexp(t, `…\(truncated \d+ bytes\)\n$`, buf.String())
+
+	assert.ErrorContains(t, (&Config{MaxLineBytes: -1}).Validate(), "invalid max line bytes")
+}
+
+func TestMaxAttrs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, MaxAttrs: 50}))
+	defer Close()
+
+	args := make([]any, 0, 1000)
+	for i := range 500 {
+		args = append(args, fmt.Sprintf("k%03d", i), i)
+	}
+	Info("many", args...)
+
+	group := make([]any, 0, 20)
+	for i := range 10 {
+		group = append(group, fmt.Sprintf("g%d", i), i)
+	}
+	slog.With(args[:90]...).Info("grouped", slog.Group("req", group...))
+	Info("few", "a", 1)
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 3)
+
+	var many, grouped, few map[string]any
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &many))
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &grouped))
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &few))
+
+	// time、level、msg 加 50 个属性和 _dropped_attrs
+	assert.Len(t, many, 3+50+1)
+	assert.Equal(t, float64(450), many["_dropped_attrs"])
+	assert.Contains(t, many, "k049")
+	assert.NotContains(t, many, "k050")
+
+	// 预设的 45 个属性加分组中的前 5 个
+	req, _ := grouped["req"].(map[string]any)
+	assert.Len(t, req, 5)
+	assert.Equal(t, float64(5), grouped["_dropped_attrs"])
+
+	assert.NotContains(t, few, "_dropped_attrs")
+}
+
+func TestNDJSONHandler(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &Config{Level: "INFO", Format: "ndjson", AddSource: true}
+	logger := slog.New(createHandler(cfg, "ndjson", &buf, slog.LevelInfo)).With("service", "api")
+
+	logger.Info("first", "zeta", 1, "alpha", 2, "req", map[string]any{"b": 1, "a": 2})
+	logger.Info("second", "alpha", 2, "req", map[string]any{"a": 2, "b": 1}, "zeta", 1)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	for _, line := range lines {
+		assert.Regexp(t, `^\{"time":"[^"]+","level":"INFO","msg":"[a-z]+","alpha":2,"req":\{"a":2,"b":1\},"service":"api","source":"logger_test.go:\d+","zeta":1\}$`, line)
+	}
+	require.NoError(t, (&Config{Level: "INFO", Format: "ndjson"}).Validate())
+}
+
+func TestPrettyJSONHandler(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := &Config{Level: "INFO", Format: "json-pretty", AddSource: true, TimeFormat: "unix", RedactKeys: []string{"password"}}
+	logger := slog.New(createHandler(cfg, "json-pretty", &buf, slog.LevelInfo))
+	logger.Info("hello", "user", map[string]any{"name": "alice"}, "password", "secret")
+	logger.Info("again")
+
+	output := buf.String()
+	assert.True(t, strings.HasPrefix(output, "{\n  \"time\": "), output)
+	assert.Contains(t, output, "\n  \"level\": \"INFO\",\n  \"msg\": \"hello\",")
+	assert.Contains(t, output, "\n  \"user\": {\n    \"name\": \"alice\"\n  }")
+	assert.Contains(t, output, `"password": "***"`)
+	assert.Contains(t, output, `"source": "logger_test.go:`)
+	assert.Equal(t, 2, strings.Count(output, "\n}\n"))
+
+	var m map[string]any
+	dec := json.NewDecoder(strings.NewReader(output))
+	require.NoError(t, dec.Decode(&m))
+	assert.IsType(t, float64(0), m["time"])
+}
+
+func TestColorJSONHandler(t *testing.T) {
+	render := func() string {
+		var buf bytes.Buffer
+		logger := slog.New(createHandler(&Config{TimeFormat: "unix"}, "json-color", &buf, slog.LevelInfo))
+		logger.Info("hello", "n", 42, "ok", true, "user", map[string]any{"name": "alice \"a\""}, "missing", nil)
+		return buf.String()
+	}
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	output := render()
+	assert.NotContains(t, output, "\033[", "buffers are not terminals")
+	assert.Equal(t, 1, strings.Count(output, "\n"), "degrades to compact JSON")
+	var m map[string]any
+	require.NoError(t, json.Unmarshal([]byte(output), &m))
+	assert.Equal(t, "hello", m["msg"])
+
+	t.Setenv("FORCE_COLOR", "1")
+	output = render()
+	assert.Contains(t, output, jsonKeyColor+`"msg"`+colorReset+": "+jsonStringColor+`"hello"`+colorReset)
+	assert.Contains(t, output, jsonNumberColor+"42"+colorReset)
+	assert.Contains(t, output, jsonLiteralColor+"true"+colorReset)
+	assert.Contains(t, output, jsonLiteralColor+"null"+colorReset)
+	assert.Contains(t, output, jsonStringColor+`"alice \"a\""`+colorReset)
+	plain := regexp.MustCompile("\033\\[[0-9;]*m").ReplaceAllString(output, "")
+	assert.True(t, strings.HasPrefix(plain, "{\n  \"time\": "), plain)
+	require.NoError(t, json.Unmarshal([]byte(plain), &m))
+	assert.EqualValues(t, 42, m["n"])
+
+	t.Setenv("NO_COLOR", "1")
+	assert.NotContains(t, render(), "\033[", "NO_COLOR takes precedence")
+}
+
+func TestInitFromFile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.json")
+
+	yamlPath := filepath.Join(dir, "logger.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+level: DEBUG
+format: json
+output: `+logPath+`
+default_attrs:
+  service: api
+dedup_window: 5s
+`), 0o644))
+
+	cfg, err := loadConfigFile(yamlPath)
+	require.NoError(t, err)
+	assert.Equal(t, "DEBUG", cfg.Level)
+	assert.Equal(t, 5*time.Second, cfg.DedupWindow)
+	assert.Equal(t, "datetime", cfg.TimeFormat, "unset fields should keep defaults")
+
+	require.NoError(t, InitFromFile(yamlPath))
+	defer Close()
+	Debug("from file")
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"msg":"from file"`)
+	assert.Contains(t, string(data), `"service":"api"`)
+
+	// 环境变量覆盖文件中的值
+	t.Setenv("LOG_LEVEL", "ERROR")
+	require.NoError(t, InitFromFile(yamlPath))
+	assert.Equal(t, "ERROR", GetLevel())
+	require.NoError(t, Close())
+
+	jsonPath := filepath.Join(dir, "logger.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"level": "WARN", "format": "logfmt", "redact_keys": ["token"]}`), 0o644))
+	cfg, err = loadConfigFile(jsonPath)
+	require.NoError(t, err)
+	assert.Equal(t, "WARN", cfg.Level)
+	assert.Equal(t, "logfmt", cfg.Format)
+	assert.Equal(t, []string{"token"}, cfg.RedactKeys)
+
+	typoPath := filepath.Join(dir, "typo.yml")
+	require.NoError(t, os.WriteFile(typoPath, []byte("levle: DEBUG\n"), 0o644))
+	_, err = loadConfigFile(typoPath)
+	assert.ErrorContains(t, err, "levle")
+
+	_, err = loadConfigFile(filepath.Join(dir, "logger.toml"))
+	assert.ErrorContains(t, err, "unsupported config file extension")
+}
+
+func TestConfigValidateJoinsErrors(t *testing.T) {
+	cfg := &Config{Level: "VERBOSE", Format: "yaml", Output: "ftp://example.com", TimeFormat: "iso"}
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.ErrorContains(t, err, "invalid log format")
+	assert.ErrorContains(t, err, "invalid log level")
+	assert.ErrorContains(t, err, "unsupported output")
+	assert.ErrorContains(t, err, "invalid time format")
+
+	_, err = New(cfg)
+	assert.ErrorContains(t, err, "invalid log level")
+	assert.ErrorContains(t, InitCfg(cfg), "invalid log format")
+}
+
+func TestMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	require.NoError(t, InitCfg(&Config{
+		Level:       "DEBUG",
+		Format:      "json",
+		Output:      path,
+		Metrics:     true,
+		SampleEvery: 2,
+		Filter:      func(level slog.Level, msg string, attrs map[string]any) bool { return msg != "filtered" },
+	}))
+	defer Close()
+
+	before := Metrics()
+	for range 4 {
+		Debug("sampled")
+	}
+	Warn("filtered")
+	Warn("warning")
+	Error("failure")
+	slog.Log(context.Background(), slog.LevelError+2, "above error")
+	after := Metrics()
+
+	// 计数包括之后被采样丢弃的记录，不包括被 Filter 丢弃的记录
+	assert.Equal(t, uint64(4), after.Messages["DEBUG"]-before.Messages["DEBUG"])
+	assert.Equal(t, uint64(1), after.Messages["WARN"]-before.Messages["WARN"])
+	assert.Equal(t, uint64(2), after.Messages["ERROR"]-before.Messages["ERROR"])
+	assert.Equal(t, uint64(2), after.Dropped-before.Dropped)
+
+	rec := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rec.Body.String(), "# TYPE log_messages_total counter\n")
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("log_messages_total{level=\"WARN\"} %d\n", after.Messages["WARN"]))
+	assert.Contains(t, rec.Body.String(), fmt.Sprintf("log_messages_dropped_total %d\n", after.Dropped))
+}
+
+func TestRecentLogs(t *testing.T) {
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: filepath.Join(t.TempDir(), "app.log"), RingSize: 50, MaxFieldLen: 32}))
+	defer Close()
+
+	log := slog.Default().WithGroup("req")
+	for i := range 200 {
+		log.Info(fmt.Sprintf("line %d", i), "n", i)
+	}
+	Info(strings.Repeat("x", 1<<20), "body", strings.Repeat("y", 1<<20))
+
+	records := RecentLogs()
+	require.Len(t, records, 50)
+	assert.Equal(t, "line 151", records[0].Message)
+	assert.Equal(t, int64(151), records[0].Attrs["req.n"])
+	assert.Equal(t, "line 199", records[48].Message)
+	assert.Less(t, len(records[49].Message), 64)
+	assert.Less(t, len(records[49].Attrs["body"].(string)), 64)
+
+	// 与输出相同地脱敏
+	type user struct {
+		Name  string
+		Token string `log:"redact"`
+	}
+	require.NoError(t, InitCfg(&Config{
+		Level:          "INFO",
+		Format:         "json",
+		Output:         filepath.Join(t.TempDir(), "app.log"),
+		RingSize:       10,
+		RedactKeys:     []string{"password"},
+		RedactPatterns: []string{`Bearer [A-Za-z0-9]+`},
+	}))
+	slog.With("password", "hunter2").WithGroup("req").Info("token Bearer abc123", "password", "hunter2", "user", user{Name: "bob", Token: "secret"})
+	records = RecentLogs()
+	require.Len(t, records, 1)
+	assert.Equal(t, "token ***", records[0].Message)
+	assert.Equal(t, "***", records[0].Attrs["password"])
+	assert.Equal(t, "***", records[0].Attrs["req.password"])
+	assert.NotContains(t, fmt.Sprint(records[0].Attrs), "secret")
+	assert.NotContains(t, fmt.Sprint(records[0].Attrs), "hunter2")
+
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: filepath.Join(t.TempDir(), "app.log")}))
+	assert.Nil(t, RecentLogs())
+}
+
+func TestFilter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{
+		Level:  "INFO",
+		Format: "json",
+		Output: path,
+		Filter: func(level slog.Level, msg string, attrs map[string]any) bool {
+			return attrs["client"] != "bot" && attrs["req.client"] != "bot"
+		},
+		RingSize: 100,
+	}))
+	defer Close()
+
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			Info("from bot", "client", "bot", "n", i)
+			slog.With("client", "bot").Warn("bot via with")
+			slog.Default().WithGroup("req").Info("bot in group", "client", "bot")
+			Info("from browser", "client", "browser")
+		}()
+	}
+	wg.Wait()
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	output := string(data)
+	assert.NotContains(t, output, "bot")
+	assert.Equal(t, 10, strings.Count(output, "from browser"))
+
+	// 被丢弃的记录也不出现在 RecentLogs 中
+	records := RecentLogs()
+	require.Len(t, records, 10)
+	for _, rec := range records {
+		assert.Equal(t, "from browser", rec.Message)
+	}
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: "discard"}))
+}
+
+func TestWatchSignals(t *testing.T) {
+	if len(reloadSignals) == 0 {
+		t.Skip("SIGHUP is not supported on this platform")
+	}
+	path := filepath.Join(t.TempDir(), "app.json")
+	t.Setenv("LOG_LEVEL", "INFO")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_OUTPUT", path)
+	require.NoError(t, InitEnv())
+	defer Close()
+
+	derived := slog.With("component", "worker")
+	derived.Debug("before reload")
+	WatchSignals()
+
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+
+	t.Setenv("LOG_LEVEL", "DEBUG")
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	require.Eventually(t, func() bool { return GetLevel() == "DEBUG" }, 2*time.Second, 10*time.Millisecond)
+	derived.Debug("after reload")
+
+	// 无效配置不生效，原配置保持不变
+	t.Setenv("LOG_FORMAT", "bogus")
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		data, _ := os.ReadFile(path)
+		return strings.Contains(string(data), "reload log config failed")
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "DEBUG", GetLevel())
+	require.NoError(t, Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	output := string(data)
+	assert.NotContains(t, output, "before reload")
+	assert.Contains(t, output, `"msg":"after reload"`)
+	assert.Contains(t, output, `"component":"worker"`)
+	assert.Contains(t, output, "log config reloaded")
+}
+
+// rotatingBuffer 模拟 lumberjack.Logger 等自行轮转的 Writer
+type rotatingBuffer struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	rotated atomic.Int32
+	synced  atomic.Int32
+}
+
+func (w *rotatingBuffer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *rotatingBuffer) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func (w *rotatingBuffer) Rotate() error {
+	w.rotated.Add(1)
+	return nil
+}
+
+func (w *rotatingBuffer) Sync() error {
+	w.synced.Add(1)
+	return nil
+}
+
+func TestWriterRotate(t *testing.T) {
+	if len(reloadSignals) == 0 {
+		t.Skip("SIGHUP is not supported on this platform")
+	}
+	t.Setenv("LOG_LEVEL", "INFO")
+	t.Setenv("LOG_FORMAT", "json")
+	t.Setenv("LOG_OUTPUT", "stdout")
+	require.NoError(t, InitEnv())
+	defer Close()
+	w := &rotatingBuffer{}
+	require.NoError(t, SetOutput(w))
+	WatchSignals()
+
+	process, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, process.Signal(syscall.SIGHUP))
+	require.Eventually(t, func() bool {
+		return strings.Contains(w.String(), "log config reloaded")
+	}, 2*time.Second, 10*time.Millisecond, "output still goes to the Writer after reload")
+	assert.EqualValues(t, 1, w.rotated.Load())
+
+	require.NoError(t, Sync())
+	assert.EqualValues(t, 1, w.synced.Load())
+	require.NoError(t, Close())
+	assert.EqualValues(t, 2, w.synced.Load(), "Close flushes but does not close the Writer")
+
+	// Async 时等待队列写完再轮转
+	var async rotatingBuffer
+	logger, closer, err := newLogger(&Config{Writer: &async, Format: "json", Async: true}, slog.LevelInfo)
+	require.NoError(t, err)
+	logger.Info("queued")
+	require.NoError(t, rotateResource(closer))
+	assert.Contains(t, async.String(), "queued")
+	assert.EqualValues(t, 1, async.rotated.Load())
+	require.NoError(t, closer.Close())
+	assert.Nil(t, newWriterResource(os.Stdout))
+	assert.Nil(t, newWriterResource(&bytes.Buffer{}))
+}
+
+func TestSetOutputAndFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.json")
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: path, DefaultAttrs: map[string]string{"service": "api"}}))
+	defer Close()
+	require.NoError(t, SetLevel("DEBUG"))
+
+	derived := slog.With("component", "worker")
+	derived.Info("to file")
+
+	var first, second syncBuffer
+	require.NoError(t, SetOutput(&first))
+	derived.Debug("to first")
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 50 {
+				Info("concurrent")
+			}
+		}()
+	}
+	require.NoError(t, SetOutput(&second))
+	wg.Wait()
+	derived.Info("to second")
+
+	require.NoError(t, SetFormat("logfmt"))
+	derived.Info("as logfmt")
+	assert.Error(t, SetFormat("xml"))
+	derived.Info("still logfmt")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "to file")
+	assert.NotContains(t, string(data), "to first")
+	assert.Contains(t, first.String(), `"msg":"to first"`)
+	assert.Contains(t, first.String(), `"service":"api"`)
+	assert.NotContains(t, first.String(), "to second")
+	assert.Contains(t, second.String(), `"msg":"to second","service":"api"`)
+	assert.Contains(t, second.String(), "msg=\"as logfmt\"")
+	assert.Contains(t, second.String(), "msg=\"still logfmt\" service=api component=worker")
+	assert.Equal(t, 200, strings.Count(first.String()+second.String(), "concurrent"))
+	for _, line := range strings.Split(strings.TrimSpace(first.String()+second.String()), "\n") {
+		assert.True(t, strings.HasPrefix(line, "{") || strings.HasPrefix(line, "time="), "corrupted line: %q", line)
+	}
+	assert.Equal(t, "DEBUG", GetLevel())
+}
+
+func TestConfigWriter(t *testing.T) {
+	var buf bytes.Buffer
+	logger, closer, err := NewWithCloser(&Config{
+		Level:      "DEBUG",
+		Format:     "logfmt,json",
+		Output:     "/nonexistent/dir/app.log,stderr",
+		Writer:     &buf,
+		TimeFormat: "rfc3339",
+		Timezone:   "UTC",
+	})
+	require.NoError(t, err)
+	assert.Nil(t, closer, "caller-owned writer is not closed")
+
+	logger.Debug("captured", "k", "v")
+	assert.Regexp(t, `^time=\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}Z level=debug msg=captured k=v\n$`, buf.String())
+	_, err = os.Stat("/nonexistent/dir/app.log")
+	assert.True(t, os.IsNotExist(err), "Output is ignored when Writer is set")
+}
+
+func TestGroupAndWith(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"json", []string{`"svc":"api"`, `"db":{"rows":5,"table":"users"}`}},
+		{"text", []string{"svc=api", "db.rows=5", "db.table=users"}},
+		// 非终端输出时 color 格式不着色，分组展开为带前缀的键
+		{"color", []string{`"svc":"api"`, `"db.rows":"5"`, `"db.table":"users"`}},
+		{"logfmt", []string{"svc=api", "db.rows=5", "db.table=users"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf syncBuffer
+			require.NoError(t, InitCfg(&Config{Level: "INFO", Format: tt.format, Output: "stdout"}))
+			require.NoError(t, SetOutput(&buf))
+			defer Close()
+
+			// slog.SetDefault 被替换后依然使用本包的配置
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.DiscardHandler))
+			defer slog.SetDefault(previous)
+
+			With("svc", "api").Info("start")
+			Group("db").With("table", "users").Info("query", "rows", 5)
+
+			out := buf.String()
+			lines := strings.Split(strings.TrimSpace(out), "\n")
+			require.Len(t, lines, 2, out)
+			assert.Contains(t, lines[0], tt.want[0])
+			for _, want := range tt.want[1:] {
+				assert.Contains(t, lines[1], want)
+			}
+		})
+	}
+}
+
+func TestSortKeys(t *testing.T) {
+	timeRe := regexp.MustCompile(`time=\S+ |"time":"[^"]*",`)
+	for _, format := range []string{"text", "color"} {
+		t.Run(format, func(t *testing.T) {
+			var buf syncBuffer
+			require.NoError(t, InitCfg(&Config{Level: "INFO", Format: format, Output: "stdout", TimeFormat: "unix", SortKeys: true}))
+			require.NoError(t, SetOutput(&buf))
+			defer Close()
+
+			With("zone", "b", "app", "x").WithGroup("req").Info("done", "z", 1, "a", 2, slog.Group("m", "y", 1, "b", 2))
+			With("app", "x", "zone", "b").WithGroup("req").Info("done", slog.Group("m", "b", 2, "y", 1), "a", 2, "z", 1)
+
+			lines := strings.Split(strings.TrimSpace(timeRe.ReplaceAllString(buf.String(), "")), "\n")
+			require.Len(t, lines, 2)
+			assert.Equal(t, lines[0], lines[1])
+			if format == "text" {
+				assert.Equal(t, "level=INFO msg=done app=x req.a=2 req.m.b=2 req.m.y=1 req.z=1 zone=b", lines[0])
+			}
+		})
+	}
+}
+
+func TestAlignColumns(t *testing.T) {
+	tests := []struct {
+		format string
+		msgKey string
+	}{
+		{"text", "msg="},
+		{"color", `"msg"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf syncBuffer
+			require.NoError(t, InitCfg(&Config{Level: "DEBUG", Format: tt.format, Output: "stdout", AlignColumns: true}))
+			require.NoError(t, SetOutput(&buf))
+			defer Close()
+
+			Info("short", "k", 1)
+			Warn("a much longer message than the others", "k", 2)
+			Error("mid length message")
+			Debug("x")
+			With("component", "db").Info("grouped")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			require.Len(t, lines, 5)
+			col := strings.Index(lines[0], tt.msgKey)
+			require.Positive(t, col)
+			for _, line := range lines {
+				assert.Equal(t, col, strings.Index(line, tt.msgKey), "line: %q", line)
+			}
+			if tt.format == "text" {
+				assert.Contains(t, lines[0], "level=INFO  msg=short k=1")
+				assert.Contains(t, lines[4], "msg=grouped component=db")
+			}
+		})
+	}
+}
+
+func TestConfigReplaceAttr(t *testing.T) {
+	var seen []string
+	hook := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 {
+			return a
+		}
+		switch a.Key {
+		case slog.LevelKey:
+			return slog.String("severity", a.Value.String())
+		case slog.MessageKey:
+			return slog.Attr{Key: "message", Value: a.Value}
+		case "password", slog.TimeKey:
+			seen = append(seen, a.Key+"="+a.Value.Kind().String()+":"+a.Value.String())
+		case "internal":
+			return slog.Attr{}
+		}
+		return a
+	}
+
+	for _, format := range []string{"json", "text", "logfmt"} {
+		seen = nil
+		var buf bytes.Buffer
+		logger, err := New(&Config{
+			Format:      format,
+			Writer:      &buf,
+			TimeFormat:  "2006-01-02",
+			RedactKeys:  []string{"password"},
+			ReplaceAttr: hook,
+		})
+		require.NoError(t, err, format)
+		logger.Warn("login", "password", "hunter2", "internal", "x", "user", "alice")
+
+		out := buf.String()
+		assert.NotContains(t, out, "hunter2", format)
+		assert.NotContains(t, out, "internal", format)
+		assert.NotContains(t, out, "level", format)
+		assert.Contains(t, out, "severity", format)
+		assert.Contains(t, strings.ToUpper(out), "WARN", format)
+		assert.Contains(t, out, "message", format)
+		assert.Contains(t, out, "alice", format)
+		// 用户函数在内置处理之后调用：时间已格式化，密码已脱敏
+		assert.Equal(t, []string{"time=String:" + time.Now().In(loadTimezone("")).Format("2006-01-02"), "password=String:***"}, seen, format)
+	}
+}
+
+func TestKeyMap(t *testing.T) {
+	keyMap := map[string]string{"time": "@timestamp", "msg": "message", "level": "severity", "source": "caller"}
+
+	for _, format := range []string{"json", "ndjson"} {
+		var buf bytes.Buffer
+		logger, err := New(&Config{Format: format, Writer: &buf, AddSource: true, KeyMap: keyMap})
+		require.NoError(t, err, format)
+		logger.WithGroup("req").Info("hello", "time", "nested", "user", "alice")
+
+		var m map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &m), format)
+		assert.Equal(t, "hello", m["message"], format)
+		assert.Equal(t, "INFO", m["severity"], format)
+		assert.NotEmpty(t, m["@timestamp"], format)
+		assert.NotEmpty(t, m["caller"], format)
+		for _, key := range []string{"time", "msg", "level", "source"} {
+			assert.NotContains(t, m, key, format)
+		}
+		// 分组内的属性不会被重命名
+		assert.Equal(t, map[string]any{"time": "nested", "user": "alice"}, m["req"], format)
+	}
+
+	// ndjson 中重命名后的内置字段仍排在最前
+	var buf bytes.Buffer
+	logger, err := New(&Config{Format: "ndjson", Writer: &buf, KeyMap: keyMap})
+	require.NoError(t, err)
+	logger.Info("hello", "a", 1)
+	assert.Regexp(t, `^\{"@timestamp":"[^"]+","severity":"INFO","message":"hello","a":1\}`, buf.String())
+
+	// color 格式中重命名后的内置字段保持原来的位置
+	buf.Reset()
+	logger, err = New(&Config{Format: "color", Writer: &buf, KeyMap: keyMap, AlignColumns: true})
+	require.NoError(t, err)
+	logger.Info("hello", "a", 1)
+	assert.Regexp(t, `^\{"@timestamp":"[^"]+","severity":"INFO", +"message":"hello","a":"1"\}`, buf.String())
+
+	require.ErrorContains(t, (&Config{KeyMap: map[string]string{"msg": ""}}).Validate(), "invalid key map entry")
+
+	t.Setenv("LOG_KEY_MAP", "time=@timestamp,msg=message")
+	cfg := defaultConfig()
+	applyEnv(cfg)
+	assert.Equal(t, map[string]string{"time": "@timestamp", "msg": "message"}, cfg.KeyMap)
+}
+
+func TestRedactPatterns(t *testing.T) {
+	var buf syncBuffer
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "json", Output: "stdout", RedactPatterns: []string{`Bearer [A-Za-z0-9]+`, `\d{4}-\d{4}`}}))
+	require.NoError(t, SetOutput(&buf))
+	defer Close()
+
+	Info("auth header Bearer abc123XYZ rejected", "url", "https://api/x?token=Bearer qq9", "card", "1234-5678", "n", 1234)
+
+	out := buf.String()
+	assert.Contains(t, out, `"msg":"auth header *** rejected"`)
+	assert.Contains(t, out, `"url":"https://api/x?token=***"`)
+	assert.Contains(t, out, `"card":"***"`)
+	assert.Contains(t, out, `"n":1234`)
+	assert.NotContains(t, out, "abc123XYZ")
+
+	err := InitCfg(&Config{Level: "INFO", Format: "json", Output: "stdout", RedactPatterns: []string{`(unclosed`}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid redact pattern")
+}
+
+func TestRedactStructTags(t *testing.T) {
+	type Address struct {
+		City string
+		Zip  string `log:"redact"`
+	}
+	type User struct {
+		Name     string `json:"name"`
+		Password string `log:"redact"`
+		Token    string `log:"-"`
+		Age      int
+		Address  *Address
+	}
+	type Plain struct {
+		A, B int
+	}
+	user := User{Name: "alice", Password: "s3cret", Token: "tok", Age: 30, Address: &Address{City: "sh", Zip: "200000"}}
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{"json", []string{`"user":{"Address":{"City":"sh","Zip":"***"},"Age":30,"Password":"***","name":"alice"}`, `"plain":{"A":1,"B":2}`}},
+		{"text", []string{"user.name=alice user.Password=*** user.Age=30 user.Address.City=sh user.Address.Zip=***", `plain="{A:1 B:2}"`}},
+		{"logfmt", []string{"user.name=alice user.Password=*** user.Age=30", "user.Address.Zip=***"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf syncBuffer
+			require.NoError(t, InitCfg(&Config{Level: "INFO", Format: tt.format, Output: "stdout"}))
+			require.NoError(t, SetOutput(&buf))
+			defer Close()
+
+			Info("login", "user", &user, "plain", Plain{A: 1, B: 2})
+
+			out := buf.String()
+			for _, want := range tt.want {
+				assert.Contains(t, out, want)
+			}
+			assert.NotContains(t, out, "s3cret")
+			assert.NotContains(t, out, "tok")
+			assert.NotContains(t, out, "200000")
+		})
+	}
+}
+
+func TestFilePerm(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix permissions only")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a", "b", "c", "app.log")
+	logger, closer, err := NewWithCloser(&Config{Level: "INFO", Format: "json", Output: path, FilePerm: "0640", DirPerm: "0750"})
+	require.NoError(t, err)
+	logger.Info("created")
+	require.NoError(t, closer.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+	for _, sub := range []string{"a", "a/b", "a/b/c"} {
+		info, err := os.Stat(filepath.Join(dir, sub))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0750), info.Mode().Perm(), sub)
+	}
+
+	// 轮转写入器使用同样的权限
+	rotated := filepath.Join(dir, "rotated", "app.log")
+	_, closer, err = NewWithCloser(&Config{Format: "json", Output: rotated, MaxSizeMB: 1, FilePerm: "0600"})
+	require.NoError(t, err)
+	require.NoError(t, closer.Close())
+	info, err = os.Stat(rotated)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	assert.ErrorContains(t, (&Config{FilePerm: "rw-r--r--"}).Validate(), "invalid file perm")
+	assert.ErrorContains(t, (&Config{DirPerm: "1777"}).Validate(), "invalid dir perm")
+}
+
+func TestEvery(t *testing.T) {
+	// 计数是包级别的，清空以免 -count=N 时受上一次运行的影响
+	everyCounters.Clear()
+	t.Cleanup(func() { everyCounters.Clear() })
+
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	for i := 0; i < 1000; i++ {
+		Every(100, "progress", "i", i)
+	}
+	// 另一个调用位置独立计数
+	for i := 0; i < 3; i++ {
+		Every(2, "other")
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 11)
+	for i, line := range lines[:10] {
+		assert.Contains(t, line, fmt.Sprintf("msg=progress i=%d count=%d", (i+1)*100-1, (i+1)*100))
+	}
+	assert.Contains(t, lines[10], "msg=other count=2")
+}
+
+func TestLogOnce(t *testing.T) {
+	resetOnce := func() {
+		onceLogged.Clear()
+		onceKeys.Store(0)
+	}
+	resetOnce()
+	t.Cleanup(resetOnce)
+
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	for i := 0; i < 5; i++ {
+		LogOnce(slog.LevelWarn, "deprecated config used", "i", i)
+		LogOnce(slog.LevelDebug, "disabled") // 级别未启用，不输出也不计入
+	}
+	LogOnce(slog.LevelWarn, "deprecated config used") // 不同调用位置
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "level=WARN msg=\"deprecated config used\" i=0")
+	assert.NotContains(t, buf.String(), "disabled")
+
+	// 动态消息：键数达到上限后只按调用位置去重，不再增长
+	resetOnce()
+	buf.Reset()
+	for i := 0; i < 2*onceMaxKeys; i++ {
+		LogOnce(slog.LevelWarn, fmt.Sprintf("request %d failed", i))
+	}
+	assert.Equal(t, onceMaxKeys+1, strings.Count(buf.String(), "\n"))
+	assert.EqualValues(t, onceMaxKeys+1, onceKeys.Load())
+}
+
+type tenantKey struct{}
+
+func TestRegisterContextExtractor(t *testing.T) {
+	previous := contextExtractors.Load()
+	defer contextExtractors.Store(previous)
+	contextExtractors.Store(nil)
+
+	var buf syncBuffer
+	require.NoError(t, InitCfg(&Config{Level: "INFO", Format: "logfmt", Output: "stdout"}))
+	require.NoError(t, SetOutput(&buf))
+	defer Close()
+
+	// 初始化之后注册同样生效，多个提取函数依次添加属性
+	RegisterContextExtractor(func(ctx context.Context) []any {
+		if id, ok := ctx.Value(tenantKey{}).(string); ok {
+			return []any{"tenant_id", id}
+		}
+		return nil
+	})
+	RegisterContextExtractor(func(ctx context.Context) []any { return []any{"user_id", 7} })
+	RegisterContextExtractor(func(ctx context.Context) []any { return []any{} })
+	RegisterContextExtractor(nil)
+
+	ctx := context.WithValue(context.Background(), tenantKey{}, "acme")
+	InfoContext(ctx, "x", "k", 1)
+	Info("no tenant")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "msg=x k=1 tenant_id=acme user_id=7")
+	assert.NotContains(t, lines[1], "tenant_id")
+	assert.Contains(t, lines[1], "user_id=7")
+}
+
+func TestBrokenPipe(t *testing.T) {
+	// 子进程：stdout 的读端已关闭，持续写入日志，不应被 SIGPIPE 终止
+	if os.Getenv("LOGGER_TEST_SIGPIPE") == "1" {
+		logger, err := New(&Config{Level: "INFO", Format: "json", Output: "stdout"})
+		require.NoError(t, err)
+		for i := 0; i < 100; i++ {
+			logger.Info("to closed pipe", "i", i)
+		}
+		fmt.Fprintln(os.Stderr, "still running")
+		return
+	}
+
+	stdout, stderr := os.Stdout, os.Stderr
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	defer w.Close()
+	diag, err := os.CreateTemp(t.TempDir(), "stderr")
+	require.NoError(t, err)
+	defer diag.Close()
+	os.Stdout, os.Stderr = w, diag
+
+	logger, err := New(&Config{Level: "INFO", Format: "json", Output: "stdout"})
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.NotPanics(t, func() {
+		for i := 0; i < 3; i++ {
+			logger.Info("to closed pipe", "i", i)
+		}
+	})
+	require.NoError(t, w.Close())
+	assert.NotPanics(t, func() { logger.Info("to closed file") })
+
+	data, err := os.ReadFile(diag.Name())
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"), "diagnostic is printed once: %s", data)
+	assert.Contains(t, string(data), "logger: stdout: ")
+	assert.Contains(t, string(data), "discarding further logs")
+
+	if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+		return
+	}
+	pr, pw, err := os.Pipe()
+	require.NoError(t, err)
+	require.NoError(t, pr.Close())
+	var childErr bytes.Buffer
+	cmd := exec.Command(os.Args[0], "-test.run=^TestBrokenPipe$")
+	cmd.Env = append(os.Environ(), "LOGGER_TEST_SIGPIPE=1")
+	cmd.Stdout, cmd.Stderr = pw, &childErr
+	err = cmd.Run()
+	require.NoError(t, pw.Close())
+	require.NoError(t, err, "child killed by SIGPIPE: %s", childErr.String())
+	assert.Contains(t, childErr.String(), "still running")
+	assert.Contains(t, childErr.String(), "logger: stdout: ")
+}
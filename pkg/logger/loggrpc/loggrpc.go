@@ -0,0 +1,111 @@
+// Package loggrpc 提供记录 gRPC 请求日志的服务端拦截器
+//
+// 与 [logger.Middleware] 对应：每个 RPC 结束后记录一条 "grpc request" 日志，
+// 包含 method、code 和 duration，级别由状态码决定（见 [CodeToLevel]）；
+// 服务实现可通过 [logger.FromContext] 获取带 request_id 的 logger：
+//
+//	srv := grpc.NewServer(
+//	    grpc.UnaryInterceptor(loggrpc.UnaryServerInterceptor()),
+//	    grpc.StreamInterceptor(loggrpc.StreamServerInterceptor()),
+//	)
+package loggrpc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// requestIDKey 传递请求 ID 的 metadata 键，请求中已携带时沿用，否则自动生成
+const requestIDKey = "x-request-id"
+
+// UnaryServerInterceptor 返回记录一元 RPC 日志的拦截器
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		start := time.Now()
+		ctx = withRequestID(ctx)
+		resp, err := handler(ctx, req)
+		logRPC(ctx, info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor 返回记录流式 RPC 日志的拦截器，流结束时记录一条日志
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx := withRequestID(ss.Context())
+		err := handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+		logRPC(ctx, info.FullMethod, start, err)
+		return err
+	}
+}
+
+// CodeToLevel 返回状态码对应的日志级别
+//
+// 客户端引起的错误（NotFound、InvalidArgument 等）为 INFO，需要关注但不一定是服务端问题的
+// （DeadlineExceeded、PermissionDenied、ResourceExhausted 等）为 WARN，服务端错误（Internal、Unavailable 等）为 ERROR
+func CodeToLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		return slog.LevelInfo
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return slog.LevelWarn
+	default: // Unknown、Unimplemented、Internal、Unavailable、DataLoss
+		return slog.LevelError
+	}
+}
+
+// logRPC 记录一次 RPC 的结果
+func logRPC(ctx context.Context, method string, start time.Time, err error) {
+	code := status.Code(err)
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("code", code.String()),
+		slog.String("duration", logger.FormatDuration(time.Since(start))),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	logger.FromContext(ctx).LogAttrs(ctx, CodeToLevel(code), "grpc request", attrs...)
+}
+
+// withRequestID 将带 request_id 的 logger 存入 context
+func withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDKey); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = newRequestID()
+	}
+	return logger.WithRequestID(ctx, requestID)
+}
+
+// newRequestID 生成 16 位十六进制的随机请求 ID
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// serverStream 替换 Context 以传递带 request_id 的 logger
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context 实现 grpc.ServerStream 接口
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,128 @@
+package loggrpc
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger"
+	"github.com/lwmacct/251125-go-mod-logger/pkg/logger/logtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// healthServer 按请求的服务名返回不同的状态码，Watch 未实现
+type healthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (s *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	logger.FromContext(ctx).Info("checking", "service", req.Service)
+	switch req.Service {
+	case "missing":
+		return nil, status.Error(codes.NotFound, "unknown service")
+	case "broken":
+		return nil, status.Error(codes.Internal, "database unavailable")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func newTestClient(t *testing.T) healthpb.HealthClient {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor()),
+		grpc.StreamInterceptor(StreamServerInterceptor()),
+	)
+	healthpb.RegisterHealthServer(srv, &healthServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	log, h := logtest.New()
+	slog.SetDefault(log)
+	client := newTestClient(t)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestIDKey, "req-42")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	rec, ok := h.Find(slog.LevelInfo, "grpc request")
+	require.True(t, ok)
+	assert.Equal(t, "/grpc.health.v1.Health/Check", rec.Attrs["method"])
+	assert.Equal(t, "OK", rec.Attrs["code"])
+	assert.NotEmpty(t, rec.Attrs["duration"])
+	assert.Equal(t, "req-42", rec.Attrs["request_id"])
+	inner, ok := h.Find(slog.LevelInfo, "checking")
+	require.True(t, ok)
+	assert.Equal(t, "req-42", inner.Attrs["request_id"], "handlers should get the request logger from the context")
+
+	h.Reset()
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	rec, ok = h.Find(slog.LevelInfo, "grpc request")
+	require.True(t, ok, "NotFound should be logged at INFO")
+	assert.Equal(t, "NotFound", rec.Attrs["code"])
+	assert.Len(t, rec.Attrs["request_id"], 16)
+
+	h.Reset()
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "broken"})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	rec, ok = h.Find(slog.LevelError, "grpc request")
+	require.True(t, ok, "Internal should be logged at ERROR")
+	assert.Equal(t, "/grpc.health.v1.Health/Check", rec.Attrs["method"])
+	assert.Equal(t, "Internal", rec.Attrs["code"])
+	assert.Equal(t, "database unavailable", rec.Attrs["error"])
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	log, h := logtest.New()
+	slog.SetDefault(log)
+	client := newTestClient(t)
+
+	stream, err := client.Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	require.NotErrorIs(t, err, io.EOF)
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	rec, ok := h.Find(slog.LevelError, "grpc request")
+	require.True(t, ok)
+	assert.Equal(t, "/grpc.health.v1.Health/Watch", rec.Attrs["method"])
+	assert.Equal(t, "Unimplemented", rec.Attrs["code"])
+}
+
+func TestCodeToLevel(t *testing.T) {
+	tests := []struct {
+		code codes.Code
+		want slog.Level
+	}{
+		{codes.OK, slog.LevelInfo},
+		{codes.NotFound, slog.LevelInfo},
+		{codes.InvalidArgument, slog.LevelInfo},
+		{codes.DeadlineExceeded, slog.LevelWarn},
+		{codes.PermissionDenied, slog.LevelWarn},
+		{codes.Internal, slog.LevelError},
+		{codes.Unavailable, slog.LevelError},
+		{codes.Unknown, slog.LevelError},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, CodeToLevel(tt.code), tt.code.String())
+	}
+}
@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+)
+
+// NewLogr 返回基于全局 logger 的 logr.Logger，用于 controller-runtime 等接受 logr 的库
+//
+// logr 的 V 级别映射为 slog 级别：V(0) 为 INFO，V(1) 为 DEBUG，V(2) 及以上为 TRACE；
+// Error 记录为 ERROR 级别并附带 error 属性。WithName 的名称以 "/" 连接，记录为 logger 属性。
+//
+//	ctrl.SetLogger(logger.NewLogr())
+func NewLogr() logr.Logger {
+	return logr.New(&logrSink{handler: slog.Default().Handler()})
+}
+
+// logrSink 将 logr 调用转换为 slog 记录的 logr.LogSink 实现
+type logrSink struct {
+	handler slog.Handler
+	name    string
+	// callDepth logr 在调用方与 LogSink 之间增加的栈帧数
+	callDepth int
+}
+
+// logrLevel 将 logr 的 V 级别转换为 slog 级别
+func logrLevel(v int) slog.Level {
+	level := slog.LevelInfo - slog.Level(4*v)
+	return max(level, LevelTrace)
+}
+
+// Init 实现 logr.LogSink 接口
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.callDepth = info.CallDepth
+}
+
+// Enabled 实现 logr.LogSink 接口
+func (s *logrSink) Enabled(level int) bool {
+	return s.handler.Enabled(context.Background(), logrLevel(level))
+}
+
+// Info 实现 logr.LogSink 接口
+func (s *logrSink) Info(level int, msg string, keysAndValues ...any) {
+	s.log(logrLevel(level), msg, keysAndValues)
+}
+
+// Error 实现 logr.LogSink 接口
+func (s *logrSink) Error(err error, msg string, keysAndValues ...any) {
+	s.log(slog.LevelError, msg, append([]any{"error", err}, keysAndValues...))
+}
+
+// log 构造 slog 记录，调用位置指向使用 logr 的代码
+func (s *logrSink) log(level slog.Level, msg string, args []any) {
+	ctx := context.Background()
+	if !s.handler.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// 跳过 runtime.Callers、log、Info/Error 以及 logr 自身的栈帧
+	runtime.Callers(3+s.callDepth, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	_ = s.handler.Handle(ctx, r)
+}
+
+// WithValues 实现 logr.LogSink 接口
+func (s *logrSink) WithValues(keysAndValues ...any) logr.LogSink {
+	s2 := *s
+	s2.handler = slog.New(s.handler).With(keysAndValues...).Handler()
+	return &s2
+}
+
+// WithName 实现 logr.LogSink 接口
+func (s *logrSink) WithName(name string) logr.LogSink {
+	s2 := *s
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	s2.name = name
+	s2.handler = s.handler.WithAttrs([]slog.Attr{slog.String(moduleKey, name)})
+	return &s2
+}
+
+// WithCallDepth 实现 logr.CallDepthLogSink 接口
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	s2 := *s
+	s2.callDepth += depth
+	return &s2
+}
@@ -0,0 +1,200 @@
+// Package logtest 提供用于测试的日志捕获工具
+//
+// 使用 [NewCaptureHandler] 创建的 handler 不输出任何内容，而是将记录保存在内存中，
+// 便于在测试中断言代码是否输出了预期的日志：
+//
+//	log, h := logtest.New()
+//	svc := NewService(log)
+//	svc.Do()
+//	logtest.AssertLogged(t, h, slog.LevelError, "连接失败")
+package logtest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loggerKey 具名 logger 的属性键，与 logger.Named 相同
+const loggerKey = "logger"
+
+// Record 捕获到的一条日志记录
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// Attrs 记录的所有属性（包括 With 添加的），分组内的属性以 "group.key" 形式平铺
+	Attrs map[string]any
+	// Logger 顶级 logger 属性的值（见 logger.Named），普通 logger 为空
+	Logger string
+}
+
+// captureState 同一 handler 及其派生 handler 共享的记录存储
+type captureState struct {
+	mu      sync.Mutex
+	records []Record
+}
+
+// Handler 捕获日志记录的 slog.Handler，可安全地并发使用
+type Handler struct {
+	state  *captureState
+	level  slog.Leveler
+	groups []string
+	attrs  map[string]any // With 添加的属性（已平铺）
+}
+
+// NewCaptureHandler 创建捕获所有级别日志的 handler
+func NewCaptureHandler() *Handler {
+	return &Handler{
+		state: &captureState{},
+		level: slog.Level(-1 << 10), // 捕获所有级别
+		attrs: map[string]any{},
+	}
+}
+
+// New 创建捕获 handler 以及使用它的 logger
+func New() (*slog.Logger, *Handler) {
+	h := NewCaptureHandler()
+	return slog.New(h), h
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	attrs := make(map[string]any, len(h.attrs)+r.NumAttrs())
+	for k, v := range h.attrs {
+		attrs[k] = v
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		flatten(attrs, h.prefix(), a)
+		return true
+	})
+
+	name, _ := attrs[loggerKey].(string)
+
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	h.state.records = append(h.state.records, Record{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   attrs,
+		Logger:  name,
+	})
+	return nil
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newAttrs := make(map[string]any, len(h.attrs)+len(attrs))
+	for k, v := range h.attrs {
+		newAttrs[k] = v
+	}
+	for _, a := range attrs {
+		flatten(newAttrs, h.prefix(), a)
+	}
+	return &Handler{state: h.state, level: h.level, groups: h.groups, attrs: newAttrs}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(h.groups[:len(h.groups):len(h.groups)], name)
+	return &Handler{state: h.state, level: h.level, groups: groups, attrs: h.attrs}
+}
+
+// Records 返回目前捕获的所有记录的副本，按记录顺序排列
+func (h *Handler) Records() []Record {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	return append([]Record(nil), h.state.records...)
+}
+
+// Reset 清空已捕获的记录
+func (h *Handler) Reset() {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	h.state.records = nil
+}
+
+// Find 返回第一条级别相同且消息包含 msgSubstring 的记录
+func (h *Handler) Find(level slog.Level, msgSubstring string) (Record, bool) {
+	for _, r := range h.Records() {
+		if r.Level == level && strings.Contains(r.Message, msgSubstring) {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
+
+// AssertLogged 断言 h 捕获过级别为 level 且消息包含 msgSubstring 的记录
+//
+// 断言失败时通过 t.Errorf 报告，并列出已捕获的记录，返回是否成功
+func AssertLogged(t testing.TB, h *Handler, level slog.Level, msgSubstring string) bool {
+	t.Helper()
+	if _, ok := h.Find(level, msgSubstring); ok {
+		return true
+	}
+	t.Errorf("expected %s log containing %q, got:\n%s", level, msgSubstring, h.dump())
+	return false
+}
+
+// AssertNotLogged 断言 h 没有捕获级别为 level 且消息包含 msgSubstring 的记录
+func AssertNotLogged(t testing.TB, h *Handler, level slog.Level, msgSubstring string) bool {
+	t.Helper()
+	if r, ok := h.Find(level, msgSubstring); ok {
+		t.Errorf("unexpected %s log %q with attrs %v", level, r.Message, r.Attrs)
+		return false
+	}
+	return true
+}
+
+// dump 格式化已捕获的记录，用于断言失败时的提示
+func (h *Handler) dump() string {
+	records := h.Records()
+	if len(records) == 0 {
+		return "  (no records)"
+	}
+	var b strings.Builder
+	for _, r := range records {
+		fmt.Fprintf(&b, "  %s %q %v\n", r.Level, r.Message, r.Attrs)
+	}
+	return b.String()
+}
+
+// prefix 返回当前分组路径对应的键前缀
+func (h *Handler) prefix() string {
+	if len(h.groups) == 0 {
+		return ""
+	}
+	return strings.Join(h.groups, ".") + "."
+}
+
+// flatten 将属性平铺到 m 中，分组属性的键以 "." 连接
+func flatten(m map[string]any, prefix string, a slog.Attr) {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		groupPrefix := prefix
+		if a.Key != "" {
+			groupPrefix = prefix + a.Key + "."
+		}
+		for _, attr := range v.Group() {
+			flatten(m, groupPrefix, attr)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	m[prefix+a.Key] = v.Any()
+}
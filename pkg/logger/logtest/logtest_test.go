@@ -0,0 +1,88 @@
+package logtest
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCaptureHandler(t *testing.T) {
+	log, h := New()
+
+	log.With("service", "api").WithGroup("db").Error("query failed", "table", "users", "error", errors.New("timeout"))
+	log.Debug("debug message", slog.Group("request", "method", "GET"))
+
+	records := h.Records()
+	require.Len(t, records, 2)
+
+	assert.Equal(t, slog.LevelError, records[0].Level)
+	assert.Equal(t, "query failed", records[0].Message)
+	assert.Equal(t, "api", records[0].Attrs["service"])
+	assert.Equal(t, "users", records[0].Attrs["db.table"])
+	assert.EqualError(t, records[0].Attrs["db.error"].(error), "timeout")
+
+	assert.Equal(t, "GET", records[1].Attrs["request.method"])
+
+	AssertLogged(t, h, slog.LevelError, "query")
+	AssertNotLogged(t, h, slog.LevelWarn, "query")
+
+	h.Reset()
+	assert.Empty(t, h.Records())
+}
+
+func TestAssertLoggedFailure(t *testing.T) {
+	_, h := New()
+	fake := &testing.T{}
+	assert.False(t, AssertLogged(fake, h, slog.LevelInfo, "missing"))
+}
+
+func TestCaptureHandlerConcurrent(t *testing.T) {
+	log, h := New()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 10; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				log.Info(fmt.Sprintf("worker %d", g), "i", i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	assert.Len(t, h.Records(), 1000)
+}
+
+func TestSink(t *testing.T) {
+	sink, log := NewTestSink()
+
+	a := sink.Named("a")
+	b := log.With("logger", "b")
+	a.Error("connect failed", "host", "db")
+	b.Info("started")
+	log.Info("plain")
+	log.WithGroup("req").Info("grouped", "logger", "c")
+
+	require.Len(t, sink.Records(), 4)
+	assert.Equal(t, []string{"a", "b"}, sink.Loggers(), "grouped logger attrs are not logger names")
+	require.Len(t, sink.ByLogger("a"), 1)
+	assert.Equal(t, "db", sink.ByLogger("a")[0].Attrs["host"])
+	assert.Len(t, sink.ByLogger(""), 2)
+
+	AssertLoggedBy(t, sink, "a", slog.LevelError, "connect")
+	AssertLoggedBy(t, sink, "b", slog.LevelInfo, "started")
+	AssertNotLoggedBy(t, sink, "b", slog.LevelError, "connect")
+	AssertLogged(t, sink.Handler(), slog.LevelInfo, "plain")
+
+	fake := &testing.T{}
+	assert.False(t, AssertLoggedBy(fake, sink, "b", slog.LevelError, "connect"))
+
+	sink.Reset()
+	assert.Empty(t, sink.Records())
+}
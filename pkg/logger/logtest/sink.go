@@ -0,0 +1,105 @@
+package logtest
+
+import (
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// Sink 汇集多个具名 logger 记录的内存存储，按 logger 属性区分来源
+//
+// 适合测试由多个组件组成、每个组件使用各自具名 logger 的代码：
+//
+//	sink, log := logtest.NewTestSink()
+//	a := NewComponentA(sink.Named("a"))
+//	b := NewComponentB(log.With("logger", "b"))
+//	...
+//	logtest.AssertLoggedBy(t, sink, "a", slog.LevelError, "连接失败")
+//	logtest.AssertLoggedBy(t, sink, "b", slog.LevelInfo, "已启动")
+type Sink struct {
+	h *Handler
+}
+
+// NewTestSink 创建 Sink 以及写入它的 logger
+//
+// 通过 [Sink.Named] 或 logger.With("logger", name) 派生的 logger 写入同一个 Sink
+func NewTestSink() (*Sink, *slog.Logger) {
+	h := NewCaptureHandler()
+	return &Sink{h: h}, slog.New(h)
+}
+
+// Handler 返回 Sink 使用的捕获 handler，可配合 [AssertLogged] 等函数使用
+func (s *Sink) Handler() *Handler {
+	return s.h
+}
+
+// Named 返回写入 Sink 的具名 logger，带有 logger=name 属性
+func (s *Sink) Named(name string) *slog.Logger {
+	return slog.New(s.h).With(loggerKey, name)
+}
+
+// Records 返回目前汇集的所有记录的副本，按记录顺序排列
+func (s *Sink) Records() []Record {
+	return s.h.Records()
+}
+
+// ByLogger 返回指定 logger 的所有记录，name 为空时返回普通 logger 的记录
+func (s *Sink) ByLogger(name string) []Record {
+	var records []Record
+	for _, r := range s.h.Records() {
+		if r.Logger == name {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// Loggers 返回输出过记录的 logger 名称，按首次出现的顺序排列（不含普通 logger）
+func (s *Sink) Loggers() []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, r := range s.h.Records() {
+		if r.Logger != "" && !seen[r.Logger] {
+			seen[r.Logger] = true
+			names = append(names, r.Logger)
+		}
+	}
+	return names
+}
+
+// Find 返回指定 logger 的第一条级别相同且消息包含 msgSubstring 的记录
+func (s *Sink) Find(name string, level slog.Level, msgSubstring string) (Record, bool) {
+	for _, r := range s.ByLogger(name) {
+		if r.Level == level && strings.Contains(r.Message, msgSubstring) {
+			return r, true
+		}
+	}
+	return Record{}, false
+}
+
+// Reset 清空已汇集的记录
+func (s *Sink) Reset() {
+	s.h.Reset()
+}
+
+// AssertLoggedBy 断言名为 name 的 logger 输出过级别为 level 且消息包含 msgSubstring 的记录
+//
+// 断言失败时通过 t.Errorf 报告，并列出所有 logger 的记录，返回是否成功
+func AssertLoggedBy(t testing.TB, s *Sink, name string, level slog.Level, msgSubstring string) bool {
+	t.Helper()
+	if _, ok := s.Find(name, level, msgSubstring); ok {
+		return true
+	}
+	t.Errorf("expected %s log from logger %q containing %q, got:\n%s", level, name, msgSubstring, s.h.dump())
+	return false
+}
+
+// AssertNotLoggedBy 断言名为 name 的 logger 没有输出级别为 level 且消息包含 msgSubstring 的记录
+func AssertNotLoggedBy(t testing.TB, s *Sink, name string, level slog.Level, msgSubstring string) bool {
+	t.Helper()
+	if r, ok := s.Find(name, level, msgSubstring); ok {
+		t.Errorf("unexpected %s log from logger %q: %q with attrs %v", level, name, r.Message, r.Attrs)
+		return false
+	}
+	return true
+}
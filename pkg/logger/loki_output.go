@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lokiPushPath Loki 推送接口的默认路径
+const lokiPushPath = "/loki/api/v1/push"
+
+func init() {
+	registerOutput("loki", newLokiOutput)
+	registerOutput("loki+https", newLokiOutput)
+}
+
+// newLokiOutput 创建 Grafana Loki 输出："loki://host:3100"，使用 HTTPS 时为 "loki+https://host"
+//
+// 未指定路径时推送到 /loki/api/v1/push。DefaultAttrs 作为 stream 的标签，
+// 不再出现在日志行中；没有 DefaultAttrs 时使用程序名作为 job 标签。
+// 日志行的格式为 json（默认）、logfmt 或 text，其他格式一律使用 json。
+// 批量参数、队列长度和请求头（例如 X-Scope-OrgID）与 http 输出相同。
+func newLokiOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	scheme, address, _ := strings.Cut(target, "://")
+	if address == "" {
+		return nil, nil, fmt.Errorf("loki output requires a host, e.g. loki://localhost:3100: %q", target)
+	}
+	url := "http://" + address
+	if scheme == "loki+https" {
+		url = "https://" + address
+	}
+	if !strings.Contains(address, "/") {
+		url += lokiPushPath
+	}
+
+	labels := lokiLabels(cfg.DefaultAttrs)
+	stream, err := json.Marshal(labels)
+	if err != nil {
+		return nil, nil, err
+	}
+	sink := &lokiSink{
+		stream: stream,
+		http: &httpSink{
+			url:     url,
+			headers: cfg.Headers,
+			client:  &http.Client{Timeout: httpTimeout},
+			backoff: httpRetryBackoff,
+		},
+	}
+	writer := newBatchWriter(target, sink.send, cfg.BatchSize, cfg.FlushInterval, cfg.BufferSize)
+
+	switch format {
+	case "logfmt", "text":
+	default:
+		format = "json"
+	}
+	handler := createHandler(cfg, format, &lokiWriter{batch: writer, now: time.Now}, level)
+	return &lokiHandler{inner: handler, labels: cfg.DefaultAttrs}, writer, nil
+}
+
+// lokiLabels 将 DefaultAttrs 转换为 Loki 标签，标签名中的非法字符替换为下划线
+func lokiLabels(attrs map[string]string) map[string]string {
+	labels := make(map[string]string, len(attrs))
+	for key, value := range attrs {
+		labels[lokiLabelName(key)] = value
+	}
+	if len(labels) == 0 {
+		labels["job"] = filepath.Base(os.Args[0])
+	}
+	return labels
+}
+
+// lokiLabelName 将 key 转换为合法的标签名：[a-zA-Z_][a-zA-Z0-9_]*
+func lokiLabelName(key string) string {
+	b := []byte(key)
+	for i, c := range b {
+		if c != '_' && !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			b[i] = '_'
+		}
+	}
+	// 不能以数字开头
+	if len(b) == 0 || ('0' <= b[0] && b[0] <= '9') {
+		return "_" + string(b)
+	}
+	return string(b)
+}
+
+// lokiWriter 将一条格式化后的日志行编码为 Loki 的 [时间戳, 日志行] 并放入发送队列
+//
+// 时间戳取写入时间（纳秒），同步 handler 在记录时立即写入，与记录时间一致，
+// 且同一 stream 内保持递增，避免被 Loki 以乱序拒绝
+type lokiWriter struct {
+	batch *batchWriter
+	now   func() time.Time
+}
+
+// Write 实现 io.Writer 接口
+func (w *lokiWriter) Write(p []byte) (int, error) {
+	line, _ := json.Marshal(strings.TrimRight(string(p), "\n"))
+	entry := make([]byte, 0, len(line)+24)
+	entry = append(entry, `["`...)
+	entry = strconv.AppendInt(entry, w.now().UnixNano(), 10)
+	entry = append(entry, `",`...)
+	entry = append(entry, line...)
+	entry = append(entry, ']')
+	if _, err := w.batch.Write(entry); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// lokiSink 将一批日志行组装为推送请求：{"streams":[{"stream":{标签},"values":[...]}]}
+type lokiSink struct {
+	stream []byte // 序列化后的标签
+	http   *httpSink
+}
+
+// send 发送一批记录，每条记录为 lokiWriter 编码的 [时间戳, 日志行]
+func (s *lokiSink) send(batch [][]byte) error {
+	body := make([]byte, 0, 64+len(s.stream)+len(batch)*256)
+	body = append(body, `{"streams":[{"stream":`...)
+	body = append(body, s.stream...)
+	body = append(body, `,"values":[`...)
+	for i, entry := range batch {
+		if i > 0 {
+			body = append(body, ',')
+		}
+		body = append(body, entry...)
+	}
+	body = append(body, `]}]}`...)
+	return s.http.deliver(body)
+}
+
+// lokiHandler 从日志行中去掉已作为标签的 DefaultAttrs
+//
+// DefaultAttrs 由 newLogger 通过 With 添加，因此只需过滤分组之外的 WithAttrs
+type lokiHandler struct {
+	inner   slog.Handler
+	labels  map[string]string
+	grouped bool
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *lokiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *lokiHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *lokiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if !h.grouped {
+		kept := make([]slog.Attr, 0, len(attrs))
+		for _, a := range attrs {
+			if value, ok := h.labels[a.Key]; !ok || a.Value.String() != value {
+				kept = append(kept, a)
+			}
+		}
+		attrs = kept
+	}
+	return &lokiHandler{inner: h.inner.WithAttrs(attrs), labels: h.labels, grouped: h.grouped}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *lokiHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &lokiHandler{inner: h.inner.WithGroup(name), labels: h.labels, grouped: true}
+}
@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// droppedAttrsKey 超出 MaxAttrs 时记录被丢弃属性数量的键
+const droppedAttrsKey = "_dropped_attrs"
+
+// maxAttrsHandler 限制每条记录属性数量的 handler 包装器
+//
+// 分组按其中的叶子属性计数；WithAttrs 预设的属性与记录自身的属性共享同一上限，
+// 超出的属性被丢弃，并添加 _dropped_attrs=N 标记被丢弃的数量
+type maxAttrsHandler struct {
+	inner slog.Handler
+	max   int
+	// count WithAttrs 已保留的属性数量
+	count int
+	// dropped WithAttrs 已丢弃的属性数量
+	dropped int
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *maxAttrsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *maxAttrsHandler) Handle(ctx context.Context, r slog.Record) error {
+	n := 0
+	r.Attrs(func(a slog.Attr) bool {
+		n += countAttrs(a)
+		return true
+	})
+	if h.count+n <= h.max && h.dropped == 0 {
+		return h.inner.Handle(ctx, r)
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	kept, _, dropped := limitAttrs(attrs, h.max-h.count)
+
+	nr := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	nr.AddAttrs(kept...)
+	nr.AddAttrs(slog.Int(droppedAttrsKey, h.dropped+dropped))
+	return h.inner.Handle(ctx, nr)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *maxAttrsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	kept, used, dropped := limitAttrs(attrs, h.max-h.count)
+	h2 := *h
+	h2.count += used
+	h2.dropped += dropped
+	if len(kept) > 0 {
+		h2.inner = h.inner.WithAttrs(kept)
+	}
+	return &h2
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *maxAttrsHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithGroup(name)
+	return &h2
+}
+
+// limitAttrs 保留不超过 budget 个叶子属性，分组在预算不足时只保留能容纳的部分
+//
+// 返回保留的属性、保留的叶子属性数量和丢弃的叶子属性数量
+func limitAttrs(attrs []slog.Attr, budget int) (kept []slog.Attr, used, dropped int) {
+	kept = make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		n := countAttrs(a)
+		switch {
+		case used+n <= budget:
+			kept = append(kept, a)
+			used += n
+		case a.Value.Kind() == slog.KindGroup && used < budget:
+			sub, u, d := limitAttrs(a.Value.Group(), budget-used)
+			if len(sub) > 0 {
+				kept = append(kept, slog.Attr{Key: a.Key, Value: slog.GroupValue(sub...)})
+			}
+			used += u
+			dropped += d
+		default:
+			dropped += n
+		}
+	}
+	return kept, used, dropped
+}
+
+// countAttrs 返回属性包含的叶子属性数量，分组递归计数
+func countAttrs(a slog.Attr) int {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return 1
+	}
+	n := 0
+	for _, attr := range v.Group() {
+		n += countAttrs(attr)
+	}
+	return n
+}
@@ -0,0 +1,235 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"strconv"
+)
+
+// lineTruncatedMarker 超出 MaxLineBytes 的行被直接截断时的后缀格式
+const lineTruncatedMarker = "…(truncated %d bytes)"
+
+// errLineTooLong 格式化后的行超过 MaxLineBytes，由 lineWriter 返回
+type errLineTooLong struct {
+	n int // 格式化后的字节数（包括换行符）
+}
+
+// Error 实现 error 接口
+func (e *errLineTooLong) Error() string {
+	return "log line too long: " + strconv.Itoa(e.n) + " bytes"
+}
+
+// lineWriter 检查每行长度的写入器，handler 每条记录只调用一次 Write
+//
+// cut 为 false 时拒绝超长的行并返回 errLineTooLong，由 maxLineHandler 缩减属性后重新格式化；
+// cut 为 true 时直接截断超长的行并追加 "…(truncated N bytes)"，用于无法再缩减的记录
+type lineWriter struct {
+	out io.Writer
+	max int
+	cut bool
+}
+
+// Write 实现 io.Writer 接口
+func (w *lineWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.max {
+		return w.out.Write(p)
+	}
+	if !w.cut {
+		return 0, &errLineTooLong{n: len(p)}
+	}
+
+	line := string(p)
+	keep := w.max - 1 - len(fmt.Sprintf(lineTruncatedMarker, len(p)))
+	suffix := ""
+	if keep > 0 {
+		line = truncateUTF8(line, keep)
+		suffix = fmt.Sprintf(lineTruncatedMarker, len(p)-1-len(line))
+	} else {
+		// MaxLineBytes 小到放不下后缀时只保留开头
+		line = truncateUTF8(line, w.max-1)
+	}
+	if _, err := io.WriteString(w.out, line+suffix+"\n"); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// maxLineHandler 限制格式化后每行字节数（MaxLineBytes）的 handler 包装器
+//
+// 超长时依次缩减记录中最大的属性后重新格式化：字符串截断并追加 "…(truncated N bytes)"，
+// 其他类型的值直接丢弃并添加 _dropped_attrs=N，必要时同样截断消息，因此 JSON 等格式仍然有效。
+// WithAttrs 预设的属性和源代码位置无法缩减，缩减后仍然超长时直接截断整行。
+// 未超长的记录只格式化一次，没有额外开销。
+type maxLineHandler struct {
+	inner slog.Handler // 写入拒绝超长行的 lineWriter
+	cut   slog.Handler // 写入直接截断的 lineWriter
+	max   int
+}
+
+// newMaxLineHandler 创建 maxLineHandler，newHandler 按格式创建写入 w 的 handler
+func newMaxLineHandler(w io.Writer, maxBytes int, newHandler func(w io.Writer) slog.Handler) *maxLineHandler {
+	return &maxLineHandler{
+		inner: newHandler(&lineWriter{out: w, max: maxBytes}),
+		cut:   newHandler(&lineWriter{out: w, max: maxBytes, cut: true}),
+		max:   maxBytes,
+	}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *maxLineHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *maxLineHandler) Handle(ctx context.Context, r slog.Record) error {
+	err := h.inner.Handle(ctx, r)
+	var tooLong *errLineTooLong
+	if !errors.As(err, &tooLong) {
+		return err
+	}
+
+	s := newLineShrinker(r)
+	// 每次截断或丢弃一个属性（或截断消息），次数有限，超出后直接截断整行
+	for range 2*len(s.attrs) + 2 {
+		if !s.shrink(tooLong.n - h.max) {
+			break
+		}
+		err = h.inner.Handle(ctx, s.record(r))
+		if !errors.As(err, &tooLong) {
+			return err
+		}
+	}
+	return h.cut.Handle(ctx, s.record(r))
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *maxLineHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &maxLineHandler{inner: h.inner.WithAttrs(attrs), cut: h.cut.WithAttrs(attrs), max: h.max}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *maxLineHandler) WithGroup(name string) slog.Handler {
+	return &maxLineHandler{inner: h.inner.WithGroup(name), cut: h.cut.WithGroup(name), max: h.max}
+}
+
+// 缩减字符串时预留给 "…(truncated N bytes)" 的字节数，以及截断后至少保留的字节数
+const (
+	shrinkSlack   = 32
+	shrinkMinKeep = 16
+)
+
+// lineShrinker 记录一条超长记录的缩减状态，每次从原始值重新截断，后缀不会叠加
+type lineShrinker struct {
+	msg     string
+	msgKeep int // 消息保留的字节数，-1 表示不截断
+	attrs   []slog.Attr
+	keep    []int // 字符串属性保留的字节数，-1 表示不截断
+	dropped []bool
+	ndrop   int
+}
+
+// newLineShrinker 从记录中取出消息和属性
+func newLineShrinker(r slog.Record) *lineShrinker {
+	s := &lineShrinker{msg: r.Message, msgKeep: -1, attrs: make([]slog.Attr, 0, r.NumAttrs())}
+	r.Attrs(func(a slog.Attr) bool {
+		a.Value = a.Value.Resolve()
+		s.attrs = append(s.attrs, a)
+		return true
+	})
+	s.keep = make([]int, len(s.attrs))
+	for i := range s.keep {
+		s.keep[i] = -1
+	}
+	s.dropped = make([]bool, len(s.attrs))
+	return s
+}
+
+// shrink 截断或丢弃当前最大的属性（或消息）以减少约 excess 字节，没有可缩减的内容时返回 false
+func (s *lineShrinker) shrink(excess int) bool {
+	best, bestSize := -2, 0 // -1 表示消息
+	msgSize := len(s.msg)
+	if s.msgKeep >= 0 {
+		msgSize = s.msgKeep
+	}
+	if msgSize > bestSize {
+		best, bestSize = -1, msgSize
+	}
+	for i, a := range s.attrs {
+		if s.dropped[i] {
+			continue
+		}
+		size := len(a.Key) + valueLen(a)
+		if s.keep[i] >= 0 {
+			size = len(a.Key) + s.keep[i]
+		}
+		if size > bestSize {
+			best, bestSize = i, size
+		}
+	}
+
+	// 字符串先截断到约 excess 字节以内（转义可能使实际减少的字节更少），至少保留 shrinkMinKeep 字节，
+	// 已截断到最短后再丢弃；消息只截断不丢弃
+	switch {
+	case best == -2:
+		return false
+	case best == -1:
+		s.msgKeep = 0
+		if msgSize > shrinkMinKeep {
+			s.msgKeep = max(msgSize-excess-shrinkSlack, shrinkMinKeep)
+		}
+	case isStringValue(s.attrs[best]) && bestSize-len(s.attrs[best].Key) > shrinkMinKeep:
+		s.keep[best] = max(bestSize-len(s.attrs[best].Key)-excess-shrinkSlack, shrinkMinKeep)
+	default:
+		s.dropped[best] = true
+		s.ndrop++
+	}
+	return true
+}
+
+// record 按当前的缩减状态创建新记录
+func (s *lineShrinker) record(r slog.Record) slog.Record {
+	msg := s.msg
+	if s.msgKeep >= 0 {
+		msg = truncateAttr(slog.String(slog.MessageKey, msg), s.msgKeep).Value.String()
+	}
+	nr := slog.NewRecord(r.Time, r.Level, msg, r.PC)
+	for i, a := range s.attrs {
+		switch {
+		case s.dropped[i]:
+		case s.keep[i] >= 0:
+			nr.AddAttrs(truncateAttr(a, s.keep[i]))
+		default:
+			nr.AddAttrs(a)
+		}
+	}
+	if s.ndrop > 0 {
+		nr.AddAttrs(slog.Int(droppedAttrsKey, s.ndrop))
+	}
+	return nr
+}
+
+// isStringValue 判断属性值是否为可截断的字符串或 []byte
+func isStringValue(a slog.Attr) bool {
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return true
+	case slog.KindAny:
+		_, ok := a.Value.Any().([]byte)
+		return ok
+	default:
+		return false
+	}
+}
+
+// valueLen 估算属性值格式化后的字节数
+func valueLen(a slog.Attr) int {
+	if a.Value.Kind() == slog.KindAny {
+		if b, ok := a.Value.Any().([]byte); ok {
+			return len(b)
+		}
+	}
+	return len(a.Value.String())
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync/atomic"
+)
+
+// metricLevels 计数的级别，记录按不低于的最高级别归类，例如 INFO+2 计入 INFO
+var metricLevels = []slog.Level{LevelTrace, slog.LevelDebug, slog.LevelInfo, slog.LevelWarn, slog.LevelError}
+
+// logMetrics 日志数量统计
+type logMetrics struct {
+	messages [6]atomic.Uint64 // 与 metricLevels 一一对应
+	dropped  atomic.Uint64    // 采样和限流丢弃的记录数
+}
+
+// globalMetrics 启用 [Config].Metrics 的 logger 共享的统计
+var globalMetrics logMetrics
+
+// record 按级别计数
+func (m *logMetrics) record(level slog.Level) {
+	i := 0
+	for j, l := range metricLevels {
+		if level >= l {
+			i = j
+		}
+	}
+	m.messages[i].Add(1)
+}
+
+// MetricsSnapshot 日志数量统计的快照，见 [Metrics]
+type MetricsSnapshot struct {
+	// Messages 按级别名称统计的记录数，例如 {"INFO": 10, "ERROR": 1}，包括之后被采样和限流丢弃的记录，
+	// 不包括被 Filter 丢弃的记录
+	Messages map[string]uint64
+	// Dropped 被采样和限流丢弃的记录数
+	Dropped uint64
+}
+
+// Metrics 返回启用 [Config].Metrics 以来的日志数量统计
+func Metrics() MetricsSnapshot {
+	s := MetricsSnapshot{
+		Messages: make(map[string]uint64, len(metricLevels)),
+		Dropped:  globalMetrics.dropped.Load(),
+	}
+	for i, level := range metricLevels {
+		s.Messages[levelName(level)] = globalMetrics.messages[i].Load()
+	}
+	return s
+}
+
+// MetricsHandler 以 Prometheus 文本格式输出日志数量统计的 http.Handler
+//
+// 输出 log_messages_total{level="..."} 和 log_messages_dropped_total，
+// 不依赖 Prometheus 客户端库，可直接挂载到 /metrics 或由其他 exporter 合并：
+//
+//	mux.Handle("/metrics", logger.MetricsHandler())
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = fmt.Fprint(w, "# HELP log_messages_total Number of log records by level.\n# TYPE log_messages_total counter\n")
+		for i, level := range metricLevels {
+			_, _ = fmt.Fprintf(w, "log_messages_total{level=%q} %d\n", levelName(level), globalMetrics.messages[i].Load())
+		}
+		_, _ = fmt.Fprint(w, "# HELP log_messages_dropped_total Number of log records dropped by sampling or rate limiting.\n# TYPE log_messages_dropped_total counter\n")
+		_, _ = fmt.Fprintf(w, "log_messages_dropped_total %d\n", globalMetrics.dropped.Load())
+	})
+}
+
+// metricsHandler 按级别统计记录数的 handler 包装器
+type metricsHandler struct {
+	inner   slog.Handler
+	metrics *logMetrics
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *metricsHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *metricsHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.metrics.record(r.Level)
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *metricsHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &metricsHandler{inner: h.inner.WithAttrs(attrs), metrics: h.metrics}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *metricsHandler) WithGroup(name string) slog.Handler {
+	return &metricsHandler{inner: h.inner.WithGroup(name), metrics: h.metrics}
+}
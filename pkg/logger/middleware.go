@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+)
+
+// 传递请求 ID 和关联 ID 的 HTTP 头，请求中已携带时沿用，否则自动生成
+const (
+	requestIDHeader     = "X-Request-ID"
+	correlationIDHeader = "X-Correlation-ID"
+)
+
+// slowRequestThreshold 慢请求阈值（纳秒），由 InitCfg 根据 [Config].SlowRequestThreshold 设置
+var slowRequestThreshold atomic.Int64
+
+// Middleware 记录每个 HTTP 请求的 net/http 中间件
+//
+// 请求结束后记录一条 "http request" 日志，包含 method、path、status、duration 和 bytes；
+// 5xx 记录为 ERROR，4xx 和超过 [Config].SlowRequestThreshold 的慢请求记录为 WARN，其余为 INFO。
+// 关联 ID 从请求头 X-Correlation-ID 读取或自动生成，写入响应头并存入请求的 context（见 [WithCorrelationID]），
+// 通过带 context 的方法记录的日志都带有 correlation_id。
+// 下游 handler 可通过 [FromContext] 获取带 request_id 的 logger：
+//
+//	http.ListenAndServe(":8080", logger.Middleware(mux))
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		correlationID := r.Header.Get(correlationIDHeader)
+		if correlationID == "" {
+			correlationID = NewCorrelationID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		w.Header().Set(correlationIDHeader, correlationID)
+		ctx := WithRequestID(WithCorrelationID(r.Context(), correlationID), requestID)
+
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rw, r.WithContext(ctx))
+
+		duration := time.Since(start)
+		level := slog.LevelInfo
+		switch threshold := time.Duration(slowRequestThreshold.Load()); {
+		case rw.status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case rw.status >= http.StatusBadRequest, threshold > 0 && duration > threshold:
+			level = slog.LevelWarn
+		}
+		FromContext(ctx).LogAttrs(ctx, level, "http request",
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rw.status),
+			slog.String("duration", FormatDuration(duration)),
+			slog.Int64("bytes", rw.bytes),
+		)
+	})
+}
+
+// RecoverMiddleware 恢复 handler 中 panic 的 net/http 中间件
+//
+// 以 ERROR 级别记录 panic 值、堆栈和请求信息，并返回 500；
+// 与 net/http 的约定一致，http.ErrAbortHandler 会继续向上 panic。
+//
+//	http.ListenAndServe(":8080", logger.RecoverMiddleware(logger.Middleware(mux)))
+func RecoverMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rec := recover()
+			if rec == nil {
+				return
+			}
+			if rec == http.ErrAbortHandler {
+				panic(rec)
+			}
+			FromContext(r.Context()).LogAttrs(r.Context(), slog.LevelError, "panic",
+				slog.String("error", fmt.Sprint(rec)),
+				slog.String("stack", string(debug.Stack())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+			)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newRequestID 生成 16 位十六进制的随机请求 ID
+func newRequestID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// responseWriter 记录响应状态码和写入字节数的 http.ResponseWriter 包装器
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// WriteHeader 实现 http.ResponseWriter 接口
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// Write 实现 http.ResponseWriter 接口
+func (w *responseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	n, err := w.ResponseWriter.Write(p)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap 返回原始的 ResponseWriter，供 http.ResponseController 使用
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
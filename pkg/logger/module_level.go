@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// moduleKey 标识具名 logger 的属性键，见 [Named]
+const moduleKey = "logger"
+
+// Named 返回带有 logger=name 属性的具名 logger，基于当前的全局 logger
+//
+// 配合 [Config].ModuleLevels 可以为不同模块设置独立的日志级别：
+//
+//	var log = logger.Named("db")
+//	log.Debug("执行 SQL", "sql", query)
+func Named(name string) *slog.Logger {
+	return slog.Default().With(moduleKey, name)
+}
+
+// moduleLevelHandler 按模块级别过滤记录的 handler 包装器
+//
+// 通过 WithAttrs 识别顶级的 logger 属性，具名 logger 使用 levels 中对应的级别，
+// 未配置的模块和普通 logger 使用全局级别
+type moduleLevelHandler struct {
+	inner  slog.Handler
+	levels map[string]slog.Level
+	global slog.Leveler
+	// level 当前 logger 生效的级别
+	level slog.Leveler
+	// grouped 已调用过 WithGroup，之后的 logger 属性不再是顶级属性
+	grouped bool
+}
+
+// newModuleLevelHandler 创建模块级别 handler，level 为全局级别
+func newModuleLevelHandler(inner slog.Handler, modules map[string]string, level slog.Leveler) *moduleLevelHandler {
+	levels := make(map[string]slog.Level, len(modules))
+	for name, l := range modules {
+		levels[name] = parseLevel(l)
+	}
+	return &moduleLevelHandler{inner: inner, levels: levels, global: level, level: level}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *moduleLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *moduleLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *moduleLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithAttrs(attrs)
+	if !h.grouped {
+		for _, a := range attrs {
+			if a.Key != moduleKey {
+				continue
+			}
+			h2.level = h.global
+			if level, ok := h.levels[a.Value.Resolve().String()]; ok {
+				h2.level = level
+			}
+		}
+	}
+	return &h2
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *moduleLevelHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.inner = h.inner.WithGroup(name)
+	h2.grouped = true
+	return &h2
+}
+
+// maxLeveler 取多个级别中的最大值，用于只接收高级别记录的输出（见 [Config].ErrorOutput）
+type maxLeveler []slog.Leveler
+
+// Level 实现 slog.Leveler 接口
+func (l maxLeveler) Level() slog.Level {
+	m := l[0].Level()
+	for _, leveler := range l[1:] {
+		m = max(m, leveler.Level())
+	}
+	return m
+}
+
+// minLeveler 取多个级别中的最小值，用于让输出 handler 放行所有模块可能需要的记录
+type minLeveler []slog.Leveler
+
+// Level 实现 slog.Leveler 接口
+func (l minLeveler) Level() slog.Level {
+	m := l[0].Level()
+	for _, leveler := range l[1:] {
+		m = min(m, leveler.Level())
+	}
+	return m
+}
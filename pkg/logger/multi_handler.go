@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// multiHandler 将每条记录分发给多个 handler（tee）
+//
+// 某个 handler 写入失败不会影响其他 handler，所有错误会合并后返回
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+// newMultiHandler 创建分发 handler，只有一个 handler 时直接返回该 handler
+func newMultiHandler(handlers ...slog.Handler) slog.Handler {
+	if len(handlers) == 1 {
+		return handlers[0]
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// Enabled 实现 slog.Handler 接口，任一 handler 启用即返回 true
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+		// 每个 handler 使用独立的记录副本，避免相互影响
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+// multiCloser 依次关闭多个资源，所有错误会合并后返回
+type multiCloser []io.Closer
+
+// Close 实现 io.Closer 接口
+func (c multiCloser) Close() error {
+	var errs []error
+	for _, closer := range c {
+		if err := closer.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Sync 刷新所有支持 Sync 的资源，所有错误会合并后返回
+func (c multiCloser) Sync() error {
+	var errs []error
+	for _, closer := range c {
+		if err := syncResource(closer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Rotate 轮转所有支持 Rotate 的资源，所有错误会合并后返回
+func (c multiCloser) Rotate() error {
+	var errs []error
+	for _, closer := range c {
+		if err := rotateResource(closer); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncer 可刷新缓冲数据的资源，例如 *os.File
+type syncer interface {
+	Sync() error
+}
+
+// rotator 自行轮转文件的资源，例如 lumberjack.Logger
+type rotator interface {
+	Rotate() error
+}
+
+// syncResource 刷新资源，不支持 Sync 的资源直接忽略
+func syncResource(c io.Closer) error {
+	if s, ok := c.(syncer); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// rotateResource 轮转资源，不支持 Rotate 的资源直接忽略
+func rotateResource(c io.Closer) error {
+	if r, ok := c.(rotator); ok {
+		return r.Rotate()
+	}
+	return nil
+}
+
+// newMultiCloser 合并多个 closer，忽略 nil；没有资源时返回 nil
+func newMultiCloser(closers ...io.Closer) io.Closer {
+	var valid multiCloser
+	for _, closer := range closers {
+		if closer != nil {
+			valid = append(valid, closer)
+		}
+	}
+	switch len(valid) {
+	case 0:
+		return nil
+	case 1:
+		return valid[0]
+	default:
+		return valid
+	}
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"io"
+	"log/slog"
+	"maps"
+)
+
+// Option 修改 Config 的函数式选项，用于 [NewWithOptions] 和 [NewConfig]
+type Option func(*Config)
+
+// NewConfig 以默认配置（与 [New] 传入 nil 时相同）为基础，依次应用 opts，返回得到的配置
+//
+// 需要 closer 或初始化全局 logger 时配合 [NewWithCloser]、[InitCfg] 使用：
+//
+//	err := logger.InitCfg(logger.NewConfig(logger.WithLevel("DEBUG"), logger.WithFormat("json")))
+func NewConfig(opts ...Option) *Config {
+	cfg := defaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// NewWithOptions 使用函数式选项创建新的 logger 实例，等价于 New(NewConfig(opts...))
+//
+//	log, err := logger.NewWithOptions(
+//		logger.WithLevel("DEBUG"),
+//		logger.WithFormat("json"),
+//		logger.WithDefaultAttrs(map[string]string{"service": "api"}),
+//	)
+//
+// 注意：如果输出到文件，应使用 NewWithCloser(NewConfig(opts...)) 获取 closer 并在适当时候关闭
+func NewWithOptions(opts ...Option) (*slog.Logger, error) {
+	return New(NewConfig(opts...))
+}
+
+// WithLevel 设置日志级别，见 [Config].Level
+func WithLevel(level string) Option {
+	return func(c *Config) { c.Level = level }
+}
+
+// WithFormat 设置输出格式，见 [Config].Format
+func WithFormat(format string) Option {
+	return func(c *Config) { c.Format = format }
+}
+
+// WithOutput 设置输出目标，见 [Config].Output
+func WithOutput(output string) Option {
+	return func(c *Config) { c.Output = output }
+}
+
+// WithWriter 将 w 作为唯一的输出，见 [Config].Writer
+func WithWriter(w io.Writer) Option {
+	return func(c *Config) { c.Writer = w }
+}
+
+// WithTimeFormat 设置时间格式，见 [Config].TimeFormat
+func WithTimeFormat(format string) Option {
+	return func(c *Config) { c.TimeFormat = format }
+}
+
+// WithTimezone 设置时区，见 [Config].Timezone
+func WithTimezone(timezone string) Option {
+	return func(c *Config) { c.Timezone = timezone }
+}
+
+// WithSource 设置是否添加源代码位置信息，见 [Config].AddSource
+func WithSource(enabled bool) Option {
+	return func(c *Config) { c.AddSource = enabled }
+}
+
+// WithDefaultAttrs 添加附加到每条日志的固定属性，多次使用时合并，见 [Config].DefaultAttrs
+func WithDefaultAttrs(attrs map[string]string) Option {
+	return func(c *Config) {
+		if c.DefaultAttrs == nil {
+			c.DefaultAttrs = make(map[string]string, len(attrs))
+		}
+		maps.Copy(c.DefaultAttrs, attrs)
+	}
+}
+
+// WithRedactKeys 添加需要脱敏的属性键，见 [Config].RedactKeys
+func WithRedactKeys(keys ...string) Option {
+	return func(c *Config) { c.RedactKeys = append(c.RedactKeys, keys...) }
+}
+
+// WithReplaceAttr 设置自定义属性改写函数，见 [Config].ReplaceAttr
+func WithReplaceAttr(fn func(groups []string, a slog.Attr) slog.Attr) Option {
+	return func(c *Config) { c.ReplaceAttr = fn }
+}
+
+// WithAsync 启用异步写入，bufferSize 为 0 时使用默认值，见 [Config].Async
+func WithAsync(bufferSize int) Option {
+	return func(c *Config) {
+		c.Async = true
+		c.BufferSize = bufferSize
+	}
+}
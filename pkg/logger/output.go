@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// outputFactory 为特殊输出目标（例如 syslog）创建 handler
+//
+// target 为完整的输出配置（例如 "syslog://host:514"），format 为该输出使用的格式；
+// 返回的 closer 在关闭 logger 时调用，可以为 nil
+type outputFactory func(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error)
+
+// outputFactories 按 scheme 注册的特殊输出目标
+var outputFactories = map[string]outputFactory{}
+
+// registerOutput 注册特殊输出目标，输出配置为 scheme 本身或以 "scheme://" 开头时使用
+func registerOutput(scheme string, factory outputFactory) {
+	outputFactories[scheme] = factory
+}
+
+func init() {
+	registerOutput("discard", newDiscardOutput)
+	registerOutput("none", newDiscardOutput)
+}
+
+// isDiscardOutput 报告输出配置是否为 discard（或 none）
+func isDiscardOutput(output string) bool {
+	return output == "discard" || output == "none"
+}
+
+// newDiscardOutput 创建丢弃所有记录的输出，Enabled 始终返回 false，记录不会被格式化
+//
+// 启用了告警、指标或最近日志缓冲区时，这些功能仍需收到记录，Enabled 改为按 level 判断
+func newDiscardOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	if cfg.observesRecords() {
+		return discardHandler{level: level}, nil, nil
+	}
+	return slog.DiscardHandler, nil, nil
+}
+
+// discardHandler 按级别启用但丢弃所有记录的 handler，供告警等外层功能处理记录
+type discardHandler struct {
+	level slog.Leveler
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h discardHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle 实现 slog.Handler 接口
+func (h discardHandler) Handle(ctx context.Context, r slog.Record) error { return nil }
+
+// WithAttrs 实现 slog.Handler 接口
+func (h discardHandler) WithAttrs(attrs []slog.Attr) slog.Handler { return h }
+
+// WithGroup 实现 slog.Handler 接口
+func (h discardHandler) WithGroup(name string) slog.Handler { return h }
+
+// lookupOutput 查找输出配置对应的特殊输出目标
+func lookupOutput(output string) (outputFactory, bool) {
+	scheme, _, _ := strings.Cut(output, "://")
+	factory, ok := outputFactories[scheme]
+	return factory, ok
+}
+
+// openOutput 打开一个输出目标并创建对应的 handler
+//
+// 已注册的特殊输出目标由其 factory 创建，其他输出（stdout、stderr、文件）
+// 使用 getWriter 打开，启用 Async 时包装为异步写入器
+func openOutput(cfg *Config, output, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	if factory, ok := lookupOutput(output); ok {
+		return factory(cfg, output, format, level)
+	}
+
+	writer, closer, err := getWriter(cfg, output)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cfg.Async {
+		async := newAsyncWriter(writer, closer, cfg.BufferSize, cfg.OverflowPolicy)
+		writer, closer = async, async
+	}
+	return createHandler(cfg, format, writer, level), closer, nil
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+)
+
+// pipeWriter 包装 stdout/stderr，输出端关闭后停止写入
+//
+// 例如 app | head 中 head 退出后，写入返回 EPIPE；此时打印一次诊断信息到 stderr，
+// 之后的记录直接丢弃并返回成功，不再重复报错。Go 运行时默认在 fd 1/2 遇到 EPIPE 时
+// 以 SIGPIPE 终止进程，因此创建时会捕获 SIGPIPE（见 catchSIGPIPE），写入改为返回 EPIPE。
+type pipeWriter struct {
+	file   *os.File
+	name   string // stdout 或 stderr，用于诊断信息
+	broken atomic.Bool
+}
+
+// newPipeWriter 创建 stdout/stderr 写入器
+func newPipeWriter(file *os.File, name string) *pipeWriter {
+	catchSIGPIPE()
+	return &pipeWriter{file: file, name: name}
+}
+
+// Write 实现 io.Writer 接口
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	if w.broken.Load() {
+		return len(p), nil
+	}
+	n, err := w.file.Write(p)
+	if err != nil && isBrokenPipe(err) {
+		if w.broken.CompareAndSwap(false, true) && w.file != os.Stderr {
+			fmt.Fprintf(os.Stderr, "logger: %s: %v, discarding further logs\n", w.name, err)
+		}
+		return len(p), nil
+	}
+	return n, err
+}
+
+// isBrokenPipe 判断写入错误是否表示输出端已关闭
+func isBrokenPipe(err error) bool {
+	return errors.Is(err, syscall.EPIPE) || errors.Is(err, os.ErrClosed) || errors.Is(err, io.ErrClosedPipe)
+}
@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Record 内存中保留的一条日志记录，见 [RecentLogs]
+type Record struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+	// Attrs 记录的属性，分组属性展开为以 "." 连接的键，例如 "req.method"
+	Attrs map[string]any
+}
+
+// globalRing 全局 logger 的最近记录缓冲区，未启用 [Config].RingSize 时为 nil
+var globalRing atomic.Pointer[ringBuffer]
+
+// RecentLogs 返回全局 logger 最近的日志记录，按时间从旧到新排列
+//
+// 需要启用 [Config].RingSize，未启用时返回 nil。可与日志记录并发调用，
+// 例如用于管理后台的 "最近日志" 面板。
+func RecentLogs() []Record {
+	if ring := globalRing.Load(); ring != nil {
+		return ring.snapshot()
+	}
+	return nil
+}
+
+// ringBuffer 固定容量的环形缓冲区，写满后覆盖最旧的记录
+type ringBuffer struct {
+	mu      sync.Mutex
+	records []Record
+	next    int  // 下一条记录写入的位置
+	full    bool // 是否已写满一轮
+	// replace 与输出相同的 ReplaceAttr（见 buildReplaceAttr），保存前对消息和属性脱敏，
+	// 并按 MaxFieldLen 截断，使内存占用与记录大小无关
+	replace func(groups []string, a slog.Attr) slog.Attr
+}
+
+// newRingBuffer 创建容量为 size 的环形缓冲区，保存的记录经过 replace 处理
+func newRingBuffer(size int, replace func(groups []string, a slog.Attr) slog.Attr) *ringBuffer {
+	return &ringBuffer{records: make([]Record, size), replace: replace}
+}
+
+// add 写入一条记录
+func (b *ringBuffer) add(rec Record) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.records[b.next] = rec
+	b.next = (b.next + 1) % len(b.records)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// snapshot 返回缓冲区中的记录副本，从旧到新排列
+func (b *ringBuffer) snapshot() []Record {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.full {
+		return append([]Record(nil), b.records[:b.next]...)
+	}
+	result := make([]Record, 0, len(b.records))
+	result = append(result, b.records[b.next:]...)
+	return append(result, b.records[:b.next]...)
+}
+
+// ringHandler 将记录同时保存到环形缓冲区的 handler 包装器
+//
+// 包装在最外层，被 Filter 丢弃的记录不会写入任何输出，因此同样不保存
+type ringHandler struct {
+	inner  slog.Handler
+	ring   *ringBuffer
+	filter *filterHandler // 只用于判断记录是否被 Filter 丢弃，未配置 Filter 时为 nil
+	groups []string
+	attrs  []slog.Attr // 已展开并加上 group 前缀的 With 属性
+}
+
+// newRingHandler 创建 ringHandler，配置了 filter 时只保存 filter 保留的记录
+func newRingHandler(inner slog.Handler, ring *ringBuffer, filter FilterFunc) *ringHandler {
+	h := &ringHandler{inner: inner, ring: ring}
+	if filter != nil {
+		h.filter = &filterHandler{inner: slog.DiscardHandler, filter: filter}
+	}
+	return h
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *ringHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *ringHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.filter != nil && !h.filter.keep(r) {
+		return h.inner.Handle(ctx, r)
+	}
+	rec := Record{
+		Time:    r.Time,
+		Level:   r.Level,
+		Message: r.Message,
+		Attrs:   make(map[string]any, len(h.attrs)+r.NumAttrs()),
+	}
+	if msg, ok := replaceAttr(h.ring.replace, nil, slog.String(slog.MessageKey, r.Message)); ok {
+		rec.Message = msg.Value.String()
+	}
+	for _, a := range h.attrs {
+		rec.Attrs[a.Key] = a.Value.Any()
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		for _, attr := range h.prepare([]slog.Attr{a}) {
+			rec.Attrs[attr.Key] = attr.Value.Any()
+		}
+		return true
+	})
+	h.ring.add(rec)
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *ringHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	newAttrs := append(h.attrs[:len(h.attrs):len(h.attrs)], h.prepare(attrs)...)
+	h2 := &ringHandler{inner: h.inner.WithAttrs(attrs), ring: h.ring, groups: h.groups, attrs: newAttrs}
+	if h.filter != nil {
+		h2.filter = h.filter.WithAttrs(attrs).(*filterHandler)
+	}
+	return h2
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *ringHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	groups := append(h.groups[:len(h.groups):len(h.groups)], name)
+	h2 := &ringHandler{inner: h.inner.WithGroup(name), ring: h.ring, groups: groups, attrs: h.attrs}
+	if h.filter != nil {
+		h2.filter = h.filter.WithGroup(name).(*filterHandler)
+	}
+	return h2
+}
+
+// prepare 对属性应用 ReplaceAttr，然后展开为加上 group 前缀的键
+func (h *ringHandler) prepare(attrs []slog.Attr) []slog.Attr {
+	replaced := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a, ok := replaceAttr(h.ring.replace, h.groups, a); ok {
+			replaced = append(replaced, a)
+		}
+	}
+	return prefixAttrs(h.groups, replaced)
+}
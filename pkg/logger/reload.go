@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+)
+
+var (
+	// reloadFile InitFromFile 使用的配置文件，重新加载时从该文件读取；为空时从环境变量读取
+	reloadFile string
+	// watchOnce 保证 WatchSignals 只启动一个监听 goroutine
+	watchOnce sync.Once
+)
+
+// WatchSignals 监听 SIGHUP 信号并重新加载全局 logger 的配置
+//
+// 通过 [InitFromFile] 初始化时重新读取该配置文件（环境变量仍然优先），否则按 [InitEnv] 从环境变量读取。
+// 重新加载会原子地替换 handler 和级别，正在写入的日志不受影响，已有的 logger（包括 With 派生的）
+// 也会使用新配置。加载失败时记录 ERROR 日志并保留原配置。多次调用只启动一个监听。
+// [Config].Writer 实现了 Rotate() error（如 lumberjack.Logger）时，重新加载前先调用它轮转文件，
+// 重新加载后继续使用该 Writer。
+// Windows 等不支持 SIGHUP 的平台上为空操作。
+//
+//	logger.InitEnv()
+//	logger.WatchSignals()
+//	// kill -HUP <pid> 重新加载
+func WatchSignals() {
+	if len(reloadSignals) == 0 {
+		return
+	}
+	watchOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, reloadSignals...)
+		go func() {
+			for range ch {
+				if err := rotate(); err != nil {
+					Error("rotate log output failed", "error", err)
+				}
+				if err := reload(); err != nil {
+					Error("reload log config failed", "error", err)
+					continue
+				}
+				Info("log config reloaded")
+			}
+		}()
+	})
+}
+
+// reload 按最近一次初始化的来源重新加载配置
+//
+// Writer 不能通过环境变量或配置文件设置，重新加载时保留当前的 Writer
+func reload() error {
+	globalMu.Lock()
+	file := reloadFile
+	globalMu.Unlock()
+
+	cfg := envConfig()
+	if file != "" {
+		var err error
+		if cfg, err = loadConfigFile(file); err != nil {
+			return err
+		}
+		applyEnv(cfg)
+	}
+
+	initMu.Lock()
+	defer initMu.Unlock()
+	if globalConfig != nil {
+		cfg.Writer = globalConfig.Writer
+	}
+	return initCfg(cfg)
+}
+
+// rotate 轮转全局 logger 中自行管理轮转的输出，见 [Config].Writer
+func rotate() error {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if globalCloser != nil {
+		return rotateResource(globalCloser)
+	}
+	return nil
+}
+
+// setReloadFile 记录重新加载时使用的配置文件
+func setReloadFile(path string) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	reloadFile = path
+}
@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// redactedValue 敏感字段脱敏后的替换值
+const redactedValue = "***"
+
+// buildReplaceAttr 根据配置构建 ReplaceAttr 函数
+//
+// 返回的函数会安装到所有格式的 handler 中，对每个（包括分组内的）属性生效；
+// 启用 DisableTime 时丢弃顶级的 time 字段；
+// 结构体值中标记 log:"redact" 的字段始终会被脱敏（见 [redactStruct]）；
+// 启用 AddSource 时还会按 SourceMode 将顶级的 *slog.Source 改写为字符串，
+// 配置 RedactPatterns 时替换字符串（包括消息）中匹配的部分，
+// 配置 MaxFieldLen 时截断过长的字符串和字节切片（包括消息）；
+// 启用 ErrorChain 时将 error 值展开为错误链（见 [errorChainValue]）；
+// 以上处理之后按 KeyMap 重命名顶级字段，最后调用用户配置的 ReplaceAttr，
+// 因此 ReplaceAttr 接收到的是重命名后的键
+func buildReplaceAttr(cfg *Config) func(groups []string, a slog.Attr) slog.Attr {
+	redactKeys := make(map[string]bool, len(cfg.RedactKeys))
+	for _, key := range cfg.RedactKeys {
+		redactKeys[strings.ToLower(key)] = true
+	}
+	// 表达式已由 Validate 检查，这里只编译一次，之后每条记录复用
+	patterns := make([]*regexp.Regexp, 0, len(cfg.RedactPatterns))
+	for _, pattern := range cfg.RedactPatterns {
+		patterns = append(patterns, regexp.MustCompile(pattern))
+	}
+
+	builtin := func(groups []string, a slog.Attr) slog.Attr {
+		if cfg.DisableTime && len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		if len(groups) == 0 && a.Key == slog.SourceKey {
+			if src, ok := a.Value.Any().(*slog.Source); ok {
+				return slog.String(slog.SourceKey, formatSource(src, cfg.SourceMode))
+			}
+		}
+		if redactKeys[strings.ToLower(a.Key)] {
+			return slog.String(a.Key, redactedValue)
+		}
+		if redacted, ok := redactStruct(a); ok {
+			return redacted
+		}
+		// 自定义 handler 传入的 time、level 是格式化后的字符串，不脱敏也不截断
+		builtin := len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey)
+		if len(patterns) > 0 && !builtin && a.Value.Kind() == slog.KindString {
+			a = redactPatterns(a, patterns)
+		}
+		if cfg.MaxFieldLen > 0 && !builtin {
+			return truncateAttr(a, cfg.MaxFieldLen)
+		}
+		return a
+	}
+	if cfg.ErrorChain {
+		inner := builtin
+		builtin = func(groups []string, a slog.Attr) slog.Attr {
+			a = inner(groups, a)
+			if err, ok := a.Value.Any().(error); ok && a.Value.Kind() == slog.KindAny {
+				// 链中的属性按 key.chain 分组内的属性处理，同样会被脱敏和截断
+				chainGroups := append(groups[:len(groups):len(groups)], a.Key, "chain")
+				return slog.Attr{Key: a.Key, Value: errorChainValue(err, func(attr slog.Attr) slog.Attr {
+					return inner(chainGroups, attr)
+				})}
+			}
+			return a
+		}
+	}
+	if len(cfg.KeyMap) > 0 {
+		inner := builtin
+		builtin = func(groups []string, a slog.Attr) slog.Attr {
+			a = inner(groups, a)
+			if to, ok := cfg.KeyMap[a.Key]; ok && len(groups) == 0 {
+				a.Key = to
+			}
+			return a
+		}
+	}
+	if cfg.ReplaceAttr == nil {
+		return builtin
+	}
+	return func(groups []string, a slog.Attr) slog.Attr {
+		return cfg.ReplaceAttr(groups, builtin(groups, a))
+	}
+}
+
+// redactPatterns 将字符串值中匹配任一表达式的部分替换为 "***"
+func redactPatterns(a slog.Attr, patterns []*regexp.Regexp) slog.Attr {
+	s := a.Value.String()
+	redacted := s
+	for _, re := range patterns {
+		redacted = re.ReplaceAllLiteralString(redacted, redactedValue)
+	}
+	if redacted == s {
+		return a
+	}
+	return slog.String(a.Key, redacted)
+}
+
+// truncateAttr 截断超过 maxLen 字节的字符串或 []byte 值，并追加 "…(truncated N bytes)" 后缀
+//
+// 截断位置会回退到 UTF-8 字符边界，[]byte 截断后以字符串输出
+func truncateAttr(a slog.Attr, maxLen int) slog.Attr {
+	var s string
+	switch a.Value.Kind() {
+	case slog.KindString:
+		s = a.Value.String()
+	case slog.KindAny:
+		b, ok := a.Value.Any().([]byte)
+		if !ok {
+			return a
+		}
+		s = string(b)
+	default:
+		return a
+	}
+	if len(s) <= maxLen {
+		return a
+	}
+
+	t := truncateUTF8(s, maxLen)
+	return slog.String(a.Key, t+"…(truncated "+strconv.Itoa(len(s)-len(t))+" bytes)")
+}
+
+// truncateUTF8 将 s 截断到最多 n 字节，不截断多字节字符
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// replaceAttr 对属性应用 ReplaceAttr，分组属性递归处理其中的每个子属性
+//
+// 与 slog 内置 handler 的约定一致：先解析 LogValuer；ReplaceAttr 只作用于非分组属性；
+// 返回 false 表示该属性应被丢弃（键为空，或分组内已没有属性）
+func replaceAttr(replace func([]string, slog.Attr) slog.Attr, groups []string, a slog.Attr) (slog.Attr, bool) {
+	a.Value = a.Value.Resolve()
+
+	if a.Value.Kind() == slog.KindGroup {
+		attrs := a.Value.Group()
+		if len(attrs) == 0 {
+			return a, false
+		}
+		// 空键分组的属性直接内联到上一层
+		childGroups := groups
+		if a.Key != "" {
+			childGroups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		replaced := make([]slog.Attr, 0, len(attrs))
+		for _, attr := range attrs {
+			if attr, ok := replaceAttr(replace, childGroups, attr); ok {
+				replaced = append(replaced, attr)
+			}
+		}
+		if len(replaced) == 0 {
+			return a, false
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(replaced...)}, true
+	}
+
+	if replace != nil {
+		a = replace(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	return a, a.Key != ""
+}
+
+// replaceMessage 对消息应用 ReplaceAttr（脱敏、截断等），用于直接写出消息的协议字段
+//
+// 协议字段的名称固定，只使用替换后的值；ReplaceAttr 丢弃消息时返回空字符串
+func replaceMessage(replace func([]string, slog.Attr) slog.Attr, msg string) string {
+	a, ok := replaceAttr(replace, nil, slog.String(slog.MessageKey, msg))
+	if !ok {
+		return ""
+	}
+	return a.Value.String()
+}
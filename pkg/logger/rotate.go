@@ -0,0 +1,404 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 时间轮转周期
+const (
+	rotateDaily  = "daily"
+	rotateHourly = "hourly"
+)
+
+// rotateOptions 轮转写入器的配置
+type rotateOptions struct {
+	maxSize    int64            // 单个文件最大字节数，0 表示不按大小轮转
+	maxBackups int              // 保留的旧文件数量，0 表示不限制
+	interval   string           // 时间轮转周期: daily, hourly，空表示不按时间轮转
+	location   *time.Location   // 计算周期边界所用的时区
+	compress   bool             // 轮转后在后台将备份压缩为 .gz
+	perm       os.FileMode      // 新建文件（包括压缩文件）的权限，0 表示 0644
+	now        func() time.Time // 当前时间，用于计算时间轮转的周期，nil 表示 time.Now
+}
+
+// rotatingWriter 支持按大小和时间轮转的文件写入器
+//
+// 仅按大小轮转时，写满的文件被重命名为 name.1、name.2 ...（序号递增，越大越新）；
+// 启用时间轮转后，备份文件名带上所属周期，例如 app-2025-01-02.log，同一周期内
+// 再次轮转（大小超限或重启）时追加序号：app-2025-01-02.1.log。
+// 超过 maxBackups 的最旧备份会被删除（压缩后的 .gz 备份同样计数）。
+//
+// 文件大小在内存中计数，只在打开文件时 stat 一次，避免每次写入都触发系统调用。
+// 时间轮转只在写入时检查，长时间无日志不会产生空文件。
+type rotatingWriter struct {
+	mu          sync.Mutex
+	path        string
+	opts        rotateOptions
+	file        *os.File
+	size        int64     // 当前文件已写入的字节数
+	periodStart time.Time // 当前文件所属周期的起点
+	now         func() time.Time
+	compressing sync.WaitGroup // 后台进行中的压缩，Close 时等待完成
+}
+
+// newRotatingWriter 创建轮转文件写入器
+func newRotatingWriter(path string, opts rotateOptions) (*rotatingWriter, error) {
+	if opts.location == nil {
+		opts.location = time.Local
+	}
+	if opts.perm == 0 {
+		opts.perm = defaultFilePerm
+	}
+	if opts.now == nil {
+		opts.now = time.Now
+	}
+	w := &rotatingWriter{
+		path: path,
+		opts: opts,
+		now:  opts.now,
+	}
+	if err := w.openFile(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Write 实现 io.Writer 接口，写入前检查是否需要轮转
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if w.opts.interval != "" {
+		if start := w.periodOf(w.now()); start.After(w.periodStart) {
+			// 空文件无需备份，直接归入新周期
+			if w.size > 0 {
+				if err := w.tryRotate(); err != nil {
+					return 0, err
+				}
+			}
+			w.periodStart = start
+		}
+	}
+
+	// 空文件不轮转，避免单条超大记录导致无限轮转
+	if w.opts.maxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.opts.maxSize {
+		if err := w.tryRotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Sync 将当前文件的内容刷新到磁盘
+func (w *rotatingWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close 实现 io.Closer 接口
+func (w *rotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	w.compressing.Wait()
+	return err
+}
+
+// openFile 打开（或创建）当前日志文件，并记录其已有大小和所属周期
+func (w *rotatingWriter) openFile() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.opts.perm)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+
+	if w.opts.interval != "" {
+		// 已有内容的文件以最后修改时间确定周期，保证重启后能正确归档
+		if w.size > 0 {
+			w.periodStart = w.periodOf(info.ModTime())
+		} else {
+			w.periodStart = w.periodOf(w.now())
+		}
+	}
+	return nil
+}
+
+// periodOf 返回 t 所在周期的起点（按墙上时间对齐到整点或零点）
+func (w *rotatingWriter) periodOf(t time.Time) time.Time {
+	t = t.In(w.opts.location)
+	switch w.opts.interval {
+	case rotateHourly:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, w.opts.location)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, w.opts.location)
+	}
+}
+
+// stampLayout 返回备份文件名中周期部分的时间格式
+func (w *rotatingWriter) stampLayout() string {
+	if w.opts.interval == rotateHourly {
+		return "2006-01-02T15"
+	}
+	return "2006-01-02"
+}
+
+// tryRotate 在写入前轮转，失败但当前文件已重新打开时只报告错误，日志继续写入当前文件
+func (w *rotatingWriter) tryRotate() error {
+	err := w.rotate()
+	if err != nil && w.file != nil {
+		fmt.Fprintf(os.Stderr, "logger: rotate %s: %v\n", w.path, err)
+		return nil
+	}
+	return err
+}
+
+// rotate 关闭当前文件，重命名为备份文件，然后打开新文件（调用方需持有锁）
+//
+// 重命名或打开新文件失败时以追加模式重新打开 path，避免一次失败的轮转使后续写入全部失败
+func (w *rotatingWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	backup := w.nextBackupName()
+	if err := os.Rename(w.path, backup); err != nil {
+		return w.reopen(err)
+	}
+
+	if err := w.openFile(); err != nil {
+		return w.reopen(err)
+	}
+
+	if w.opts.compress {
+		w.compressing.Add(1)
+		go func() {
+			defer w.compressing.Done()
+			// 压缩失败时保留未压缩的备份，只报告错误
+			if err := compressFile(backup, w.opts.perm); err != nil {
+				fmt.Fprintf(os.Stderr, "logger: compress %s: %v\n", backup, err)
+			}
+		}()
+	}
+
+	w.prune()
+	return nil
+}
+
+// reopen 轮转失败后重新打开 path，返回轮转的错误
+func (w *rotatingWriter) reopen(err error) error {
+	if openErr := w.openFile(); openErr != nil {
+		return errors.Join(err, openErr)
+	}
+	return err
+}
+
+// compressFile 将 path 压缩为权限为 perm 的 path.gz 后删除原文件
+//
+// 先写入临时文件再重命名，避免留下不完整的 .gz；原文件已被删除（例如被 prune 清理）时不保留压缩结果
+func compressFile(path string, perm os.FileMode) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer src.Close()
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if os.IsNotExist(err) {
+			// 压缩期间原文件已被 prune 清理，压缩结果同样不再保留
+			_ = os.Remove(path + ".gz")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// nextBackupName 计算下一个备份文件名
+func (w *rotatingWriter) nextBackupName() string {
+	if w.opts.interval == "" {
+		next := 1
+		if backups := w.backups(); len(backups) > 0 {
+			next = backups[len(backups)-1].index + 1
+		}
+		return fmt.Sprintf("%s.%d", w.path, next)
+	}
+
+	ext := filepath.Ext(w.path)
+	stem := strings.TrimSuffix(w.path, ext)
+	stamp := w.periodStart.Format(w.stampLayout())
+
+	name := fmt.Sprintf("%s-%s%s", stem, stamp, ext)
+	for i := 1; fileExists(name) || fileExists(name+".gz"); i++ {
+		name = fmt.Sprintf("%s-%s.%d%s", stem, stamp, i, ext)
+	}
+	return name
+}
+
+// backupFile 描述一个已轮转的备份文件
+type backupFile struct {
+	path  string
+	stamp string // 所属周期，仅时间轮转时有值
+	index int
+	// compressed 是否为压缩后的 .gz 文件
+	compressed bool
+}
+
+// backups 列出当前所有备份文件，按从旧到新排序
+func (w *rotatingWriter) backups() []backupFile {
+	dir := filepath.Dir(w.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []backupFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if b, ok := w.parseBackupName(entry.Name()); ok {
+			b.path = filepath.Join(dir, entry.Name())
+			files = append(files, b)
+		}
+	}
+	// 压缩完成到删除原文件之间，同一备份的两个文件同时存在，只保留未压缩的一个
+	files = slices.DeleteFunc(files, func(b backupFile) bool {
+		return b.compressed && fileExists(strings.TrimSuffix(b.path, ".gz"))
+	})
+
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].stamp != files[j].stamp {
+			return files[i].stamp < files[j].stamp
+		}
+		return files[i].index < files[j].index
+	})
+	return files
+}
+
+// parseBackupName 判断文件名是否为当前日志的备份，并解析其周期和序号
+func (w *rotatingWriter) parseBackupName(name string) (backupFile, bool) {
+	base := filepath.Base(w.path)
+	name, compressed := strings.CutSuffix(name, ".gz")
+
+	if w.opts.interval == "" {
+		rest, ok := strings.CutPrefix(name, base+".")
+		if !ok {
+			return backupFile{}, false
+		}
+		index, err := strconv.Atoi(rest)
+		if err != nil || index <= 0 {
+			return backupFile{}, false
+		}
+		return backupFile{index: index, compressed: compressed}, true
+	}
+
+	ext := filepath.Ext(base)
+	rest, ok := strings.CutPrefix(name, strings.TrimSuffix(base, ext)+"-")
+	if !ok {
+		return backupFile{}, false
+	}
+	rest, ok = strings.CutSuffix(rest, ext)
+	if !ok {
+		return backupFile{}, false
+	}
+
+	stamp, indexPart, hasIndex := strings.Cut(rest, ".")
+	if _, err := time.Parse(w.stampLayout(), stamp); err != nil {
+		return backupFile{}, false
+	}
+	b := backupFile{stamp: stamp, compressed: compressed}
+	if hasIndex {
+		index, err := strconv.Atoi(indexPart)
+		if err != nil || index <= 0 {
+			return backupFile{}, false
+		}
+		b.index = index
+	}
+	return b, true
+}
+
+// prune 删除超出 maxBackups 数量的最旧备份
+func (w *rotatingWriter) prune() {
+	if w.opts.maxBackups <= 0 {
+		return
+	}
+	backups := w.backups()
+	for len(backups) > w.opts.maxBackups {
+		_ = os.Remove(backups[0].path)
+		if !backups[0].compressed {
+			// 可能正在压缩，一并删除压缩结果
+			_ = os.Remove(backups[0].path + ".gz")
+		}
+		backups = backups[1:]
+	}
+}
+
+// fileExists 判断文件是否存在
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// samplingSummaryInterval 输出丢弃统计的最小间隔
+const samplingSummaryInterval = 10 * time.Second
+
+// defaultSampleTick SampleFirst 计数器的默认重置间隔
+const defaultSampleTick = time.Second
+
+// samplingHandler 对记录进行采样和限流的 handler 包装
+//
+// SampleEvery 为 N 时，DEBUG/INFO 级别每 N 条只输出第 1 条，WARN 及以上始终输出；
+// SampleFirst 为 N 时，每个计数周期内相同级别和消息的记录只输出前 N 条，
+// 之后每 SampleThereafter 条输出 1 条（所有级别，SampleThereafter 为 0 时全部丢弃）；
+// RateLimit 限制每秒输出的记录数（所有级别），超出的记录被丢弃。
+// 有记录被丢弃时，每隔 samplingSummaryInterval 输出一条 "dropped N messages" 的 WARN 日志。
+type samplingHandler struct {
+	inner slog.Handler
+	state *samplingState
+}
+
+// samplingState 同一 handler 及其 With 派生 handler 共享的计数状态
+type samplingState struct {
+	root        slog.Handler // 输出统计日志的 handler，不带 With 添加的属性
+	sampleEvery uint64
+	first       uint64        // SampleFirst，0 表示不按消息采样
+	thereafter  uint64        // SampleThereafter
+	tick        time.Duration // first 计数器的重置间隔
+	rateLimit   int
+	now         func() time.Time
+	counter     atomic.Uint64 // 参与采样的记录数
+	metrics     *logMetrics   // 启用 Metrics 时统计丢弃数，否则为 nil
+	sampled     atomic.Int64  // 上次输出汇总后被采样丢弃的记录数，见 [suppressionSummary]
+	limited     atomic.Int64  // 上次输出汇总后被限流丢弃的记录数
+
+	mu          sync.Mutex
+	windowStart time.Time // 当前限流窗口的起点
+	windowCount int       // 当前窗口内已输出的记录数
+	tickStart   time.Time // 当前计数周期的起点
+	counts      map[sampleKey]uint64
+	dropped     int64 // 上次统计后丢弃的记录数
+	lastSummary time.Time
+}
+
+// sampleKey SampleFirst 的计数键
+type sampleKey struct {
+	level slog.Level
+	msg   string
+}
+
+// newSamplingHandler 创建采样限流 handler，按消息采样通过 [samplingState.sampleFirst] 配置
+func newSamplingHandler(inner slog.Handler, sampleEvery, rateLimit int) *samplingHandler {
+	now := time.Now()
+	return &samplingHandler{
+		inner: inner,
+		state: &samplingState{
+			root:        inner,
+			sampleEvery: uint64(sampleEvery),
+			rateLimit:   rateLimit,
+			now:         time.Now,
+			windowStart: now,
+			tickStart:   now,
+			lastSummary: now,
+		},
+	}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *samplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *samplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := h.state
+	allowed := s.sample(r.Level) && s.sampleMessage(r.Level, r.Message)
+	if !allowed {
+		s.sampled.Add(1)
+	} else if allowed = s.limit(); !allowed {
+		s.limited.Add(1)
+	}
+	if err := s.summarize(ctx, !allowed); err != nil {
+		return err
+	}
+	if !allowed {
+		return nil
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{inner: h.inner.WithAttrs(attrs), state: h.state}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{inner: h.inner.WithGroup(name), state: h.state}
+}
+
+// sample 判断记录是否通过采样
+func (s *samplingState) sample(level slog.Level) bool {
+	if s.sampleEvery <= 1 || level >= slog.LevelWarn {
+		return true
+	}
+	return (s.counter.Add(1)-1)%s.sampleEvery == 0
+}
+
+// sampleFirst 启用按消息采样：每 tick 内相同级别和消息的记录输出前 first 条，之后每 thereafter 条输出 1 条
+//
+// tick 为 0 时使用默认值 1s
+func (s *samplingState) sampleFirst(first, thereafter int, tick time.Duration) {
+	if tick <= 0 {
+		tick = defaultSampleTick
+	}
+	s.first = uint64(first)
+	s.thereafter = uint64(thereafter)
+	s.tick = tick
+	s.counts = make(map[sampleKey]uint64)
+}
+
+// sampleMessage 判断记录是否通过按消息采样
+//
+// 计数器在每个 tick 开始时清空，因此 counts 最多保存一个周期内出现过的消息
+func (s *samplingState) sampleMessage(level slog.Level, msg string) bool {
+	if s.first == 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.tickStart) >= s.tick {
+		s.tickStart = now
+		clear(s.counts)
+	}
+	key := sampleKey{level: level, msg: msg}
+	n := s.counts[key] + 1
+	s.counts[key] = n
+	if n <= s.first {
+		return true
+	}
+	return s.thereafter > 0 && (n-s.first-1)%s.thereafter == 0
+}
+
+// limit 判断记录是否在当前秒的限额内
+func (s *samplingState) limit() bool {
+	if s.rateLimit <= 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.windowStart) >= time.Second {
+		s.windowStart = now
+		s.windowCount = 0
+	}
+	if s.windowCount >= s.rateLimit {
+		return false
+	}
+	s.windowCount++
+	return true
+}
+
+// summarize 记录丢弃数，并在距上次统计超过间隔时输出丢弃统计
+func (s *samplingState) summarize(ctx context.Context, dropped bool) error {
+	s.mu.Lock()
+	if dropped {
+		s.dropped++
+		if s.metrics != nil {
+			s.metrics.dropped.Add(1)
+		}
+	}
+	now := s.now()
+	if s.dropped == 0 || now.Sub(s.lastSummary) < samplingSummaryInterval {
+		s.mu.Unlock()
+		return nil
+	}
+	n := s.dropped
+	s.dropped = 0
+	s.lastSummary = now
+	s.mu.Unlock()
+
+	if !s.root.Enabled(ctx, slog.LevelWarn) {
+		return nil
+	}
+	r := slog.NewRecord(now, slog.LevelWarn, fmt.Sprintf("dropped %d messages", n), 0)
+	r.AddAttrs(slog.Int64("dropped", n))
+	return s.root.Handle(ctx, r)
+}
+
+// suppressionSummary 在 Sync / Close 时输出被采样、限流丢弃以及被去重合并的记录总数
+//
+// 在启用采样、限流或去重时使用。输出一条 "log suppression summary: ..." 的 WARN 日志，
+// 只包含已启用的部分；计数在输出后清零，每条被丢弃的记录只统计一次；没有记录被丢弃或合并时不输出
+type suppressionSummary struct {
+	root     slog.Handler // 输出汇总日志的 handler，位于采样和去重之后
+	now      func() time.Time
+	sampling *samplingState // 未启用采样和限流时为 nil
+	dedup    *dedupState    // 未启用去重时为 nil
+}
+
+// Sync 输出汇总日志
+func (s *suppressionSummary) Sync() error {
+	var sampled, limited, merged int64
+	if s.sampling != nil {
+		sampled, limited = s.sampling.sampled.Swap(0), s.sampling.limited.Swap(0)
+	}
+	if s.dedup != nil {
+		merged = s.dedup.merged.Swap(0)
+	}
+	if sampled == 0 && limited == 0 && merged == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	if !s.root.Enabled(ctx, slog.LevelWarn) {
+		return nil
+	}
+	var parts []string
+	if s.sampling != nil {
+		parts = append(parts, fmt.Sprintf("dropped=%d by sampling, %d by rate limit", sampled, limited))
+	}
+	if s.dedup != nil {
+		parts = append(parts, fmt.Sprintf("merged=%d by dedup", merged))
+	}
+	r := slog.NewRecord(s.now(), slog.LevelWarn, "log suppression summary: "+strings.Join(parts, ", "), 0)
+	if s.sampling != nil {
+		r.AddAttrs(slog.Int64("sampled", sampled), slog.Int64("rate_limited", limited))
+	}
+	if s.dedup != nil {
+		r.AddAttrs(slog.Int64("deduplicated", merged))
+	}
+	return s.root.Handle(ctx, r)
+}
+
+// Close 实现 io.Closer 接口，输出汇总日志
+func (s *suppressionSummary) Close() error {
+	return s.Sync()
+}
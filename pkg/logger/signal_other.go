@@ -0,0 +1,11 @@
+//go:build windows || plan9
+
+package logger
+
+import "os"
+
+// reloadSignals 当前平台不支持 SIGHUP，WatchSignals 为空操作
+var reloadSignals []os.Signal
+
+// catchSIGPIPE 当前平台没有 SIGPIPE，为空操作
+func catchSIGPIPE() {}
@@ -0,0 +1,26 @@
+//go:build !windows && !plan9
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// reloadSignals 触发重新加载配置的信号，见 [WatchSignals]
+var reloadSignals = []os.Signal{syscall.SIGHUP}
+
+// sigpipeOnce 保证只注册一次 SIGPIPE
+var sigpipeOnce sync.Once
+
+// catchSIGPIPE 捕获 SIGPIPE，使写入已关闭的 stdout/stderr 时返回 EPIPE 而不是终止进程
+//
+// 使用 signal.Notify 而不是 signal.Ignore：忽略的信号会被子进程继承，捕获的不会。
+// channel 不需要读取，信号在 channel 已满时直接丢弃
+func catchSIGPIPE() {
+	sigpipeOnce.Do(func() {
+		signal.Notify(make(chan os.Signal, 1), syscall.SIGPIPE)
+	})
+}
@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"log/slog"
+	"slices"
+)
+
+// sortHandler 按键名排序属性的 handler 包装器，用于 text 格式的 SortKeys
+//
+// slog.TextHandler 按添加顺序输出属性，且 WithAttrs 添加的属性总在记录属性之前，
+// 因此这里自行保存 With、WithGroup 添加的属性，记录时与记录属性合并、逐层排序后一次交给 inner
+type sortHandler struct {
+	inner slog.Handler
+	// frames 从顶层到当前分组的各层属性，frames[0] 为顶层
+	frames []sortFrame
+}
+
+// sortFrame 一层分组及其中通过 WithAttrs 添加的属性
+type sortFrame struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// newSortHandler 创建按键名排序属性的 handler
+func newSortHandler(inner slog.Handler) *sortHandler {
+	return &sortHandler{inner: inner, frames: []sortFrame{{}}}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *sortHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *sortHandler) Handle(ctx context.Context, r slog.Record) error {
+	last := len(h.frames) - 1
+	attrs := make([]slog.Attr, 0, len(h.frames[last].attrs)+r.NumAttrs())
+	attrs = append(attrs, h.frames[last].attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	// 从最内层分组开始，逐层包装为上一层的分组属性
+	for i := last; i > 0; i-- {
+		group := slog.Attr{Key: h.frames[i].name, Value: slog.GroupValue(sortAttrs(attrs)...)}
+		attrs = append(slices.Clip(h.frames[i-1].attrs), group)
+	}
+
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	out.AddAttrs(sortAttrs(attrs)...)
+	return h.inner.Handle(ctx, out)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *sortHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	frames := slices.Clone(h.frames)
+	last := &frames[len(frames)-1]
+	last.attrs = append(slices.Clip(last.attrs), attrs...)
+	return &sortHandler{inner: h.inner, frames: frames}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *sortHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	frames := append(slices.Clip(h.frames), sortFrame{name: name})
+	return &sortHandler{inner: h.inner, frames: frames}
+}
+
+// sortAttrs 返回按键名稳定排序的属性，分组内的属性同样排序，空键分组内联到当前层级
+func sortAttrs(attrs []slog.Attr) []slog.Attr {
+	sorted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		a.Value = a.Value.Resolve()
+		if a.Value.Kind() == slog.KindGroup {
+			children := sortAttrs(a.Value.Group())
+			if a.Key == "" {
+				sorted = append(sorted, children...)
+				continue
+			}
+			a.Value = slog.GroupValue(children...)
+		}
+		sorted = append(sorted, a)
+	}
+	slices.SortStableFunc(sorted, func(a, b slog.Attr) int {
+		return cmp.Compare(a.Key, b.Key)
+	})
+	return sorted
+}
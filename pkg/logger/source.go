@@ -0,0 +1,45 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+)
+
+// 调用位置的显示方式
+const (
+	sourceModeFull    = "full"    // 完整路径: /home/app/pkg/logger/logger.go:42
+	sourceModeShort   = "short"   // 文件名: logger.go:42
+	sourceModePackage = "package" // 包目录 + 文件名: logger/logger.go:42
+)
+
+// formatSource 按 SourceMode 格式化调用位置，空值等同于 short
+func formatSource(src *slog.Source, mode string) string {
+	file := src.File
+	switch mode {
+	case sourceModeFull:
+	case sourceModePackage:
+		file = filepath.Join(filepath.Base(filepath.Dir(file)), filepath.Base(file))
+	default:
+		file = filepath.Base(file)
+	}
+	return fmt.Sprintf("%s:%d", file, src.Line)
+}
+
+// sourceAttr 构造 source 内置字段并经过 ReplaceAttr 处理，返回 false 表示丢弃该字段
+//
+// 与 slog 内置 handler 一致，传给 ReplaceAttr 的值为 *slog.Source；
+// ReplaceAttr 未改写时使用 clip 裁剪 "file:line" 作为默认显示
+func sourceAttr(replace func([]string, slog.Attr) slog.Attr, src *slog.Source, clip func(string) string) (slog.Attr, bool) {
+	a := slog.Any(slog.SourceKey, src)
+	if replace != nil {
+		a = replace(nil, a)
+		if a.Key == "" {
+			return a, false
+		}
+	}
+	if s, ok := a.Value.Any().(*slog.Source); ok {
+		a.Value = slog.StringValue(clip(fmt.Sprintf("%s:%d", s.File, s.Line)))
+	}
+	return a, true
+}
@@ -0,0 +1,97 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth 堆栈最多记录的帧数
+const maxStackDepth = 32
+
+// packageDir 本包源文件所在目录，用于在堆栈中跳过本包的辅助函数
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// stackHandler 为达到指定级别的记录添加 stacktrace 属性的 handler 包装
+//
+// 堆栈从记录的调用位置开始，跳过 log/slog 和本包的辅助函数（例如 logger.Error），
+// 第一帧即为调用方代码。获取堆栈开销较大，只应对 ERROR 等低频级别启用。
+type stackHandler struct {
+	inner    slog.Handler
+	minLevel slog.Level
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *stackHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *stackHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.minLevel {
+		if stack := captureStack(); stack != "" {
+			r.AddAttrs(slog.String("stacktrace", stack))
+		}
+	}
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *stackHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &stackHandler{inner: h.inner.WithAttrs(attrs), minLevel: h.minLevel}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *stackHandler) WithGroup(name string) slog.Handler {
+	return &stackHandler{inner: h.inner.WithGroup(name), minLevel: h.minLevel}
+}
+
+// captureStack 获取当前 goroutine 的堆栈，跳过 log/slog 和本包的帧
+//
+// 输出格式与 panic 堆栈一致：每帧两行，函数名和 "\tfile:line"
+func captureStack() string {
+	pcs := make([]uintptr, maxStackDepth+16)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var b strings.Builder
+	depth := 0
+	started := false
+	for {
+		frame, more := frames.Next()
+		if !started && isLoggerFrame(frame) {
+			if !more {
+				break
+			}
+			continue
+		}
+		started = true
+
+		b.WriteString(frame.Function)
+		b.WriteString("\n\t")
+		b.WriteString(frame.File)
+		b.WriteByte(':')
+		b.WriteString(strconv.Itoa(frame.Line))
+		b.WriteByte('\n')
+
+		depth++
+		if !more || depth >= maxStackDepth {
+			break
+		}
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// isLoggerFrame 判断堆栈帧是否属于 log/slog 或本包（不包括测试文件）
+func isLoggerFrame(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, "log/slog.") {
+		return true
+	}
+	return filepath.Dir(frame.File) == packageDir && !strings.HasSuffix(frame.File, "_test.go")
+}
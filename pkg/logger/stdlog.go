@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// NewStdLogger 返回将输出转发到全局 logger 的标准库 *log.Logger，每次输出记录为一条 level 级别的日志
+//
+// 用于只接受 *log.Logger 的旧代码或第三方库，例如 http.Server.ErrorLog：
+//
+//	srv := &http.Server{ErrorLog: logger.NewStdLogger("ERROR")}
+//
+// 无法识别的级别按 INFO 处理。应在 InitCfg / InitEnv 之后调用。
+func NewStdLogger(level string) *log.Logger {
+	return log.New(newStdWriter(parseLevel(level)), "", 0)
+}
+
+// RedirectStdLog 将标准库 log 包的默认输出重定向到全局 logger，记录为 INFO 级别
+//
+// 会清除 log 包的时间等前缀标志，由 handler 负责格式化。应在 InitCfg / InitEnv 之后调用。
+func RedirectStdLog() {
+	log.SetFlags(0)
+	log.SetOutput(newStdWriter(slog.LevelInfo))
+}
+
+// stdWriter 将标准库 log 的每次输出转换为一条 slog 记录
+type stdWriter struct {
+	handler slog.Handler
+	level   slog.Level
+}
+
+// newStdWriter 创建基于当前全局 logger 的 stdWriter
+func newStdWriter(level slog.Level) *stdWriter {
+	return &stdWriter{handler: slog.Default().Handler(), level: level}
+}
+
+// Write 实现 io.Writer 接口，多行内容作为同一条记录的消息
+func (w *stdWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, w.level) {
+		return len(p), nil
+	}
+	var pcs [1]uintptr
+	// 跳过 runtime.Callers、Write、log.(*Logger).output 和 log.Print 等函数
+	runtime.Callers(4, pcs[:])
+	msg := strings.TrimSuffix(string(p), "\n")
+	r := slog.NewRecord(time.Now(), w.level, msg, pcs[0])
+	return len(p), w.handler.Handle(ctx, r)
+}
@@ -0,0 +1,137 @@
+package logger
+
+import (
+	"log/slog"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// structFields 缓存各结构体类型的字段信息，键为 reflect.Type，值为 *structInfo
+var structFields sync.Map
+
+// structInfo 结构体类型的字段信息
+type structInfo struct {
+	// tagged 该类型（包括嵌套的结构体字段）是否有带 log 标签的字段，没有时按原样输出
+	tagged bool
+	fields []structField
+}
+
+// structField 结构体字段的输出方式
+type structField struct {
+	index  int
+	name   string
+	redact bool // log:"redact"，值替换为 "***"
+	inline bool // 匿名嵌入的结构体，字段展开到当前层级
+}
+
+// redactStruct 对带 log 标签的结构体（或结构体指针）值进行脱敏，转换为按字段展开的分组
+//
+// 标记 log:"redact" 的字段替换为 "***"，标记 log:"-" 的字段被省略；
+// 字段名优先使用 json 标签中的名称。没有任何 log 标签的类型保持原值，不改变原有的输出
+func redactStruct(a slog.Attr) (slog.Attr, bool) {
+	if a.Value.Kind() != slog.KindAny {
+		return a, false
+	}
+	v := reflect.ValueOf(a.Value.Any())
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return a, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return a, false
+	}
+	info := structInfoOf(v.Type())
+	if !info.tagged {
+		return a, false
+	}
+	return slog.Attr{Key: a.Key, Value: slog.GroupValue(structAttrs(v, info)...)}, true
+}
+
+// structAttrs 将结构体的导出字段转换为属性，带 log 标签的嵌套结构体递归转换
+func structAttrs(v reflect.Value, info *structInfo) []slog.Attr {
+	attrs := make([]slog.Attr, 0, len(info.fields))
+	for _, f := range info.fields {
+		fv := v.Field(f.index)
+		if f.redact {
+			attrs = append(attrs, slog.String(f.name, redactedValue))
+			continue
+		}
+		if nested, ok := structValue(fv); ok {
+			if f.inline {
+				attrs = append(attrs, structAttrs(nested, structInfoOf(nested.Type()))...)
+			} else {
+				attrs = append(attrs, slog.Attr{Key: f.name, Value: slog.GroupValue(structAttrs(nested, structInfoOf(nested.Type()))...)})
+			}
+			continue
+		}
+		attrs = append(attrs, slog.Any(f.name, fv.Interface()))
+	}
+	return attrs
+}
+
+// structValue 返回需要递归脱敏的结构体值（可以是非空指针）
+func structValue(v reflect.Value) (reflect.Value, bool) {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return v, false
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || !structInfoOf(v.Type()).tagged {
+		return v, false
+	}
+	return v, true
+}
+
+// structInfoOf 返回结构体类型的字段信息，每个类型只解析一次
+func structInfoOf(t reflect.Type) *structInfo {
+	if info, ok := structFields.Load(t); ok {
+		return info.(*structInfo)
+	}
+	info, _ := structFields.LoadOrStore(t, buildStructInfo(t, map[reflect.Type]bool{}))
+	return info.(*structInfo)
+}
+
+// buildStructInfo 解析结构体类型的字段，visiting 用于避免递归类型无限展开
+func buildStructInfo(t reflect.Type, visiting map[reflect.Type]bool) *structInfo {
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	info := &structInfo{}
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		tag := sf.Tag.Get("log")
+		if tag == "-" {
+			info.tagged = true
+			continue
+		}
+		f := structField{index: i, name: sf.Name, redact: tag == "redact"}
+		if name, _, _ := strings.Cut(sf.Tag.Get("json"), ","); name != "" && name != "-" {
+			f.name = name
+		}
+		if f.redact {
+			info.tagged = true
+		} else if ft := indirectType(sf.Type); ft.Kind() == reflect.Struct && !visiting[ft] {
+			if buildStructInfo(ft, visiting).tagged {
+				info.tagged = true
+			}
+			f.inline = sf.Anonymous && sf.Tag.Get("json") == ""
+		}
+		info.fields = append(info.fields, f)
+	}
+	return info
+}
+
+// indirectType 返回指针指向的类型
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// globalHandler 全局 logger 使用的可替换 handler
+//
+// InitCfg 重新初始化（包括 [WatchSignals] 触发的重新加载）时只替换其中的根 handler，
+// 此前通过 With、WithGroup 派生的 logger 也会使用新的配置
+var globalHandler = newSwapHandler()
+
+// swapState 可替换 handler 及其派生 handler 共享的状态
+type swapState struct {
+	// mu 记录时持有读锁，替换时持有写锁，保证替换后不再有记录写入旧的输出
+	mu   sync.RWMutex
+	root slog.Handler
+	gen  uint64 // 每次替换递增，用于判断派生 handler 的缓存是否过期
+}
+
+// swapHandler 将记录转发到当前根 handler 的 handler
+//
+// 派生 handler 记录 With、WithGroup 操作，根 handler 替换后按顺序重新应用到新的根 handler 上
+type swapHandler struct {
+	state *swapState
+	ops   []func(slog.Handler) slog.Handler
+	cache atomic.Pointer[swapCache]
+}
+
+// swapCache 派生 handler 基于某一代根 handler 构建的结果
+type swapCache struct {
+	gen     uint64
+	handler slog.Handler
+}
+
+// newSwapHandler 创建可替换 handler，替换前丢弃所有记录
+func newSwapHandler() *swapHandler {
+	return &swapHandler{state: &swapState{root: slog.DiscardHandler}}
+}
+
+// swap 替换根 handler，等待正在进行的记录完成后返回
+func (h *swapHandler) swap(root slog.Handler) {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	h.state.root = root
+	h.state.gen++
+}
+
+// current 返回基于当前根 handler 的 handler，需持有 state.mu 读锁
+func (h *swapHandler) current() slog.Handler {
+	gen := h.state.gen
+	if c := h.cache.Load(); c != nil && c.gen == gen {
+		return c.handler
+	}
+	handler := h.state.root
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+	h.cache.Store(&swapCache{gen: gen, handler: handler})
+	return handler
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *swapHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	h.state.mu.RLock()
+	defer h.state.mu.RUnlock()
+	return h.current().Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *swapHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.state.mu.RLock()
+	defer h.state.mu.RUnlock()
+	return h.current().Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *swapHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithAttrs(attrs) })
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *swapHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler { return handler.WithGroup(name) })
+}
+
+// with 返回追加了一个派生操作的 handler
+func (h *swapHandler) with(op func(slog.Handler) slog.Handler) *swapHandler {
+	ops := append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &swapHandler{state: h.state, ops: ops}
+}
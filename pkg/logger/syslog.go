@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// syslogFacilities syslog facility 名称与编号（RFC 5424）
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// defaultSyslogFacility 未配置 SyslogFacility 时使用的 facility
+const defaultSyslogFacility = "user"
+
+func init() {
+	registerOutput("syslog", newSyslogOutput)
+	registerOutput("syslog+tcp", newSyslogOutput)
+	registerOutput("syslog+udp", newSyslogOutput)
+}
+
+// newSyslogOutput 创建 syslog 输出，支持以下目标：
+//   - syslog: 本地 syslog 守护进程（/dev/log 等）
+//   - syslog:///path/to/socket: 指定的本地 Unix socket
+//   - syslog://host:514、syslog+udp://host:514: 远程 UDP
+//   - syslog+tcp://host:514: 远程 TCP（使用 octet-counting 分帧）
+//
+// 记录按 format 格式化后作为 RFC 5424 消息的 MSG 部分发送，
+// PRI 由 SyslogFacility 和记录级别计算。
+func newSyslogOutput(cfg *Config, target, format string, level slog.Leveler) (slog.Handler, io.Closer, error) {
+	if !syslogSupported {
+		return nil, nil, fmt.Errorf("syslog output is not supported on this platform")
+	}
+
+	network, addr, err := parseSyslogTarget(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	facility := cfg.SyslogFacility
+	if facility == "" {
+		facility = defaultSyslogFacility
+	}
+	code, ok := syslogFacilities[facility]
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid syslog facility: %q", facility)
+	}
+
+	sink := &syslogSink{
+		network:  network,
+		addr:     addr,
+		facility: code,
+		hostname: hostname(),
+		appName:  filepath.Base(os.Args[0]),
+		pid:      os.Getpid(),
+	}
+	if err := sink.connect(); err != nil {
+		return nil, nil, fmt.Errorf("connect to syslog %s: %w", target, err)
+	}
+	return &syslogHandler{inner: createHandler(cfg, format, sink, level), sink: sink}, sink, nil
+}
+
+// parseSyslogTarget 解析 syslog 输出目标，network 为空表示本地默认 socket
+func parseSyslogTarget(target string) (network, addr string, err error) {
+	if target == "syslog" {
+		return "", "", nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid syslog target %q: %w", target, err)
+	}
+	if u.Host == "" {
+		if u.Path == "" {
+			return "", "", nil
+		}
+		return "unixgram", u.Path, nil
+	}
+
+	addr = u.Host
+	if u.Port() == "" {
+		addr = net.JoinHostPort(u.Hostname(), "514")
+	}
+	if u.Scheme == "syslog+tcp" {
+		return "tcp", addr, nil
+	}
+	return "udp", addr, nil
+}
+
+// syslogHandler 将记录交给 inner 格式化，并为写入 sink 的消息附带记录的级别和时间
+type syslogHandler struct {
+	inner slog.Handler
+	sink  *syslogSink
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *syslogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle 实现 slog.Handler 接口
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.sink.mu.Lock()
+	defer h.sink.mu.Unlock()
+
+	h.sink.level = r.Level
+	h.sink.time = r.Time
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{inner: h.inner.WithAttrs(attrs), sink: h.sink}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{inner: h.inner.WithGroup(name), sink: h.sink}
+}
+
+// syslogSink 向 syslog 发送 RFC 5424 消息的写入器，写入失败时重新连接一次
+//
+// Write 只由 syslogHandler 在持有 mu 时调用，level 和 time 为当前记录的级别和时间
+type syslogSink struct {
+	network  string
+	addr     string
+	facility int
+	hostname string
+	appName  string
+	pid      int
+
+	mu    sync.Mutex
+	conn  net.Conn
+	level slog.Level
+	time  time.Time
+}
+
+// Write 实现 io.Writer 接口，p 为一条格式化后的记录
+func (s *syslogSink) Write(p []byte) (int, error) {
+	msg := s.format(p)
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := s.conn.Write(msg); err != nil {
+		// 连接可能已断开（例如 syslog 守护进程重启），重连后重试一次
+		_ = s.conn.Close()
+		s.conn = nil
+		if err := s.connect(); err != nil {
+			return 0, err
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close 实现 io.Closer 接口
+func (s *syslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// connect 建立到 syslog 的连接
+func (s *syslogSink) connect() error {
+	if s.network == "" {
+		conn, err := dialLocalSyslog()
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		return nil
+	}
+	conn, err := net.DialTimeout(s.network, s.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+// format 构造 RFC 5424 消息：<PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID SD MSG
+func (s *syslogSink) format(p []byte) []byte {
+	// 去掉 handler 添加的换行符
+	for len(p) > 0 && (p[len(p)-1] == '\n' || p[len(p)-1] == '\r') {
+		p = p[:len(p)-1]
+	}
+
+	t := s.time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	pri := s.facility*8 + syslogSeverity(s.level)
+
+	msg := make([]byte, 0, len(p)+128)
+	msg = append(msg, '<')
+	msg = strconv.AppendInt(msg, int64(pri), 10)
+	msg = append(msg, ">1 "...)
+	msg = t.AppendFormat(msg, "2006-01-02T15:04:05.000000Z07:00")
+	msg = append(msg, ' ')
+	msg = append(msg, syslogHeaderField(s.hostname)...)
+	msg = append(msg, ' ')
+	msg = append(msg, syslogHeaderField(s.appName)...)
+	msg = append(msg, ' ')
+	msg = strconv.AppendInt(msg, int64(s.pid), 10)
+	msg = append(msg, " - - "...)
+	msg = append(msg, p...)
+
+	// TCP 使用 octet-counting 分帧（RFC 6587）
+	if s.network == "tcp" {
+		framed := strconv.AppendInt(make([]byte, 0, len(msg)+8), int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		return append(framed, msg...)
+	}
+	return msg
+}
+
+// syslogHeaderField 返回头部字段值，空值或包含非可打印 ASCII 字符时替换为 "-"
+func syslogHeaderField(s string) string {
+	if s == "" {
+		return "-"
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < 33 || s[i] > 126 {
+			return "-"
+		}
+	}
+	return s
+}
+
+// errSyslogUnavailable 找不到本地 syslog socket
+var errSyslogUnavailable = errors.New("local syslog socket not found")
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package logger
+
+import "net"
+
+// syslogSupported 当前平台是否支持 syslog 输出
+const syslogSupported = false
+
+// dialLocalSyslog 当前平台不支持本地 syslog
+func dialLocalSyslog() (net.Conn, error) {
+	return nil, errSyslogUnavailable
+}
@@ -0,0 +1,23 @@
+//go:build !windows && !plan9
+
+package logger
+
+import "net"
+
+// syslogSupported 当前平台是否支持 syslog 输出
+const syslogSupported = true
+
+// localSyslogPaths 本地 syslog socket 的常见路径
+var localSyslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// dialLocalSyslog 连接本地 syslog 守护进程
+func dialLocalSyslog() (net.Conn, error) {
+	for _, path := range localSyslogPaths {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				return conn, nil
+			}
+		}
+	}
+	return nil, errSyslogUnavailable
+}
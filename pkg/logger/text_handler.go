@@ -1,16 +1,29 @@
 package logger
 
 import (
+	"bytes"
+	"context"
 	"io"
 	"log/slog"
+	"strconv"
+	"sync"
 	"time"
 )
 
+// levelColumnWidth AlignColumns 时级别列的宽度，等于最长的内置级别名称 (TRACE, DEBUG, ERROR)
+const levelColumnWidth = 5
+
 // newTextHandler 创建自定义 Text handler，支持灵活的时间格式
 func newTextHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, timezone string) *slog.TextHandler {
 	if opts == nil {
 		opts = &slog.HandlerOptions{}
 	}
+	opts.ReplaceAttr = textReplaceAttr(opts.ReplaceAttr, timeFormat, timezone)
+	return slog.NewTextHandler(w, opts)
+}
+
+// textReplaceAttr 格式化顶级的 time 和 level 字段后再调用 original
+func textReplaceAttr(original func([]string, slog.Attr) slog.Attr, timeFormat string, timezone string) func([]string, slog.Attr) slog.Attr {
 	if timeFormat == "" {
 		timeFormat = "datetime"
 	}
@@ -19,11 +32,12 @@ func newTextHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, t
 	loc := loadTimezone(timezone)
 
 	// 使用 ReplaceAttr 来自定义时间格式
-	originalReplace := opts.ReplaceAttr
-	opts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
-		// 先执行原有的 ReplaceAttr（如果有）
-		if originalReplace != nil {
-			a = originalReplace(groups, a)
+	return func(groups []string, a slog.Attr) slog.Attr {
+		// 扩展级别使用自定义名称
+		if len(groups) == 0 && a.Key == slog.LevelKey {
+			if level, ok := a.Value.Any().(slog.Level); ok {
+				a = slog.String(slog.LevelKey, levelName(level))
+			}
 		}
 
 		// 只处理顶级的 time 字段
@@ -34,32 +48,105 @@ func newTextHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, t
 					t = t.In(loc)
 				}
 				// 格式化时间
-				formatted := formatTimeString(t, timeFormat)
-				return slog.String(slog.TimeKey, formatted)
+				a = slog.String(slog.TimeKey, formatTimeString(t, timeFormat))
 			}
 		}
+
+		// 再执行原有的 ReplaceAttr（如果有），与其他格式一致，接收到的 time、level 为格式化后的字符串
+		if original != nil {
+			a = original(groups, a)
+		}
 		return a
 	}
+}
 
-	return slog.NewTextHandler(w, opts)
+// alignedTextHandler 对齐列的 text handler，用于 AlignColumns
+//
+// time 和 level 由自身输出，level 补齐到 levelColumnWidth，使之后的 msg 列起始位置一致；
+// 其余部分交给写入共享缓冲区的 slog.TextHandler，再与前缀一起写入输出
+type alignedTextHandler struct {
+	state   *alignedState
+	inner   slog.Handler
+	replace func([]string, slog.Attr) slog.Attr
+}
+
+// alignedState 对齐 handler 及其派生 handler 共享的输出状态
+type alignedState struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	out io.Writer
+}
+
+// newAlignedTextHandler 创建对齐列的 text handler，参数与 [newTextHandler] 相同
+func newAlignedTextHandler(w io.Writer, opts *slog.HandlerOptions, timeFormat string, timezone string) *alignedTextHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	replace := textReplaceAttr(opts.ReplaceAttr, timeFormat, timezone)
+	state := &alignedState{out: w}
+
+	innerOpts := *opts
+	innerOpts.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+			return slog.Attr{}
+		}
+		return replace(groups, a)
+	}
+	return &alignedTextHandler{
+		state:   state,
+		inner:   slog.NewTextHandler(&state.buf, &innerOpts),
+		replace: replace,
+	}
+}
+
+// Enabled 实现 slog.Handler 接口
+func (h *alignedTextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
 }
 
-// formatTimeString 根据配置格式化时间为字符串
-func formatTimeString(t time.Time, timeFormat string) string {
-	switch timeFormat {
-	case "rfc3339":
-		return t.Format(time.RFC3339)
-	case "rfc3339ms":
-		return t.Format("2006-01-02T15:04:05.000Z07:00")
-	case "time":
-		return t.Format("15:04:05")
-	case "timems":
-		return t.Format("15:04:05.000")
-	case "datetime", "":
-		// 默认格式：日期时间（秒精度）
-		return t.Format("2006-01-02 15:04:05")
-	default:
-		// 自定义格式
-		return t.Format(timeFormat)
+// Handle 实现 slog.Handler 接口
+func (h *alignedTextHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+
+	buf := &h.state.buf
+	buf.Reset()
+	if !r.Time.IsZero() {
+		if a := h.replace(nil, slog.Time(slog.TimeKey, r.Time)); a.Key != "" {
+			h.writePair(buf, a, 0)
+		}
+	}
+	if a := h.replace(nil, slog.Any(slog.LevelKey, r.Level)); a.Key != "" {
+		h.writePair(buf, a, levelColumnWidth)
+	}
+	if err := h.inner.Handle(ctx, r); err != nil {
+		return err
 	}
+	_, err := h.state.out.Write(buf.Bytes())
+	return err
+}
+
+// writePair 写入 "key=value "，值不足 width 时用空格补齐
+func (h *alignedTextHandler) writePair(buf *bytes.Buffer, a slog.Attr, width int) {
+	value := logfmtValue(a.Value.Resolve())
+	buf.WriteString(a.Key)
+	buf.WriteByte('=')
+	if logfmtNeedsQuote(value) {
+		value = strconv.Quote(value)
+	}
+	buf.WriteString(value)
+	for n := len(value); n < width; n++ {
+		buf.WriteByte(' ')
+	}
+	buf.WriteByte(' ')
+}
+
+// WithAttrs 实现 slog.Handler 接口
+func (h *alignedTextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &alignedTextHandler{state: h.state, inner: h.inner.WithAttrs(attrs), replace: h.replace}
+}
+
+// WithGroup 实现 slog.Handler 接口
+func (h *alignedTextHandler) WithGroup(name string) slog.Handler {
+	return &alignedTextHandler{state: h.state, inner: h.inner.WithGroup(name), replace: h.replace}
 }
@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// timeLayouts 时间格式别名对应的 Go 时间格式
+var timeLayouts = map[string]string{
+	"datetime":  "2006-01-02 15:04:05",
+	"time":      "15:04:05",
+	"timems":    "15:04:05.000",
+	"rfc3339":   time.RFC3339,
+	"rfc3339ms": "2006-01-02T15:04:05.000Z07:00",
+}
+
+// unixTimeFormats Unix 时间戳格式，与时区无关
+var unixTimeFormats = map[string]bool{
+	"unix":      true,
+	"unixms":    true,
+	"unixnano":  true,
+	"unixfloat": true,
+}
+
+// relativeTimeFormat 相对于进程启动的时间格式，例如 "+1.234s"，与时区无关
+const relativeTimeFormat = "relative"
+
+// processStart 进程启动（包初始化）的时间，relative 格式的起点
+var processStart = time.Now()
+
+// validateTimeFormat 检查时间格式是否为已知别名或有效的 Go 时间格式
+//
+// 自定义格式必须至少包含一个参考时间元素（如 2006、01、15、04），
+// 否则格式化后的结果是固定字符串，通常是拼写错误
+func validateTimeFormat(format string) error {
+	if format == "" || format == relativeTimeFormat || timeLayouts[format] != "" || unixTimeFormats[format] {
+		return nil
+	}
+	sample := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	if sample.Format(format) == format {
+		return fmt.Errorf("invalid time format: %q, valid options: datetime, time, timems, rfc3339, rfc3339ms, unix, unixms, unixnano, unixfloat, relative, or a Go layout like \"2006-01-02 15:04:05\"", format)
+	}
+	return nil
+}
+
+// formatTimeValue 根据配置格式化时间
+//
+// Unix 时间戳格式返回 int64（unixfloat 返回保留 3 位小数的字符串），其他格式返回字符串；
+// relative 返回相对于进程启动的秒数，例如 "+1.234s"；
+// 未知的格式作为 Go 时间格式使用
+func formatTimeValue(t time.Time, format string) any {
+	switch format {
+	case "unix":
+		// Unix 时间戳（秒）
+		return t.Unix()
+	case "unixms":
+		// Unix 时间戳（毫秒）
+		return t.UnixMilli()
+	case "unixnano":
+		// Unix 时间戳（纳秒）
+		return t.UnixNano()
+	case "unixfloat":
+		// Unix 时间戳（浮点数，秒+小数）
+		return strconv.FormatFloat(float64(t.Unix())+float64(t.Nanosecond())/1e9, 'f', 3, 64)
+	case relativeTimeFormat:
+		// 相对于进程启动的时间（秒，毫秒精度）
+		return fmt.Sprintf("%+.3fs", t.Sub(processStart).Seconds())
+	case "":
+		// 默认格式：日期时间（秒精度）
+		return t.Format(timeLayouts["datetime"])
+	}
+	if layout, ok := timeLayouts[format]; ok {
+		return t.Format(layout)
+	}
+	// 自定义格式
+	return t.Format(format)
+}
+
+// formatTimeString 根据配置格式化时间为字符串
+func formatTimeString(t time.Time, format string) string {
+	switch v := formatTimeValue(t, format).(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return v.(string)
+	}
+}